@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"winopsguard/internal/errcode"
+	"winopsguard/internal/model"
+)
+
+const maxInputBytes = 5_000_000
+
+type result struct {
+	Kind        string               `json:"kind"`
+	GeneratedAt string               `json:"generatedAt"`
+	Codes       []model.DecodedError `json:"codes"`
+	Errors      []string             `json:"errors"`
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: winopsguard-errcode [code ...]\n\n"+
+			"Decodes HRESULT, Win32, NTSTATUS and exception codes given as arguments\n"+
+			"(0x800f081f, 800f081f, -2146498529, 5). Without arguments, every code\n"+
+			"found in the text or JSON on stdin is decoded.\n")
+	}
+	flag.Parse()
+
+	res := result{
+		Kind:        "error_codes",
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Codes:       []model.DecodedError{},
+		Errors:      []string{},
+	}
+
+	if flag.NArg() > 0 {
+		for _, arg := range flag.Args() {
+			code, err := errcode.Parse(arg)
+			if err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: not a numeric code", arg))
+				continue
+			}
+			res.Codes = append(res.Codes, describe(code))
+		}
+		output(res)
+		return
+	}
+
+	raw, err := readStdinLimited(maxInputBytes)
+	if err != nil {
+		exitErr(err)
+	}
+	for _, code := range errcode.Extract(string(raw)) {
+		res.Codes = append(res.Codes, describe(code))
+	}
+	output(res)
+}
+
+func describe(code uint32) model.DecodedError {
+	d := errcode.Describe(code)
+	if d.Name == "" && d.Description == "" {
+		d.Description = "not in the offline table"
+	}
+	return d
+}
+
+func readStdinLimited(limit int64) ([]byte, error) {
+	lr := &io.LimitedReader{R: os.Stdin, N: limit + 1}
+	data, err := io.ReadAll(lr)
+	if err != nil {
+		return nil, fmt.Errorf("read stdin: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("stdin exceeds limit (%d bytes)", limit)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("stdin is empty (pass codes as arguments or pipe text)")
+	}
+	return data, nil
+}
+
+func output(v result) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		exitErr(fmt.Errorf("encode output: %w", err))
+	}
+}
+
+func exitErr(err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	os.Exit(2)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"winopsguard/internal/iislog"
+)
+
+const (
+	defaultMinutes  = 60
+	defaultMaxBytes = 20 * 1024 * 1024
+)
+
+func main() {
+	dir := flag.String("dir", defaultLogDir(), "IIS log root containing the W3SVC<n> site directories")
+	httpErrDir := flag.String("httperr-dir", defaultHTTPErrDir(), `HTTP.sys error log directory ("" to skip)`)
+	site := flag.String("site", "", "only report this site (e.g. W3SVC1); default all sites")
+	minutes := flag.Int("minutes", defaultMinutes, "lookback window in minutes")
+	bucket := flag.Duration("bucket", 5*time.Minute, "time bucket for the 5xx series")
+	top := flag.Int("top", 10, "length of the top failing URI / substatus lists")
+	maxBytes := flag.Int64("max-bytes", defaultMaxBytes, "maximum bytes read from the end of each log file")
+	flag.Parse()
+
+	if *minutes <= 0 {
+		*minutes = defaultMinutes
+	}
+	since := time.Now().UTC().Add(-time.Duration(*minutes) * time.Minute)
+
+	res := iislog.Signals{
+		Kind:          iislog.SignalsKind,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		WindowMinutes: *minutes,
+		Sites:         []iislog.SiteStats{},
+		Errors:        []string{},
+	}
+
+	a := iislog.NewAnalyzer(since, *bucket, *top)
+	for _, err := range a.ReadDir(*dir, *maxBytes) {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	for _, s := range a.Sites() {
+		if *site != "" && !strings.EqualFold(s.Site, *site) {
+			continue
+		}
+		res.Sites = append(res.Sites, s)
+	}
+
+	if *httpErrDir != "" {
+		h := iislog.NewHTTPErrAnalyzer(since, *bucket, *top)
+		for _, err := range h.ReadDir(*httpErrDir, *maxBytes) {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		res.HTTPErr = h.Stats()
+	}
+	output(res)
+}
+
+func defaultHTTPErrDir() string {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	return filepath.Join(root, "System32", "LogFiles", "HTTPERR")
+}
+
+func defaultLogDir() string {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	return filepath.Join(drive+`\`, "inetpub", "logs", "LogFiles")
+}
+
+func output(v iislog.Signals) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
+		os.Exit(2)
+	}
+}
@@ -14,32 +14,42 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"winopsguard/internal/iislog"
 )
 
 const (
-	maxInputBytes = 5_000_000
-	actionName    = "iisreset"
+	maxInputBytes    = 5_000_000
+	actionName       = "iisreset"
+	defaultMin5xx    = 10
+	defaultMin5xxPct = 0.05
 )
 
 type triageInput struct {
 	Summary string          `json:"summary"`
 	Signals json.RawMessage `json:"signals"`
+	IIS     *iislog.Signals `json:"iis"`
 }
 
 type result struct {
-	Action     string `json:"action"`
-	Approved   bool   `json:"approved"`
-	Executed   bool   `json:"executed"`
-	Stdout     string `json:"stdout"`
-	Stderr     string `json:"stderr"`
-	ExitCode   int    `json:"exitCode"`
-	StartedAt  string `json:"startedAt"`
-	FinishedAt string `json:"finishedAt"`
-	Error      string `json:"error,omitempty"`
+	Action     string   `json:"action"`
+	Approved   bool     `json:"approved"`
+	Executed   bool     `json:"executed"`
+	Stdout     string   `json:"stdout"`
+	Stderr     string   `json:"stderr"`
+	ExitCode   int      `json:"exitCode"`
+	StartedAt  string   `json:"startedAt"`
+	FinishedAt string   `json:"finishedAt"`
+	Error      string   `json:"error,omitempty"`
+	Diagnosis  string   `json:"diagnosis,omitempty"`
+	Evidence   []string `json:"evidence,omitempty"`
 }
 
 func main() {
+	min5xx := flag.Int("min-5xx", defaultMin5xx, "minimum 5xx responses on a site for IIS signals to count as an outage")
+	minRate := flag.Float64("min-5xx-rate", defaultMin5xxPct, "minimum 5xx ratio (0-1) on a site for IIS signals to count as an outage")
 	flag.Parse()
+	limits := thresholds{min5xx: *min5xx, minRate: *minRate}
 
 	res := result{
 		Action:     actionName,
@@ -64,7 +74,24 @@ func main() {
 		return
 	}
 
-	if !isIISIssue(triage) {
+	if triage.IIS != nil {
+		diagnosis, issue, evidence := iisSignalsIssue(triage.IIS, limits)
+		res.Diagnosis = diagnosis
+		res.Evidence = evidence
+		if diagnosis == iislog.DiagnosisOverloaded {
+			res.Error = "application pool is overloaded, not dead; iisreset would drop queued requests; no action proposed"
+			output(res)
+			return
+		}
+		if !issue {
+			res.Error = "IIS signals show no outage above thresholds; no action proposed"
+			output(res)
+			return
+		}
+		for _, e := range evidence {
+			fmt.Fprintf(os.Stderr, "Evidence: %s\n", e)
+		}
+	} else if !isIISIssue(triage) {
 		res.Error = "no IIS-related issue detected; no action proposed"
 		output(res)
 		return
@@ -128,6 +155,15 @@ func parseTriage(raw []byte) (triageInput, error) {
 	if err := dec.Decode(&t); err != nil {
 		return t, fmt.Errorf("parse triage JSON: %w", err)
 	}
+	// A bare signal document (winopsguard-iis-signals output) is accepted too.
+	var probe map[string]any
+	if err := json.Unmarshal(raw, &probe); err == nil && iislog.IsSignals(probe) {
+		var sig iislog.Signals
+		if err := json.Unmarshal(raw, &sig); err != nil {
+			return t, fmt.Errorf("parse IIS signals: %w", err)
+		}
+		t.IIS = &sig
+	}
 	return t, nil
 }
 
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main exists so the platform-independent decision logic builds and is
+// tested everywhere; restarting IIS needs Windows.
+func main() {
+	fmt.Fprintln(os.Stderr, "winopsguard-remediate-iis runs on Windows only")
+	os.Exit(1)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"winopsguard/internal/iislog"
+)
+
+// thresholds decide when IIS signals count as an outage.
+type thresholds struct {
+	min5xx  int
+	minRate float64
+}
+
+// iisSignalsIssue applies the thresholds to every site and inspects the
+// HTTP.sys pool diagnoses. A dead pool is an outage even when the site logs
+// look clean, since HTTP.sys rejects those requests before IIS logs them.
+// An overloaded pool without a dead one is reported as "overloaded".
+func iisSignalsIssue(sig *iislog.Signals, t thresholds) (string, bool, []string) {
+	var (
+		evidence   []string
+		dead       bool
+		overloaded bool
+	)
+	if sig.HTTPErr != nil {
+		for _, pool := range sig.HTTPErr.Pools {
+			switch pool.Diagnosis {
+			case iislog.DiagnosisDeadPool:
+				dead = true
+			case iislog.DiagnosisOverloaded:
+				overloaded = true
+			default:
+				continue
+			}
+			line := fmt.Sprintf("app pool %s: %s (%d dead, %d overloaded of %d HTTP.sys failures, %s - %s)",
+				pool.Queue, pool.Diagnosis, pool.Dead, pool.Overloaded, pool.Total, pool.FirstSeen, pool.LastSeen)
+			if len(pool.Reasons) > 0 {
+				line += fmt.Sprintf("; top reason %s x%d", pool.Reasons[0].Key, pool.Reasons[0].Count)
+			}
+			evidence = append(evidence, line)
+		}
+	}
+	siteIssue := false
+	for _, site := range sig.Sites {
+		if site.Errors5xx < t.min5xx || site.ErrorRate5xx < t.minRate {
+			continue
+		}
+		siteIssue = true
+		line := fmt.Sprintf("site %s: %d/%d responses 5xx (%.1f%%), %d x 503",
+			site.Site, site.Errors5xx, site.Requests, site.ErrorRate5xx*100, site.Errors503)
+		if len(site.Substatus) > 0 {
+			line += fmt.Sprintf("; top status %s x%d", site.Substatus[0].Key, site.Substatus[0].Count)
+		}
+		if len(site.TopFailingURIs) > 0 {
+			line += fmt.Sprintf("; top URI %s", site.TopFailingURIs[0].Key)
+		}
+		evidence = append(evidence, line)
+	}
+	switch {
+	case dead:
+		return iislog.DiagnosisDeadPool, true, evidence
+	case overloaded:
+		return iislog.DiagnosisOverloaded, false, evidence
+	}
+	return "", siteIssue, evidence
+}
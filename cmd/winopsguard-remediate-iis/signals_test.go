@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"winopsguard/internal/iislog"
+)
+
+func TestIISSignalsIssue(t *testing.T) {
+	limits := thresholds{min5xx: 10, minRate: 0.05}
+	busySite := iislog.SiteStats{
+		Site: "W3SVC1", Requests: 200, Errors5xx: 40, Errors503: 30, ErrorRate5xx: 0.2,
+		Substatus:      []iislog.Count{{Key: "503.2", Count: 30}},
+		TopFailingURIs: []iislog.Count{{Key: "/shop/cart", Count: 25}},
+	}
+	pool := func(queue, diagnosis string, dead, overloaded int, reason string) iislog.PoolStats {
+		return iislog.PoolStats{
+			Queue: queue, Diagnosis: diagnosis, Dead: dead, Overloaded: overloaded, Total: dead + overloaded,
+			FirstSeen: "2026-10-01T10:00:00Z", LastSeen: "2026-10-01T10:05:00Z",
+			Reasons: []iislog.Count{{Key: reason, Count: max(dead, overloaded)}},
+		}
+	}
+	tests := []struct {
+		name          string
+		sig           iislog.Signals
+		wantDiagnosis string
+		wantIssue     bool
+		wantEvidence  []string
+	}{
+		{
+			name:      "site over both thresholds",
+			sig:       iislog.Signals{Sites: []iislog.SiteStats{busySite}},
+			wantIssue: true,
+			wantEvidence: []string{
+				"site W3SVC1: 40/200 responses 5xx (20.0%), 30 x 503; top status 503.2 x30; top URI /shop/cart",
+			},
+		},
+		{
+			name: "below the count threshold",
+			sig:  iislog.Signals{Sites: []iislog.SiteStats{{Site: "W3SVC1", Requests: 20, Errors5xx: 9, ErrorRate5xx: 0.45}}},
+		},
+		{
+			name: "below the rate threshold",
+			sig:  iislog.Signals{Sites: []iislog.SiteStats{{Site: "W3SVC1", Requests: 100000, Errors5xx: 400, ErrorRate5xx: 0.004}}},
+		},
+		{
+			name: "dead pool with clean site logs",
+			sig: iislog.Signals{
+				Sites:   []iislog.SiteStats{{Site: "W3SVC1", Requests: 100}},
+				HTTPErr: &iislog.HTTPErrStats{Pools: []iislog.PoolStats{pool("ShopPool", iislog.DiagnosisDeadPool, 12, 0, "AppOffline")}},
+			},
+			wantDiagnosis: iislog.DiagnosisDeadPool,
+			wantIssue:     true,
+			wantEvidence: []string{
+				"app pool ShopPool: dead_pool (12 dead, 0 overloaded of 12 HTTP.sys failures, 2026-10-01T10:00:00Z - 2026-10-01T10:05:00Z); top reason AppOffline x12",
+			},
+		},
+		{
+			name: "overloaded pool holds back a site outage",
+			sig: iislog.Signals{
+				Sites:   []iislog.SiteStats{busySite},
+				HTTPErr: &iislog.HTTPErrStats{Pools: []iislog.PoolStats{pool("ShopPool", iislog.DiagnosisOverloaded, 0, 50, "QueueFull")}},
+			},
+			wantDiagnosis: iislog.DiagnosisOverloaded,
+			wantEvidence: []string{
+				"app pool ShopPool: overloaded (0 dead, 50 overloaded of 50 HTTP.sys failures, 2026-10-01T10:00:00Z - 2026-10-01T10:05:00Z); top reason QueueFull x50",
+				"site W3SVC1: 40/200 responses 5xx (20.0%), 30 x 503; top status 503.2 x30; top URI /shop/cart",
+			},
+		},
+		{
+			name: "dead pool wins over an overloaded one",
+			sig: iislog.Signals{HTTPErr: &iislog.HTTPErrStats{Pools: []iislog.PoolStats{
+				pool("ApiPool", iislog.DiagnosisOverloaded, 0, 7, "Timer_AppPool"),
+				pool("ShopPool", iislog.DiagnosisDeadPool, 3, 0, "AppShutdown"),
+				{Queue: "-", Total: 4, Reasons: []iislog.Count{{Key: "Timer_ConnectionIdle", Count: 4}}},
+			}}},
+			wantDiagnosis: iislog.DiagnosisDeadPool,
+			wantIssue:     true,
+			wantEvidence: []string{
+				"app pool ApiPool: overloaded (0 dead, 7 overloaded of 7 HTTP.sys failures, 2026-10-01T10:00:00Z - 2026-10-01T10:05:00Z); top reason Timer_AppPool x7",
+				"app pool ShopPool: dead_pool (3 dead, 0 overloaded of 3 HTTP.sys failures, 2026-10-01T10:00:00Z - 2026-10-01T10:05:00Z); top reason AppShutdown x3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnosis, issue, evidence := iisSignalsIssue(&tt.sig, limits)
+			if diagnosis != tt.wantDiagnosis || issue != tt.wantIssue {
+				t.Errorf("diagnosis %q issue %v, want %q %v", diagnosis, issue, tt.wantDiagnosis, tt.wantIssue)
+			}
+			if !reflect.DeepEqual(evidence, tt.wantEvidence) {
+				t.Errorf("evidence:\n got %q\nwant %q", evidence, tt.wantEvidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"winopsguard/internal/model"
+)
+
+type commandSpec struct {
+	name string
+	exe  string
+	args []string
+	// servicing marks repairs of the component store, which must not run
+	// while a reboot is pending.
+	servicing bool
+}
+
+type triageInput struct {
+	IncidentType string       `json:"incident_type"`
+	Summary      string       `json:"summary"`
+	Signals      []any        `json:"signals"`
+	RootCause    string       `json:"rootCause"`
+	Tags         []string     `json:"tags"`
+	Plan         recoveryPlan `json:"recovery_plan"`
+	Actions      []string     `json:"recommendedActions"`
+	Security     secIn        `json:"security"`
+}
+
+type secIn struct {
+	MissingKBs  []string `json:"missing_kbs"`
+	RelatedCVEs []string `json:"related_cves"`
+}
+
+type recoveryPlan struct {
+	RecommendedAction string `json:"recommended_action"`
+	ExactCommand      string `json:"exact_command"`
+}
+
+func chooseAction(t triageInput) (commandSpec, string) {
+	dism := commandSpec{name: "dism_restorehealth", exe: "dism.exe", args: []string{"/online", "/cleanup-image", "/restorehealth"}, servicing: true}
+	sfc := commandSpec{name: "sfc_scannow", exe: "sfc.exe", args: []string{"/scannow"}, servicing: true}
+	cacheReset := commandSpec{name: "reset_update_cache", exe: "powershell.exe", args: []string{
+		"-NoProfile", "-NonInteractive", "-Command",
+		`$ErrorActionPreference="Stop";
+Stop-Service -Name wuauserv -Force;
+Stop-Service -Name bits -Force;
+$path="$env:SystemRoot\SoftwareDistribution";
+$backup="$path.bak-"+(Get-Date -Format "yyyyMMddHHmmss");
+if (Test-Path $path) { Rename-Item -Path $path -NewName $backup -Force };
+Start-Service -Name bits;
+Start-Service -Name wuauserv;
+Write-Output "SoftwareDistribution reset completed: renamed to $backup";`,
+	}}
+
+	choices := []string{}
+
+	if strings.TrimSpace(t.Plan.RecommendedAction) != "" {
+		choices = append(choices, t.Plan.RecommendedAction)
+	}
+	if strings.TrimSpace(t.Plan.ExactCommand) != "" {
+		choices = append(choices, t.Plan.ExactCommand)
+	}
+	for _, item := range t.Actions {
+		if strings.TrimSpace(item) != "" {
+			choices = append(choices, item)
+		}
+	}
+
+	for _, choice := range choices {
+		normalized := strings.ToLower(strings.TrimSpace(choice))
+		switch normalized {
+		case "dism_restore_health", "dism_restorehealth":
+			return dism, "recommended action requested DISM"
+		case "sfc_scannow":
+			return sfc, "recommended action requested SFC"
+		case "reset_update_cache", "clear_update_cache", "reset windows update cache":
+			return cacheReset, "recommended action requested cache reset"
+		default:
+			if strings.Contains(normalized, "dism") && strings.Contains(normalized, "restorehealth") {
+				return dism, "recommended action matched DISM"
+			}
+			if strings.Contains(normalized, "sfc") {
+				return sfc, "recommended action matched SFC"
+			}
+			if strings.Contains(normalized, "cache") && strings.Contains(normalized, "update") {
+				return cacheReset, "recommended action matched cache reset"
+			}
+		}
+	}
+
+	if len(t.Security.MissingKBs) > 0 {
+		return dism, "missing KBs detected; attempting repair via DISM"
+	}
+
+	return dism, "default repair: DISM"
+}
+
+// rebootGate refuses to run servicing repairs while Windows waits for a
+// restart: DISM and SFC then work against a half-applied component store,
+// take long and usually fail. Resetting the update cache only restarts
+// services and renames SoftwareDistribution, which a pending reboot does
+// not affect, so it is not gated. allow turns the refusal into a warning.
+func rebootGate(spec commandSpec, state model.PendingReboot, allow bool) (bool, string) {
+	for _, e := range state.Errors {
+		fmt.Fprintf(os.Stderr, "pending reboot check: %s\n", e)
+	}
+	if !spec.servicing || !state.Pending {
+		return false, ""
+	}
+	msg := "reboot pending (" + strings.Join(state.Reasons, ", ") + ")"
+	if allow {
+		return false, msg + "; running anyway because -allow-pending-reboot is set"
+	}
+	return true, msg + "; restart the server first or rerun with -allow-pending-reboot"
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"winopsguard/internal/model"
+)
+
+func TestRebootGate(t *testing.T) {
+	pending := model.PendingReboot{Pending: true, Reasons: []string{"cbs_reboot_pending", "pending_file_rename"}}
+	tests := []struct {
+		name       string
+		triage     triageInput
+		state      model.PendingReboot
+		allow      bool
+		wantAction string
+		wantRefuse bool
+		wantMsg    string
+	}{
+		{"dism refused", triageInput{Actions: []string{"dism_restorehealth"}}, pending, false,
+			"dism_restorehealth", true, "reboot pending (cbs_reboot_pending, pending_file_rename); restart the server first"},
+		{"sfc refused", triageInput{Actions: []string{"sfc_scannow"}}, pending, false, "sfc_scannow", true, "-allow-pending-reboot"},
+		{"dism allowed", triageInput{Actions: []string{"dism_restorehealth"}}, pending, true,
+			"dism_restorehealth", false, "running anyway because -allow-pending-reboot is set"},
+		{"cache reset not gated", triageInput{Actions: []string{"reset_update_cache"}}, pending, false, "reset_update_cache", false, ""},
+		{"nothing pending", triageInput{Actions: []string{"dism_restorehealth"}}, model.PendingReboot{Reasons: []string{}}, false,
+			"dism_restorehealth", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, _ := chooseAction(tt.triage)
+			if spec.name != tt.wantAction {
+				t.Fatalf("action = %s, want %s", spec.name, tt.wantAction)
+			}
+			refuse, msg := rebootGate(spec, tt.state, tt.allow)
+			if refuse != tt.wantRefuse {
+				t.Errorf("refuse = %v, want %v", refuse, tt.wantRefuse)
+			}
+			if tt.wantMsg == "" && msg != "" || !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("msg = %q, want %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestChooseAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		triage     triageInput
+		wantAction string
+		wantReason string
+	}{
+		{"recommended action", triageInput{Plan: recoveryPlan{RecommendedAction: "sfc_scannow"}}, "sfc_scannow", "recommended action requested SFC"},
+		{"verdict spelling of dism", triageInput{Plan: recoveryPlan{RecommendedAction: "DISM_Restore_Health"}}, "dism_restorehealth", "recommended action requested DISM"},
+		{"exact command", triageInput{Plan: recoveryPlan{ExactCommand: "dism /online /cleanup-image /restorehealth"}}, "dism_restorehealth", "recommended action matched DISM"},
+		{"recommended action wins over the list", triageInput{Plan: recoveryPlan{RecommendedAction: "reset_update_cache"}, Actions: []string{"sfc_scannow"}},
+			"reset_update_cache", "recommended action requested cache reset"},
+		{"unknown action falls through to the list", triageInput{Plan: recoveryPlan{RecommendedAction: "manual_check"}, Actions: []string{"Clear the Windows Update cache"}},
+			"reset_update_cache", "recommended action matched cache reset"},
+		{"missing KBs", triageInput{Security: secIn{MissingKBs: []string{"KB5031356"}}}, "dism_restorehealth", "missing KBs detected; attempting repair via DISM"},
+		{"nothing recommended", triageInput{}, "dism_restorehealth", "default repair: DISM"},
+	}
+	for _, tt := range tests {
+		spec, reason := chooseAction(tt.triage)
+		if spec.name != tt.wantAction || reason != tt.wantReason {
+			t.Errorf("%s: got %s (%q), want %s (%q)", tt.name, spec.name, reason, tt.wantAction, tt.wantReason)
+		}
+		if servicing := spec.name != "reset_update_cache"; spec.servicing != servicing {
+			t.Errorf("%s: servicing = %v for %s", tt.name, spec.servicing, spec.name)
+		}
+	}
+}
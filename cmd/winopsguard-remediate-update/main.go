@@ -15,6 +15,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"winopsguard/internal/collector"
 )
 
 const (
@@ -36,27 +38,9 @@ type remediationResult struct {
 	Reason     string `json:"reason"`
 	Security   secOut `json:"securityContext"`
 	Command    string `json:"command"`
-}
-
-type commandSpec struct {
-	name string
-	exe  string
-	args []string
-}
-
-type triageInput struct {
-	Summary   string       `json:"summary"`
-	Signals   []any        `json:"signals"`
-	RootCause string       `json:"rootCause"`
-	Tags      []string     `json:"tags"`
-	Plan      recoveryPlan `json:"recovery_plan"`
-	Actions   []string     `json:"recommendedActions"`
-	Security  secIn        `json:"security"`
-}
-
-type secIn struct {
-	MissingKBs  []string `json:"missing_kbs"`
-	RelatedCVEs []string `json:"related_cves"`
+	// PendingReboot lists the reasons a restart was pending when the action
+	// was considered.
+	PendingReboot []string `json:"pendingReboot,omitempty"`
 }
 
 type secOut struct {
@@ -66,6 +50,7 @@ type secOut struct {
 
 func main() {
 	timeoutSeconds := flag.Int("timeout", defaultTimeoutSeconds, "timeout per action in seconds")
+	allowPendingReboot := flag.Bool("allow-pending-reboot", false, "run the action even when a reboot is pending (warns instead of refusing)")
 	flag.Parse()
 
 	if *timeoutSeconds <= 0 {
@@ -100,6 +85,11 @@ func main() {
 	}
 
 	applicable, reason := isWindowsUpdateIssue(triage)
+	if triage.IncidentType == "triage_failed" {
+		// The triage response never passed schema validation; nothing in it
+		// can be trusted to pick a repair.
+		applicable, reason = false, "triage failed validation; investigate manually"
+	}
 	if !applicable {
 		result.Error = "not applicable"
 		result.Reason = reason
@@ -118,6 +108,18 @@ func main() {
 		result.Reason = reason
 	}
 
+	pending := collector.CheckPendingReboot(collector.DefaultRegistry())
+	result.PendingReboot = pending.Reasons
+	refuse, msg := rebootGate(spec, pending, *allowPendingReboot)
+	if refuse {
+		result.Error = msg
+		outputResult(result)
+		return
+	}
+	if msg != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+	}
+
 	fmt.Fprintf(os.Stderr, "Proposed action: %s. Approve? (yes/no): ", spec.name)
 	approved, err := askApproval()
 	if err != nil {
@@ -169,11 +171,6 @@ func readStdinLimited(limit int64) ([]byte, error) {
 	return data, nil
 }
 
-type recoveryPlan struct {
-	RecommendedAction string `json:"recommended_action"`
-	ExactCommand      string `json:"exact_command"`
-}
-
 func parseTriage(raw []byte) (triageInput, error) {
 	var t triageInput
 	dec := json.NewDecoder(bytes.NewReader(raw))
@@ -251,65 +248,6 @@ func addStringsFromValue(val []any, dest *[]string) {
 	}
 }
 
-func chooseAction(t triageInput) (commandSpec, string) {
-	dism := commandSpec{name: "dism_restorehealth", exe: "dism.exe", args: []string{"/online", "/cleanup-image", "/restorehealth"}}
-	sfc := commandSpec{name: "sfc_scannow", exe: "sfc.exe", args: []string{"/scannow"}}
-	cacheReset := commandSpec{name: "reset_update_cache", exe: "powershell.exe", args: []string{
-		"-NoProfile", "-NonInteractive", "-Command",
-		`$ErrorActionPreference="Stop";
-Stop-Service -Name wuauserv -Force;
-Stop-Service -Name bits -Force;
-$path="$env:SystemRoot\SoftwareDistribution";
-$backup="$path.bak-"+(Get-Date -Format "yyyyMMddHHmmss");
-if (Test-Path $path) { Rename-Item -Path $path -NewName $backup -Force };
-Start-Service -Name bits;
-Start-Service -Name wuauserv;
-Write-Output "SoftwareDistribution reset completed: renamed to $backup";`,
-	}}
-
-	choices := []string{}
-
-	if strings.TrimSpace(t.Plan.RecommendedAction) != "" {
-		choices = append(choices, t.Plan.RecommendedAction)
-	}
-	if strings.TrimSpace(t.Plan.ExactCommand) != "" {
-		choices = append(choices, t.Plan.ExactCommand)
-	}
-	for _, item := range t.Actions {
-		if strings.TrimSpace(item) != "" {
-			choices = append(choices, item)
-		}
-	}
-
-	for _, choice := range choices {
-		normalized := strings.ToLower(strings.TrimSpace(choice))
-		switch normalized {
-		case "dism_restore_health", "dism_restorehealth":
-			return dism, "recommended action requested DISM"
-		case "sfc_scannow":
-			return sfc, "recommended action requested SFC"
-		case "reset_update_cache", "clear_update_cache", "reset windows update cache":
-			return cacheReset, "recommended action requested cache reset"
-		default:
-			if strings.Contains(normalized, "dism") && strings.Contains(normalized, "restorehealth") {
-				return dism, "recommended action matched DISM"
-			}
-			if strings.Contains(normalized, "sfc") {
-				return sfc, "recommended action matched SFC"
-			}
-			if strings.Contains(normalized, "cache") && strings.Contains(normalized, "update") {
-				return cacheReset, "recommended action matched cache reset"
-			}
-		}
-	}
-
-	if len(t.Security.MissingKBs) > 0 {
-		return dism, "missing KBs detected; attempting repair via DISM"
-	}
-
-	return dism, "default repair: DISM"
-}
-
 func askApproval() (bool, error) {
 	reader := bufio.NewReader(os.Stdin)
 	line, err := reader.ReadString('\n')
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main exists so the platform-independent action choice builds and is
+// tested everywhere; the repairs themselves need Windows.
+func main() {
+	fmt.Fprintln(os.Stderr, "winopsguard-remediate-update runs on Windows only")
+	os.Exit(1)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+
+	"winopsguard/internal/model"
+	"winopsguard/internal/summarizer"
+	"winopsguard/internal/tokenizer"
+)
+
+// tokenEstimate is recorded in the triage output so callers can see what the
+// prompt cost and whether the input was cut to fit.
+type tokenEstimate struct {
+	Tokenizer    string `json:"tokenizer"`
+	PromptTokens int    `json:"prompt_tokens"`
+	Budget       int    `json:"budget,omitempty"`
+	Trimmed      bool   `json:"trimmed"`
+}
+
+// promptTokens counts the system prompt and user prompt together.
+func promptTokens(est tokenizer.Estimator, userPrompt string) int {
+	return est.Count(systemPrompt) + est.Count(userPrompt)
+}
+
+// fitInput trims signalsJSON until the prompt built from it fits budget
+// tokens. Agent payloads go through the summarizer's priority budgeter,
+// which keeps the most valuable evidence and scores events with known;
+// anything else, or a payload still too large, has its long strings and
+// arrays shortened.
+func fitInput(signalsJSON string, sec securityContext, est tokenizer.Estimator, budget int, known summarizer.KnownFunc) (string, bool) {
+	over := func(s string) bool { return promptTokens(est, buildUserPrompt(s, sec)) > budget }
+	if budget <= 0 || !over(signalsJSON) {
+		return signalsJSON, false
+	}
+	overhead := promptTokens(est, buildUserPrompt("", sec))
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(signalsJSON), &obj); err == nil && obj["eventlog"] != nil {
+		var req model.AIRequest
+		if err := json.Unmarshal([]byte(signalsJSON), &req); err == nil {
+			// Raw is not serialized; rarity is scored over the recent events
+			// the agent sent rather than over none, which rates every event
+			// as rare.
+			req.EventLog.System.Raw = req.EventLog.System.Recent
+			req.EventLog.Application.Raw = req.EventLog.Application.Recent
+			summarizer.FitPayloadTokens(&req, est, budget-overhead, known)
+			b, _ := json.Marshal(req)
+			var fitted map[string]any
+			if err := json.Unmarshal(b, &fitted); err == nil {
+				for k, v := range fitted {
+					obj[k] = v
+				}
+			}
+			b, _ = json.Marshal(obj)
+			signalsJSON = string(b)
+			if !over(signalsJSON) {
+				return signalsJSON, true
+			}
+		}
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(signalsJSON), &v); err != nil {
+		return signalsJSON, false
+	}
+	for limit := 512; limit >= 16; limit /= 2 {
+		v = shrinkJSON(v, limit)
+		b, _ := json.Marshal(v)
+		signalsJSON = string(b)
+		if !over(signalsJSON) {
+			break
+		}
+	}
+	return signalsJSON, true
+}
+
+// shrinkJSON truncates strings to limit bytes and arrays to limit/16
+// elements (at least one), keeping the head of each.
+func shrinkJSON(v any, limit int) any {
+	switch t := v.(type) {
+	case string:
+		return summarizer.Truncate(t, limit)
+	case []any:
+		keep := max(limit/16, 1)
+		if len(t) > keep {
+			t = t[:keep]
+		}
+		for i := range t {
+			t[i] = shrinkJSON(t[i], limit)
+		}
+		return t
+	case map[string]any:
+		for k, val := range t {
+			t[k] = shrinkJSON(val, limit)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"winopsguard/internal/event"
+	"winopsguard/internal/model"
+	"winopsguard/internal/signature"
+	"winopsguard/internal/summarizer"
+	"winopsguard/internal/tokenizer"
+)
+
+func TestFitInput(t *testing.T) {
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ev := func(sec int, id uint32, msg string) model.Event {
+		// Long enough that half an event outweighs the trim report.
+		msg += strings.Repeat(" detail", 60)
+		return model.Event{Time: at.Add(time.Duration(sec) * time.Second), Level: "Warning", EventID: id, Source: "Contoso", Message: msg}
+	}
+	catalog := signature.Builtin()
+	withCatalog := func(e event.Event) bool { return summarizer.KnownEvent(e) || catalog.KnownEvent(e) }
+	est := tokenizer.For("openai", "", nil)
+	var sec securityContext
+
+	tests := []struct {
+		name   string
+		recent []model.Event
+		drop   int
+		known  summarizer.KnownFunc
+		want   []uint32
+	}{
+		// The oldest event is the only 9001; with rarity counted over the
+		// recent events it outranks the newer, repeated 9002s.
+		{"rare event kept", []model.Event{
+			ev(0, 9001, "queue stalled"), ev(1, 9002, "retrying send"), ev(2, 9002, "retrying send"),
+			ev(3, 9002, "retrying send"), ev(4, 9002, "retrying send"),
+		}, 2, nil, []uint32{9001, 9002, 9002}},
+		// 0x80070070 matches the catalog's disk-full signature, not the
+		// built-in event IDs.
+		{"built-in known only", []model.Event{
+			ev(0, 9003, "staging failed with 0x80070070"), ev(1, 9004, "download slow"),
+		}, 1, nil, []uint32{9004}},
+		{"catalog known", []model.Event{
+			ev(0, 9003, "staging failed with 0x80070070"), ev(1, 9004, "download slow"),
+		}, 1, withCatalog, []uint32{9003}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req model.AIRequest
+			req.EventLog.System.Recent = tt.recent
+			full, _ := json.Marshal(req)
+			// Room for all but tt.drop events, plus half an event for the
+			// trim report.
+			kept := req
+			kept.EventLog.System.Recent = tt.recent[tt.drop:]
+			part, _ := json.Marshal(kept)
+			one, _ := json.Marshal(tt.recent[0])
+			budget := promptTokens(est, buildUserPrompt(string(part), sec)) + est.Count(string(one))/2
+
+			if promptTokens(est, buildUserPrompt(string(full), sec)) <= budget {
+				t.Fatal("budget does not force a cut")
+			}
+			got, trimmed := fitInput(string(full), sec, est, budget, tt.known)
+			if !trimmed {
+				t.Fatal("not trimmed")
+			}
+			var out model.AIRequest
+			if err := json.Unmarshal([]byte(got), &out); err != nil {
+				t.Fatal(err)
+			}
+			var ids []uint32
+			for _, e := range out.EventLog.System.Recent {
+				ids = append(ids, e.EventID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("kept %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -8,21 +8,28 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
+
+	"winopsguard/internal/collector"
+	"winopsguard/internal/config"
+	"winopsguard/internal/errcode"
+	"winopsguard/internal/event"
+	"winopsguard/internal/iislog"
+	"winopsguard/internal/llm"
+	"winopsguard/internal/model"
+	"winopsguard/internal/signature"
+	"winopsguard/internal/summarizer"
+	"winopsguard/internal/tokenizer"
 )
 
 const (
-	defaultProvider    = "openai"
-	defaultOpenAIModel = "gpt-4o-mini"
-	defaultGeminiModel = "gemini-1.5-flash"
-	defaultTimeout     = 30 * time.Second
-	defaultMaxBytes    = 5_000_000
-	openAIEndpoint     = "https://api.openai.com/v1/chat/completions"
-	geminiEndpointFmt  = "https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s"
-	maxOutputTokens    = 1200
+	defaultProvider = "openai"
+	defaultTimeout  = 30 * time.Second
+	defaultMaxBytes = 5_000_000
+	maxOutputTokens = 1200
 )
 
 const systemPrompt = `You are a Senior Windows System Engineer specializing in OS servicing and update recovery.
@@ -34,8 +41,16 @@ Diagnostic goals:
 3) Recovery Path: decide if DISM/SFC are appropriate.
 
 Remediation logic:
-- If logs indicate "Component Store Corrupt" or "Manifest missing": suggest DISM RestoreHealth.
-- If logs indicate "File not found" or "Integrity violation": suggest SFC Scannow.
+- signature_matches are deterministic matches from the local known-issue catalog. Treat them as high-confidence evidence: use their incident_type, error_code and action unless other signals clearly contradict them, and say so when you deviate.
+- Without a matching signature, if logs indicate "Component Store Corrupt" or "Manifest missing": suggest DISM RestoreHealth; if they indicate "File not found" or "Integrity violation": suggest SFC Scannow.
+- If IIS signals (kind "iis_signals") are present, judge IIS health from their 5xx/503 rates over time, sc-substatus and sc-win32-status breakdowns and time-taken percentiles, and set incident_type to "iis_failure" when they show an outage.
+- If servicing_logs (CBS.log/dism.log) are present, treat their corrupt / manifest_missing markers and HRESULTs as the primary evidence of Component Store state.
+- eventlog clusters are message templates (<*> marks variable tokens) with counts; weigh an event by its cluster count, not by how often it appears in recent.
+- If crashes buckets are present, name the faulting application, module and exception code (e.g. "w3wp.exe crashing in foo.dll 0xc0000005") instead of citing raw event 1000 counts.
+- If reboot_history shows unexpected reboots, treat them as a likely cause of failed or rolled-back updates and cite the stop code name and time.
+- If host_health flags report low disk space, memory pressure or a disabled/stopped key service (wuauserv, bits, cryptsvc, trustedinstaller, w3svc, was), address that first: servicing fails without free space and a running TrustedInstaller.
+- decoded_errors gives the meaning of each error code from an offline table; use those names and descriptions rather than recalling codes from memory, and put the most relevant one in error_code.
+- If pending_reboot.pending is true, recommend restarting first and do NOT suggest DISM or SFC until after the reboot.
 - If unsure: suggest Manual Investigation and do NOT provide a command.
 
 Safety:
@@ -52,22 +67,104 @@ Respond with JSON using this schema:
   "incident_type": "windows_update_failure",
   "error_code": "0xXXXXXXXX",
   "analysis": "Briefly explain why the update failed based on logs.",
-  "severity": "Critical",
+  "severity": "Critical | High | Medium | Low",
   "recovery_plan": {
-    "recommended_action": "dism_restore_health | sfc_scannow | manual_check",
+    "recommended_action": "dism_restore_health | sfc_scannow | reset_update_cache | iisreset | manual_check",
     "rationale": "Why this specific tool is the best first step.",
-    "exact_command": "dism /online /cleanup-image /restorehealth"
+    "exact_command": "dism /online /cleanup-image /restorehealth (the action's own command, or empty)"
   },
   "confidence_score": 0.0 to 1.0
 }`
 
 func main() {
-	provider := flag.String("provider", defaultProvider, `LLM provider ("openai" or "gemini")`)
-	model := flag.String("model", "", "Model name (defaults per provider)")
-	timeout := flag.Duration("timeout", defaultTimeout, "HTTP timeout (e.g. 30s, 60s)")
+	provider := flag.String("provider", defaultProvider, `LLM provider: "openai", "gemini", "anthropic", "azure-openai", "openai-compatible", "rules" (offline, no LLM) or a name under providers in the config`)
+	model := flag.String("model", "", "Model name (defaults per provider; the deployment name for azure-openai)")
+	timeout := flag.Duration("timeout", defaultTimeout, "Timeout of each provider request, repairs included (e.g. 30s, 60s)")
 	maxBytes := flag.Int("max-bytes", defaultMaxBytes, "Maximum stdin bytes to read")
+	hostHealth := flag.Bool("host-health", false, "Attach a host_health snapshot of this machine when the input has none")
+	configPath := flag.String("config", "config.json", "Config file for providers, token budgets and signatures (optional)")
+	maxTokens := flag.Int("max-input-tokens", 0, "Prompt token budget (overrides max_input_tokens / token_budgets; 0 uses the config)")
+	signatureDir := flag.String("signatures", "", "Directory of *.json signature overrides (default signature_dir from the config)")
+	baseURL := flag.String("base-url", "", "Provider API base URL, e.g. http://localhost:11434/v1 for an OpenAI-compatible server")
+	apiKeyEnv := flag.String("api-key-env", "", "Environment variable holding the provider API key")
+	var headers headerFlags
+	flag.Var(&headers, "header", `Extra request header "Name: value" (repeatable)`)
+	caFile := flag.String("ca-file", "", "PEM CA bundle trusted in addition to the system roots")
+	clientCert := flag.String("client-cert", "", "PEM client certificate for mutual TLS")
+	clientKey := flag.String("client-key", "", "PEM client key for mutual TLS")
+	insecure := flag.Bool("insecure-skip-verify", false, "Do not verify the provider's TLS certificate (testing only)")
+	repairs := flag.Int("repair-attempts", defaultRepairAttempts, "Re-prompts with the validation errors when a response does not match the output schema")
 	flag.Parse()
 
+	cfg, err := config.Read(*configPath)
+	if err != nil {
+		exitErr(fmt.Errorf("read config: %w", err))
+	}
+	if *signatureDir == "" {
+		*signatureDir = cfg.SignatureDir
+	}
+	catalog, err := signature.Load(*signatureDir)
+	if err != nil {
+		exitErr(fmt.Errorf("load signatures: %w", err))
+	}
+	var bpe *tokenizer.BPE
+	if cfg.TokenizerFile != "" {
+		if bpe, err = tokenizer.LoadBPE(cfg.TokenizerFile); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: load tokenizer: %v\n", err)
+		}
+	}
+	pc := cfg.Provider(*provider)
+	if *baseURL != "" {
+		pc.BaseURL = *baseURL
+	}
+	if *apiKeyEnv != "" {
+		pc.APIKeyEnv = *apiKeyEnv
+	}
+	if len(headers) > 0 {
+		merged := map[string]string{}
+		for k, v := range pc.Headers {
+			merged[k] = v
+		}
+		for k, v := range headers {
+			merged[k] = v
+		}
+		pc.Headers = merged
+	}
+	if *caFile != "" {
+		pc.TLS.CAFile = *caFile
+	}
+	if *clientCert != "" {
+		pc.TLS.CertFile, pc.TLS.KeyFile = *clientCert, *clientKey
+	}
+	if *insecure {
+		pc.TLS.InsecureSkipVerify = true
+	}
+	// Without credentials, known issues can still be triaged by the rules
+	// provider; the error is reported only if no signature matches.
+	llmProvider, providerErr := llm.New(*provider, pc)
+	if providerErr != nil && !errors.Is(providerErr, llm.ErrNoCredentials) {
+		exitErr(providerErr)
+	}
+	modelName := *model
+	if llmProvider != nil {
+		modelName = chooseModel(*model, llmProvider.DefaultModel())
+		if modelName == "" {
+			exitErr(fmt.Errorf("provider %s has no default model: pass -model or set providers.%s.model", *provider, *provider))
+		}
+	}
+	providerType := pc.Type
+	if providerType == "" {
+		providerType = *provider
+	}
+	est := tokenizer.For(providerType, modelName, bpe)
+	budget := *maxTokens
+	if budget <= 0 {
+		budget = cfg.InputTokenBudget(*provider, modelName)
+	}
+	if budget > 0 && tokenizer.IsHeuristic(est) {
+		fmt.Fprintf(os.Stderr, "warning: %d-token budget for %s checked with the heuristic estimator, which overcounts; set tokenizer_file for exact OpenAI counts\n", budget, providerType)
+	}
+
 	rawInput, err := readStdinLimited(int64(*maxBytes))
 	if err != nil {
 		exitErr(err)
@@ -77,17 +174,84 @@ func main() {
 	if err != nil {
 		exitErr(err)
 	}
+	iisSignals := extractIIS(rawInput)
+	health := extractHostHealth(rawInput)
+	if health == nil && *hostHealth {
+		snap := collector.CollectHostHealth(collector.DefaultHealth())
+		health = &snap
+		normalizedInput, err = attachField(normalizedInput, "host_health", snap)
+		if err != nil {
+			exitErr(err)
+		}
+	}
+	if decoded := decodeErrors(rawInput, normalizedInput); len(decoded) > 0 {
+		normalizedInput, err = attachField(normalizedInput, "decoded_errors", decoded)
+		if err != nil {
+			exitErr(err)
+		}
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
-	defer cancel()
+	matches, fromInput := evaluateSignatures(catalog, rawInput, normalizedInput)
+	if len(matches) > 0 && !fromInput {
+		normalizedInput, err = attachField(normalizedInput, "signature_matches", matches)
+		if err != nil {
+			exitErr(err)
+		}
+	}
+
+	// Events matching a known signature are kept longest when trimming.
+	known := func(ev event.Event) bool {
+		return summarizer.KnownEvent(ev) || catalog.KnownEvent(ev)
+	}
+	fitted, trimmed := fitInput(normalizedInput, secCtx, est, budget, known)
+	userPrompt := buildUserPrompt(fitted, secCtx)
+	tokens := tokenEstimate{
+		Tokenizer:    est.Name(),
+		PromptTokens: promptTokens(est, userPrompt),
+		Budget:       budget,
+		Trimmed:      trimmed,
+	}
+	source := "llm"
+	if llmProvider != nil && isRules(llmProvider) {
+		source = "rules"
+	}
+	extras := map[string]any{"token_estimate": tokens, "verdict_source": source}
+	if iisSignals != nil {
+		extras["iis"] = iisSignals
+	}
+	if health != nil {
+		extras["host_health"] = health
+	}
+	if len(matches) > 0 {
+		extras["signature_matches"] = matches
+	}
 
-	userPrompt := buildUserPrompt(normalizedInput, secCtx)
-	res, err := dispatchLLM(ctx, *provider, *model, userPrompt)
+	if llmProvider == nil {
+		if len(matches) == 0 {
+			exitErr(fmt.Errorf("%w (use -provider rules to triage without an LLM)", providerErr))
+		}
+		// Known issues are triaged without an LLM when no key is configured.
+		fmt.Fprintf(os.Stderr, "warning: %v; using the rules provider for signature %s\n", providerErr, matches[0].ID)
+		llmProvider = rulesProvider{name: "rules"}
+		extras["verdict_source"] = "rules"
+	}
+	result, last, problems, attempts, err := completeVerdict(context.Background(), llmProvider, llm.Request{
+		Model:     modelName,
+		System:    systemPrompt,
+		User:      userPrompt,
+		MaxTokens: maxOutputTokens,
+		Input:     normalizedInput,
+		Schema:    verdictSchema,
+	}, *repairs, *timeout)
 	if err != nil {
 		exitErr(err)
 	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %s response failed validation after %d attempt(s): %s\n", llmProvider.Name(), attempts, strings.Join(problems, "; "))
+		result = failedVerdict(llmProvider.Name(), last, problems, attempts)
+	}
 
-	if err := outputFormattedJSON(res, secCtx); err != nil {
+	if err := outputFormattedJSON(result, secCtx, extras); err != nil {
 		exitErr(err)
 	}
 }
@@ -128,7 +292,21 @@ func normalizeInput(raw []byte) (string, securityContext, error) {
 		return "", sec, fmt.Errorf("stdin must be JSON: %w", err)
 	}
 	sec = extractSecurity(anyVal)
-	return string(trimmed), sec, nil
+
+	// Event input (current document or either legacy array shape) is rewritten
+	// to the canonical schema so the prompt always sees one shape.
+	events, err := event.Decode(trimmed)
+	if errors.Is(err, event.ErrNotEvents) {
+		return string(trimmed), sec, nil
+	}
+	if err != nil {
+		return "", sec, fmt.Errorf("decode events: %w", err)
+	}
+	doc, err := json.Marshal(event.NewDocument(events))
+	if err != nil {
+		return "", sec, fmt.Errorf("encode events: %w", err)
+	}
+	return string(doc), sec, nil
 }
 
 func extractSecurity(val any) securityContext {
@@ -212,200 +390,127 @@ func extractSecurityFromMap(m map[string]any) securityContext {
 	return sec
 }
 
-func dispatchLLM(ctx context.Context, provider, model, userPrompt string) (string, error) {
-	p := strings.ToLower(strings.TrimSpace(provider))
-	switch p {
-	case "openai", "":
-		return callOpenAI(ctx, chooseModel(model, defaultOpenAIModel), userPrompt)
-	case "gemini":
-		return callGemini(ctx, chooseModel(model, defaultGeminiModel), userPrompt)
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", provider)
+// extractIIS finds IIS signal documents in the input: the document itself, an
+// "iis" field, or elements of a top-level array. Sites of several documents
+// are merged so the IIS remediation stage sees all evidence.
+func extractIIS(raw []byte) *iislog.Signals {
+	var val any
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &val); err != nil {
+		return nil
 	}
-}
-
-func buildUserPrompt(signalsJSON string, sec securityContext) string {
-	if len(sec.MissingKBs) == 0 && len(sec.RelatedCVEs) == 0 {
-		return fmt.Sprintf(userPromptTemplate, signalsJSON)
+	var candidates []any
+	switch v := val.(type) {
+	case map[string]any:
+		candidates = append(candidates, v, v["iis"])
+	case []any:
+		candidates = v
 	}
-	secBytes, _ := json.Marshal(sec)
-	return fmt.Sprintf(userPromptTemplate, signalsJSON) + "\nSecurity context:\n" + string(secBytes)
-}
-
-func callOpenAI(ctx context.Context, model, userPrompt string) (string, error) {
-	apiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
-	if apiKey == "" {
-		return "", errors.New("OPENAI_API_KEY is not set")
-	}
-
-	reqBody := struct {
-		Model       string  `json:"model"`
-		Temperature float64 `json:"temperature"`
-		MaxTokens   int     `json:"max_tokens,omitempty"`
-		Messages    []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		} `json:"messages"`
-	}{
-		Model:       model,
-		Temperature: 0,
-		MaxTokens:   maxOutputTokens,
-		Messages: []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		}{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-	}
-
-	body, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("encode OpenAI request: %w", err)
+	var merged *iislog.Signals
+	for _, c := range candidates {
+		m, ok := c.(map[string]any)
+		if !ok || !iislog.IsSignals(m) {
+			continue
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			continue
+		}
+		var sig iislog.Signals
+		if err := json.Unmarshal(b, &sig); err != nil {
+			continue
+		}
+		if merged == nil {
+			merged = &sig
+			continue
+		}
+		merged.Sites = append(merged.Sites, sig.Sites...)
+		merged.HTTPErr = mergeHTTPErr(merged.HTTPErr, sig.HTTPErr)
+		merged.Errors = append(merged.Errors, sig.Errors...)
 	}
+	return merged
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIEndpoint, bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("create OpenAI request: %w", err)
+// mergeHTTPErr combines the HTTP.sys summaries of two signal documents,
+// adding up the reasons both report.
+func mergeHTTPErr(a, b *iislog.HTTPErrStats) *iislog.HTTPErrStats {
+	if a == nil {
+		return b
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 0}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("OpenAI request failed: %w", err)
+	if b == nil {
+		return a
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("read OpenAI response: %w", err)
+	out := &iislog.HTTPErrStats{
+		Total: a.Total + b.Total,
+		Pools: append(append([]iislog.PoolStats(nil), a.Pools...), b.Pools...),
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("OpenAI HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	index := map[string]int{}
+	for _, c := range append(append([]iislog.Count(nil), a.Reasons...), b.Reasons...) {
+		if i, ok := index[c.Key]; ok {
+			out.Reasons[i].Count += c.Count
+			continue
+		}
+		index[c.Key] = len(out.Reasons)
+		out.Reasons = append(out.Reasons, c)
 	}
+	sort.SliceStable(out.Reasons, func(i, j int) bool { return out.Reasons[i].Count > out.Reasons[j].Count })
+	return out
+}
 
-	var decoded struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(respBody, &decoded); err != nil {
-		return "", fmt.Errorf("decode OpenAI response: %w", err)
+// extractHostHealth returns the host_health block of an agent payload so it
+// reaches the remediation stages alongside the LLM verdict.
+func extractHostHealth(raw []byte) *model.HostHealth {
+	var doc struct {
+		HostHealth *model.HostHealth `json:"host_health"`
 	}
-	if len(decoded.Choices) == 0 {
-		return "", errors.New("OpenAI response has no choices")
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &doc); err != nil {
+		return nil
 	}
-
-	return decoded.Choices[0].Message.Content, nil
+	return doc.HostHealth
 }
 
-func callGemini(ctx context.Context, model, userPrompt string) (string, error) {
-	apiKey := strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
-	if apiKey == "" {
-		return "", errors.New("GEMINI_API_KEY is not set")
-	}
-
-	reqBody := struct {
-		SystemInstruction struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"systemInstruction"`
-		Contents []struct {
-			Role  string `json:"role,omitempty"`
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"contents"`
-		GenerationConfig struct {
-			Temperature     float64 `json:"temperature,omitempty"`
-			MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
-		} `json:"generationConfig,omitempty"`
-	}{}
-
-	reqBody.SystemInstruction.Parts = []struct {
-		Text string `json:"text"`
-	}{{Text: systemPrompt}}
-	reqBody.Contents = []struct {
-		Role  string `json:"role,omitempty"`
-		Parts []struct {
-			Text string `json:"text"`
-		} `json:"parts"`
-	}{
-		{
-			Role: "user",
-			Parts: []struct {
-				Text string `json:"text"`
-			}{{Text: userPrompt}},
-		},
-	}
-	reqBody.GenerationConfig.Temperature = 0
-	reqBody.GenerationConfig.MaxOutputTokens = maxOutputTokens
-
-	body, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("encode Gemini request: %w", err)
+// decodeErrors decodes the error codes in the prompt input from the offline
+// table, counted and ranked like the agent's errcode.Annotate. Agent payloads
+// already carry decoded_errors, so nil is returned for them.
+func decodeErrors(raw []byte, input string) []model.DecodedError {
+	var doc struct {
+		DecodedErrors json.RawMessage `json:"decoded_errors"`
 	}
-
-	url := fmt.Sprintf(geminiEndpointFmt, model, apiKey)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("create Gemini request: %w", err)
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &doc); err == nil && doc.DecodedErrors != nil {
+		return nil
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return errcode.DecodeText(input)
+}
 
-	client := &http.Client{Timeout: 0}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("Gemini request failed: %w", err)
+// attachField adds a top-level field to the prompt input. Inputs that are
+// not JSON objects (legacy arrays) are wrapped as {"input": ..., key: ...}.
+func attachField(input, key string, v any) (string, error) {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(input), &obj); err != nil {
+		obj = map[string]any{"input": json.RawMessage(input)}
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
+	obj[key] = v
+	out, err := json.Marshal(obj)
 	if err != nil {
-		return "", fmt.Errorf("read Gemini response: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("Gemini HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
-	}
-
-	var decoded struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-	if err := json.Unmarshal(respBody, &decoded); err != nil {
-		return "", fmt.Errorf("decode Gemini response: %w", err)
-	}
-	if len(decoded.Candidates) == 0 || len(decoded.Candidates[0].Content.Parts) == 0 {
-		return "", errors.New("Gemini response has no text")
+		return "", fmt.Errorf("encode %s: %w", key, err)
 	}
-	return decoded.Candidates[0].Content.Parts[0].Text, nil
+	return string(out), nil
 }
 
-func outputFormattedJSON(raw string, secCtx securityContext) error {
-	// Markdownのコードブロック（```json ... ```）を除去する処理を追加
-	cleaned := cleanLLMOutput(raw)
-
-	rawBytes := bytes.TrimSpace([]byte(cleaned))
-	if len(rawBytes) == 0 {
-		return errors.New("LLM response is empty")
-	}
-
-	var obj map[string]any
-	if err := json.Unmarshal(rawBytes, &obj); err != nil {
-		return fmt.Errorf("LLM response is not valid JSON: %w Raw: %s", err, string(rawBytes))
+func buildUserPrompt(signalsJSON string, sec securityContext) string {
+	if len(sec.MissingKBs) == 0 && len(sec.RelatedCVEs) == 0 {
+		return fmt.Sprintf(userPromptTemplate, signalsJSON)
 	}
+	secBytes, _ := json.Marshal(sec)
+	return fmt.Sprintf(userPromptTemplate, signalsJSON) + "\nSecurity context:\n" + string(secBytes)
+}
 
+// outputFormattedJSON prints the validated verdict with the security context
+// and the extras (signals and estimates gathered by this stage) merged in.
+func outputFormattedJSON(obj map[string]any, secCtx securityContext, extras map[string]any) error {
 	obj["security"] = secCtx
+	for k, v := range extras {
+		obj[k] = v
+	}
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetEscapeHTML(false)
 	if err := enc.Encode(obj); err != nil {
@@ -439,6 +544,29 @@ func cleanLLMOutput(content string) string {
 	return content
 }
 
+// headerFlags collects repeated -header "Name: value" flags.
+type headerFlags map[string]string
+
+func (h *headerFlags) String() string {
+	names := make([]string, 0, len(*h))
+	for k := range *h {
+		names = append(names, k)
+	}
+	return strings.Join(names, ",")
+}
+
+func (h *headerFlags) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q: want \"Name: value\"", v)
+	}
+	if *h == nil {
+		*h = headerFlags{}
+	}
+	(*h)[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
 func chooseModel(flagVal, def string) string {
 	if strings.TrimSpace(flagVal) != "" {
 		return strings.TrimSpace(flagVal)
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"winopsguard/internal/iislog"
+)
+
+func TestExtractIIS(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantNil     bool
+		wantSites   []string
+		wantHTTPErr *iislog.HTTPErrStats
+	}{
+		{"not iis", `{"incident_type":"x"}`, true, nil, nil},
+		{"single document", `{"kind":"iis_signals","sites":[{"site":"W3SVC1"}],
+			"httpErr":{"total":3,"reasons":[{"key":"AppOffline","count":3}],"pools":[{"queue":"Shop"}]}}`,
+			false, []string{"W3SVC1"},
+			&iislog.HTTPErrStats{Total: 3, Reasons: []iislog.Count{{Key: "AppOffline", Count: 3}}, Pools: []iislog.PoolStats{{Queue: "Shop"}}}},
+		{"nested under iis", `{"host":"web01","iis":{"kind":"iis_signals","sites":[{"site":"W3SVC2"}]}}`,
+			false, []string{"W3SVC2"}, nil},
+		{"httperr only in second document", `[{"kind":"iis_signals","sites":[{"site":"W3SVC1"}]},
+			{"kind":"iis_signals","sites":[],"httpErr":{"total":2,"reasons":[{"key":"QueueFull","count":2}],"pools":[{"queue":"Api"}]}}]`,
+			false, []string{"W3SVC1"},
+			&iislog.HTTPErrStats{Total: 2, Reasons: []iislog.Count{{Key: "QueueFull", Count: 2}}, Pools: []iislog.PoolStats{{Queue: "Api"}}}},
+		{"httperr in both documents", `[{"kind":"iis_signals","sites":[{"site":"W3SVC1"}],
+			"httpErr":{"total":3,"reasons":[{"key":"AppOffline","count":1},{"key":"QueueFull","count":2}],"pools":[{"queue":"Shop"}]}},
+			{"kind":"iis_signals","sites":[{"site":"W3SVC2"}],
+			"httpErr":{"total":4,"reasons":[{"key":"AppOffline","count":4}],"pools":[{"queue":"Api"}]}}]`,
+			false, []string{"W3SVC1", "W3SVC2"},
+			&iislog.HTTPErrStats{
+				Total:   7,
+				Reasons: []iislog.Count{{Key: "AppOffline", Count: 5}, {Key: "QueueFull", Count: 2}},
+				Pools:   []iislog.PoolStats{{Queue: "Shop"}, {Queue: "Api"}},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractIIS([]byte(tt.in))
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("got %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil")
+			}
+			var sites []string
+			for _, s := range got.Sites {
+				sites = append(sites, s.Site)
+			}
+			if !reflect.DeepEqual(sites, tt.wantSites) {
+				t.Errorf("sites = %v, want %v", sites, tt.wantSites)
+			}
+			if !reflect.DeepEqual(got.HTTPErr, tt.wantHTTPErr) {
+				t.Errorf("httpErr = %+v, want %+v", got.HTTPErr, tt.wantHTTPErr)
+			}
+		})
+	}
+}
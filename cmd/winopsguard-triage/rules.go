@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"winopsguard/internal/config"
+	"winopsguard/internal/errcode"
+	"winopsguard/internal/llm"
+	"winopsguard/internal/model"
+)
+
+// Confidence of rules verdicts by the evidence they rest on.
+const (
+	signatureConfidence  = 0.9
+	errorCodeConfidence  = 0.4
+	noEvidenceConfidence = 0.1
+)
+
+func init() {
+	llm.Register("rules", func(name string, _ config.ProviderConfig) (llm.Provider, error) {
+		return rulesProvider{name: name}, nil
+	})
+}
+
+// rulesProvider triages without an LLM. It reads the evidence attached to
+// the input (signature_matches, decoded_errors, pending_reboot,
+// host_health) and answers in the LLM response schema; the same input
+// always gives the same verdict, and nothing leaves the machine.
+type rulesProvider struct {
+	name string
+}
+
+// isRules reports whether p triages without an LLM.
+func isRules(p llm.Provider) bool {
+	_, ok := p.(rulesProvider)
+	return ok
+}
+
+func (p rulesProvider) Name() string       { return p.name }
+func (rulesProvider) DefaultModel() string { return "rules" }
+
+func (rulesProvider) Complete(_ context.Context, req llm.Request) (string, error) {
+	b, err := json.Marshal(rulesVerdict(req.Input))
+	if err != nil {
+		return "", fmt.Errorf("encode rules verdict: %w", err)
+	}
+	return string(b), nil
+}
+
+// rulesVerdict decides from, in order: the most severe signature match, the
+// first decoded error code (the most frequent, preferring codes named in the
+// offline table), or nothing. A pending reboot holds back
+// DISM and SFC, and host health flags are reported alongside.
+func rulesVerdict(input string) verdict {
+	var doc struct {
+		SignatureMatches []model.SignatureMatch `json:"signature_matches"`
+		DecodedErrors    []model.DecodedError   `json:"decoded_errors"`
+		PendingReboot    *model.PendingReboot   `json:"pending_reboot"`
+		HostHealth       *model.HostHealth      `json:"host_health"`
+	}
+	// Inputs that are not objects simply carry no attached evidence.
+	_ = json.Unmarshal([]byte(input), &doc)
+
+	var v verdict
+	switch {
+	case len(doc.SignatureMatches) > 0:
+		m := doc.SignatureMatches[0]
+		v = verdict{
+			IncidentType: m.IncidentType,
+			ErrorCode:    m.ErrorCode,
+			Analysis:     fmt.Sprintf("%s: %s", m.Title, m.Cause),
+			Severity:     severityName(m.Severity),
+			RecoveryPlan: recoveryPlan{
+				RecommendedAction: actionName(m.Action),
+				Rationale:         fmt.Sprintf("Matched known-issue signature %s (catalog %s) on %d observation(s).", m.ID, m.Catalog, m.Count),
+				ExactCommand:      m.Command,
+			},
+			ConfidenceScore: signatureConfidence,
+		}
+		if len(doc.SignatureMatches) > 1 {
+			var others []string
+			for _, o := range doc.SignatureMatches[1:] {
+				others = append(others, o.ID)
+			}
+			v.Analysis += " Also matched: " + strings.Join(others, ", ") + "."
+		}
+	case len(doc.DecodedErrors) > 0:
+		d := doc.DecodedErrors[0]
+		name := d.Name
+		if name == "" {
+			name = strings.TrimSpace(d.Facility + " " + d.Kind)
+		}
+		v = verdict{
+			IncidentType: incidentForCode(d),
+			ErrorCode:    d.Code,
+			Analysis:     "No known-issue signature matched. " + leadingCode(d, name),
+			Severity:     "Medium",
+			RecoveryPlan: recoveryPlan{
+				RecommendedAction: "manual_check",
+				Rationale:         "An error code alone does not identify a safe repair.",
+			},
+			ConfidenceScore: errorCodeConfidence,
+		}
+		if d.Description != "" {
+			v.Analysis += ": " + d.Description
+		}
+		v.Analysis += "."
+	default:
+		v = verdict{
+			IncidentType: "no_known_issue",
+			Analysis:     "No known-issue signature or Windows error code was found in the input.",
+			Severity:     "Low",
+			RecoveryPlan: recoveryPlan{
+				RecommendedAction: "manual_check",
+				Rationale:         "There is no evidence to act on.",
+			},
+			ConfidenceScore: noEvidenceConfidence,
+		}
+	}
+
+	if doc.PendingReboot != nil && doc.PendingReboot.Pending {
+		v.Analysis += " A reboot is pending (" + strings.Join(doc.PendingReboot.Reasons, ", ") + ")."
+		if a := v.RecoveryPlan.RecommendedAction; a == "dism_restore_health" || a == "sfc_scannow" {
+			v.RecoveryPlan = recoveryPlan{
+				RecommendedAction: "manual_check",
+				Rationale:         fmt.Sprintf("Restart first; %s is unreliable until the pending reboot completes. %s", a, v.RecoveryPlan.Rationale),
+			}
+		}
+	}
+	if doc.HostHealth != nil && len(doc.HostHealth.Flags) > 0 {
+		v.Analysis += " Host health: " + strings.Join(doc.HostHealth.Flags, "; ") + "."
+	}
+	return v
+}
+
+// leadingCode describes the first decoded error code. decoded_errors lists
+// codes named in the offline table first, so an unnamed code leads only when
+// no code was recognised.
+func leadingCode(d model.DecodedError, name string) string {
+	kind := "error code"
+	if d.Name != "" {
+		kind = "recognised error code"
+	}
+	if d.Count > 0 {
+		return fmt.Sprintf("The most frequent %s is %s (%s, seen %d time(s))", kind, d.Code, name, d.Count)
+	}
+	return fmt.Sprintf("The leading %s is %s (%s)", kind, d.Code, name)
+}
+
+// incidentForCode maps an error code to an incident type by its facility or
+// kind.
+func incidentForCode(d model.DecodedError) string {
+	switch {
+	case d.Facility == "WINDOWSUPDATE" || d.Facility == "SETUPAPI" || d.Facility == "BACKGROUNDCOPY",
+		strings.HasPrefix(d.Name, "ERROR_SXS_"), strings.HasPrefix(d.Name, "CBS_E_"):
+		return "windows_update_failure"
+	case d.Kind == errcode.KindNTSTATUS || d.Kind == errcode.KindException:
+		return "application_crash"
+	}
+	return "unknown"
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"winopsguard/internal/model"
+)
+
+func TestDecodeErrors(t *testing.T) {
+	legacy := `[{"Message":"SyncUpdates failure 0x8024402C"},{"Message":"CBS source missing 0x800F081F"},` +
+		`{"Message":"retry failed 0x8024402C"},{"Message":"client error 0x87D00324"}]`
+	got := decodeErrors([]byte(legacy), legacy)
+	var codes []string
+	var counts []int
+	for _, d := range got {
+		codes = append(codes, d.Code)
+		counts = append(counts, d.Count)
+	}
+	if want := []string{"0x8024402c", "0x800f081f", "0x87d00324"}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("codes = %v, want %v", codes, want)
+	}
+	if want := []int{2, 1, 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	agent := `{"eventlog":{},"decoded_errors":[]}`
+	if got := decodeErrors([]byte(agent), agent); got != nil {
+		t.Errorf("agent payload decoded again: %+v", got)
+	}
+}
+
+func TestRulesVerdictDecodedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		decoded  []model.DecodedError
+		wantCode string
+		wantText string
+		incident string
+	}{
+		{"recognised code",
+			[]model.DecodedError{{Code: "0x8024402c", Name: "WU_E_PT_WINHTTP_NAME_NOT_RESOLVED", Facility: "WINDOWSUPDATE", Count: 2},
+				{Code: "0x87d00324", Facility: "ITF", Count: 5}},
+			"0x8024402c", "The most frequent recognised error code is 0x8024402c (WU_E_PT_WINHTTP_NAME_NOT_RESOLVED, seen 2 time(s))",
+			"windows_update_failure"},
+		{"only unrecognised codes",
+			[]model.DecodedError{{Code: "0x87d00324", Kind: "hresult", Facility: "ITF", Count: 5}},
+			"0x87d00324", "The most frequent error code is 0x87d00324 (ITF hresult, seen 5 time(s))", "unknown"},
+		{"no count",
+			[]model.DecodedError{{Code: "0xc0000005", Name: "STATUS_ACCESS_VIOLATION", Kind: "ntstatus"}},
+			"0xc0000005", "The leading recognised error code is 0xc0000005 (STATUS_ACCESS_VIOLATION)", "application_crash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := json.Marshal(map[string]any{"decoded_errors": tt.decoded})
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := rulesVerdict(string(in))
+			if v.ErrorCode != tt.wantCode || v.IncidentType != tt.incident {
+				t.Errorf("error code %q, incident %q; want %q, %q", v.ErrorCode, v.IncidentType, tt.wantCode, tt.incident)
+			}
+			if !strings.Contains(v.Analysis, tt.wantText) {
+				t.Errorf("analysis = %q, want it to contain %q", v.Analysis, tt.wantText)
+			}
+			if v.ConfidenceScore != errorCodeConfidence || v.RecoveryPlan.RecommendedAction != "manual_check" {
+				t.Errorf("verdict = %+v", v)
+			}
+		})
+	}
+}
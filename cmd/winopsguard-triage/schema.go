@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"winopsguard/internal/llm"
+	"winopsguard/internal/signature"
+)
+
+// defaultRepairAttempts is how often a response failing validation is sent
+// back to the model with the errors.
+const defaultRepairAttempts = 2
+
+// maxEchoedResponse caps how much of an invalid response is quoted back in
+// the repair prompt and in a triage_failed result.
+const maxEchoedResponse = 4000
+
+// severities are the accepted severity values, compared case-insensitively.
+var severities = []string{"Critical", "High", "Medium", "Low"}
+
+var errorCodePattern = regexp.MustCompile(`^0x[0-9a-fA-F]{1,8}$`)
+
+// actionName is a whitelisted remediation action.
+type actionName string
+
+func (actionName) Enum() []string { return actionNames() }
+
+// severityName is a verdict severity.
+type severityName string
+
+func (severityName) Enum() []string { return severities }
+
+type recoveryPlan struct {
+	RecommendedAction actionName `json:"recommended_action"`
+	Rationale         string     `json:"rationale" desc:"Why this action is the best first step."`
+	ExactCommand      string     `json:"exact_command" desc:"The action's own command, or empty."`
+}
+
+// verdict is the triage result every provider must return. It is sent as
+// the response schema to providers with a structured output mode, and
+// validateVerdict holds every response to it before it reaches the
+// remediation stages.
+type verdict struct {
+	IncidentType    string       `json:"incident_type" desc:"snake_case incident type, e.g. windows_update_failure."`
+	ErrorCode       string       `json:"error_code" desc:"Most relevant error code as 0xXXXXXXXX, or empty."`
+	Analysis        string       `json:"analysis" desc:"Brief explanation of the failure based on the signals."`
+	Severity        severityName `json:"severity"`
+	RecoveryPlan    recoveryPlan `json:"recovery_plan"`
+	ConfidenceScore float64      `json:"confidence_score" desc:"Confidence from 0.0 to 1.0."`
+}
+
+// verdictSchema is the response schema of verdict.
+var verdictSchema = llm.SchemaOf("triage_verdict", verdict{})
+
+// validateVerdict parses a response and checks it against the verdict
+// schema: required fields and their types, the severity values, the
+// whitelisted remediation actions and their commands, the error code format
+// and the confidence range. It returns the response object, which may carry
+// extra fields, and every problem found.
+func validateVerdict(raw string) (map[string]any, []string) {
+	cleaned := bytes.TrimSpace([]byte(cleanLLMOutput(raw)))
+	if len(cleaned) == 0 {
+		return nil, []string{"response is empty"}
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(cleaned, &obj); err != nil {
+		return nil, []string{fmt.Sprintf("response is not a JSON object: %v", err)}
+	}
+
+	var problems []string
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	str := func(m map[string]any, path, key string, required bool) (string, bool) {
+		v, ok := m[key]
+		if !ok || v == nil {
+			problem("%s is required", path)
+			return "", false
+		}
+		s, ok := v.(string)
+		if !ok {
+			problem("%s must be a string, got %s", path, jsonType(v))
+			return "", false
+		}
+		if required && strings.TrimSpace(s) == "" {
+			problem("%s must not be empty", path)
+			return "", false
+		}
+		return s, true
+	}
+
+	str(obj, "incident_type", "incident_type", true)
+	str(obj, "analysis", "analysis", true)
+	if code, ok := str(obj, "error_code", "error_code", false); ok && code != "" && !errorCodePattern.MatchString(code) {
+		problem("error_code %q must be a hex code like 0x800f081f, or empty", code)
+	}
+	if sev, ok := str(obj, "severity", "severity", true); ok && !containsFold(severities, sev) {
+		problem("severity %q is not one of %s", sev, strings.Join(severities, ", "))
+	}
+
+	switch v := obj["confidence_score"].(type) {
+	case nil:
+		problem("confidence_score is required")
+	case float64:
+		if v < 0 || v > 1 {
+			problem("confidence_score %v must be between 0 and 1", v)
+		}
+	default:
+		problem("confidence_score must be a number, got %s", jsonType(v))
+	}
+
+	plan, ok := obj["recovery_plan"].(map[string]any)
+	if !ok {
+		if obj["recovery_plan"] == nil {
+			problem("recovery_plan is required")
+		} else {
+			problem("recovery_plan must be an object, got %s", jsonType(obj["recovery_plan"]))
+		}
+		return obj, problems
+	}
+	str(plan, "recovery_plan.rationale", "rationale", false)
+	action, actionOK := str(plan, "recovery_plan.recommended_action", "recommended_action", true)
+	command, commandOK := str(plan, "recovery_plan.exact_command", "exact_command", false)
+	if !actionOK {
+		return obj, problems
+	}
+	allowed, known := signature.Actions[action]
+	switch {
+	case !known:
+		problem("recovery_plan.recommended_action %q is not one of %s", action, strings.Join(actionNames(), ", "))
+	case !commandOK || command == "":
+	case allowed == "":
+		problem("recovery_plan.exact_command must be empty for %s, got %q", action, command)
+	case !strings.EqualFold(strings.Join(strings.Fields(command), " "), allowed):
+		problem("recovery_plan.exact_command for %s must be %q or empty, got %q", action, allowed, command)
+	}
+	return obj, problems
+}
+
+// completeVerdict asks p for a verdict and, while the response fails
+// validation, sends it back with the problems up to repairs more times. Each
+// attempt gets its own timeout (none when timeout <= 0), so a slow first
+// reply does not leave the repairs without time. The last response and its
+// problems are returned when none passes, including when a repair attempt
+// fails outright; only a failure of the first attempt is an error.
+func completeVerdict(ctx context.Context, p llm.Provider, req llm.Request, repairs int, timeout time.Duration) (obj map[string]any, last string, problems []string, attempts int, err error) {
+	prompt := req.User
+	for {
+		attempts++
+		resp, err := completeAttempt(ctx, p, req, timeout)
+		if err != nil {
+			if attempts == 1 {
+				return nil, "", nil, attempts, err
+			}
+			problems = append(problems, fmt.Sprintf("repair attempt %d failed: %v", attempts-1, err))
+			return obj, last, problems, attempts, nil
+		}
+		last = resp
+		obj, problems = validateVerdict(last)
+		if len(problems) == 0 || attempts > repairs {
+			return obj, last, problems, attempts, nil
+		}
+		req.User = repairPrompt(prompt, last, problems)
+	}
+}
+
+func completeAttempt(ctx context.Context, p llm.Provider, req llm.Request, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return p.Complete(ctx, req)
+}
+
+func repairPrompt(prompt, response string, problems []string) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nYour previous response was:\n")
+	b.WriteString(truncate(response, maxEchoedResponse))
+	b.WriteString("\n\nIt does not match the required schema:\n")
+	for _, p := range problems {
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("Respond again with the corrected JSON object only.")
+	return b.String()
+}
+
+// failedVerdict is emitted instead of a response that never validated. It
+// keeps the verdict schema so the remediation stages read it as a
+// manual_check and do nothing.
+func failedVerdict(provider, last string, problems []string, attempts int) map[string]any {
+	return map[string]any{
+		"incident_type": "triage_failed",
+		"error_code":    "",
+		"analysis":      fmt.Sprintf("The %s response failed validation after %d attempt(s): %s", provider, attempts, strings.Join(problems, "; ")),
+		"severity":      "Medium",
+		"recovery_plan": recoveryPlan{
+			RecommendedAction: "manual_check",
+			Rationale:         "No valid verdict was produced; investigate the signals manually.",
+		},
+		"confidence_score":  0.0,
+		"validation_errors": problems,
+		"attempts":          attempts,
+		"raw_response":      truncate(last, maxEchoedResponse),
+	}
+}
+
+func actionNames() []string {
+	names := make([]string, 0, len(signature.Actions))
+	for a := range signature.Actions {
+		names = append(names, a)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, strings.TrimSpace(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "..."
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"winopsguard/internal/llm"
+)
+
+const validVerdict = `{"incident_type": "windows_update_failure", "error_code": "0x800f081f",
+	"analysis": "Repair source missing.", "severity": "High",
+	"recovery_plan": {"recommended_action": "dism_restore_health", "rationale": "Restore the store.",
+		"exact_command": "DISM /Online /Cleanup-Image /RestoreHealth"},
+	"confidence_score": 0.8}`
+
+func TestValidateVerdict(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"valid", validVerdict, nil},
+		{"fenced", "```json\n" + validVerdict + "\n```", nil},
+		{"empty", "  ", []string{"response is empty"}},
+		{
+			name: "string confidence",
+			in:   strings.Replace(validVerdict, `0.8`, `"0.8"`, 1),
+			want: []string{"confidence_score must be a number, got string"},
+		},
+		{
+			name: "confidence out of range",
+			in:   strings.Replace(validVerdict, `0.8`, `80`, 1),
+			want: []string{"confidence_score 80 must be between 0 and 1"},
+		},
+		{
+			name: "command not the action's own",
+			in:   strings.Replace(validVerdict, `DISM /Online /Cleanup-Image /RestoreHealth`, `run chkdsk`, 1),
+			want: []string{`recovery_plan.exact_command for dism_restore_health must be "dism /online /cleanup-image /restorehealth" or empty, got "run chkdsk"`},
+		},
+		{
+			name: "command for manual_check",
+			in: `{"incident_type": "disk_failure", "error_code": "", "analysis": "Disk resets.", "severity": "Critical",
+				"recovery_plan": {"recommended_action": "manual_check", "rationale": "", "exact_command": "run chkdsk"},
+				"confidence_score": 0.6}`,
+			want: []string{`recovery_plan.exact_command must be empty for manual_check, got "run chkdsk"`},
+		},
+		{
+			name: "action not allowed",
+			in:   strings.Replace(validVerdict, `"dism_restore_health"`, `"run chkdsk"`, 1),
+			want: []string{`recovery_plan.recommended_action "run chkdsk" is not one of dism_restore_health, iisreset, manual_check, reset_update_cache, sfc_scannow`},
+		},
+		{
+			name: "missing fields",
+			in:   `{"incident_type": "windows_update_failure", "error_code": "80070002", "severity": "urgent", "confidence_score": 0.5}`,
+			want: []string{
+				"analysis is required",
+				`error_code "80070002" must be a hex code like 0x800f081f, or empty`,
+				`severity "urgent" is not one of Critical, High, Medium, Low`,
+				"recovery_plan is required",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := validateVerdict(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, got := validateVerdict("I think it is a disk problem."); len(got) != 1 || !strings.HasPrefix(got[0], "response is not a JSON object") {
+		t.Errorf("prose: problems = %q", got)
+	}
+}
+
+func TestRepairPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		problems []string
+		want     []string
+	}{
+		{
+			name:     "string confidence",
+			response: `{"confidence_score": "high"}`,
+			problems: []string{"confidence_score must be a number, got string"},
+			want:     []string{`{"confidence_score": "high"}`, "- confidence_score must be a number, got string\n"},
+		},
+		{
+			name:     "run chkdsk",
+			response: `{"recovery_plan": {"recommended_action": "run chkdsk"}}`,
+			problems: []string{`recovery_plan.recommended_action "run chkdsk" is not one of manual_check`, "analysis is required"},
+			want:     []string{"- recovery_plan.recommended_action \"run chkdsk\" is not one of manual_check\n- analysis is required\n"},
+		},
+		{
+			name:     "long response is cut",
+			response: strings.Repeat("x", maxEchoedResponse+10),
+			problems: []string{"response is not a JSON object"},
+			want:     []string{strings.Repeat("x", maxEchoedResponse) + "...\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := repairPrompt("PROMPT", tt.response, tt.problems)
+			if !strings.HasPrefix(got, "PROMPT\n\nYour previous response was:\n") || !strings.HasSuffix(got, "Respond again with the corrected JSON object only.") {
+				t.Errorf("prompt frame wrong:\n%s", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("prompt lacks %q:\n%s", w, got)
+				}
+			}
+			if strings.Contains(got, strings.Repeat("x", maxEchoedResponse+1)) {
+				t.Error("response not truncated")
+			}
+		})
+	}
+}
+
+// scriptedProvider answers attempt i with replies[i], or with errs[i] when
+// set; a reply of "wait" blocks until the attempt's context is done.
+type scriptedProvider struct {
+	replies []string
+	errs    []error
+	prompts []string
+}
+
+func (p *scriptedProvider) Name() string         { return "scripted" }
+func (p *scriptedProvider) DefaultModel() string { return "m" }
+
+func (p *scriptedProvider) Complete(ctx context.Context, req llm.Request) (string, error) {
+	i := len(p.prompts)
+	p.prompts = append(p.prompts, req.User)
+	if i < len(p.errs) && p.errs[i] != nil {
+		return "", p.errs[i]
+	}
+	if p.replies[i] == "wait" {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return p.replies[i], nil
+}
+
+func TestCompleteVerdict(t *testing.T) {
+	invalid := strings.Replace(validVerdict, `0.8`, `"0.8"`, 1)
+	transport := errors.New("connection reset by peer")
+	tests := []struct {
+		name         string
+		p            *scriptedProvider
+		wantErr      error
+		wantAttempts int
+		wantProblems []string
+		wantLast     string
+	}{
+		{name: "valid first time", p: &scriptedProvider{replies: []string{validVerdict}}, wantAttempts: 1, wantLast: validVerdict},
+		{name: "repaired", p: &scriptedProvider{replies: []string{invalid, validVerdict}}, wantAttempts: 2, wantLast: validVerdict},
+		{
+			name:         "never valid",
+			p:            &scriptedProvider{replies: []string{invalid, invalid, invalid}},
+			wantAttempts: 3,
+			wantProblems: []string{"confidence_score must be a number, got string"},
+			wantLast:     invalid,
+		},
+		{name: "first attempt fails", p: &scriptedProvider{errs: []error{transport}}, wantErr: transport, wantAttempts: 1},
+		{
+			name:         "repair times out",
+			p:            &scriptedProvider{replies: []string{invalid, "wait"}},
+			wantAttempts: 2,
+			wantProblems: []string{"confidence_score must be a number, got string", "repair attempt 1 failed: context deadline exceeded"},
+			wantLast:     invalid,
+		},
+		{
+			name:         "repair transport error",
+			p:            &scriptedProvider{replies: []string{invalid, invalid, ""}, errs: []error{nil, nil, transport}},
+			wantAttempts: 3,
+			wantProblems: []string{"confidence_score must be a number, got string", "repair attempt 2 failed: connection reset by peer"},
+			wantLast:     invalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, last, problems, attempts, err := completeVerdict(context.Background(), tt.p, llm.Request{User: "PROMPT"}, 2, 50*time.Millisecond)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts || last != tt.wantLast || !reflect.DeepEqual(problems, tt.wantProblems) {
+				t.Errorf("got attempts %d, problems %q, last %q", attempts, problems, last)
+			}
+			if len(tt.p.prompts) > 1 && !strings.Contains(tt.p.prompts[1], "- confidence_score must be a number, got string") {
+				t.Errorf("repair prompt lacks the problems:\n%s", tt.p.prompts[1])
+			}
+		})
+	}
+}
+
+// slowProvider takes delay to answer unless its context ends first.
+type slowProvider struct {
+	delay time.Duration
+	reply string
+}
+
+func (slowProvider) Name() string         { return "slow" }
+func (slowProvider) DefaultModel() string { return "m" }
+
+func (p slowProvider) Complete(ctx context.Context, _ llm.Request) (string, error) {
+	select {
+	case <-time.After(p.delay):
+		return p.reply, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestCompleteVerdictTimeoutPerAttempt(t *testing.T) {
+	// Three attempts of 40ms each exceed one 100ms deadline but each fits
+	// its own.
+	p := slowProvider{delay: 40 * time.Millisecond, reply: `{"incident_type": "x"}`}
+	_, _, problems, attempts, err := completeVerdict(context.Background(), p, llm.Request{User: "u"}, 2, 100*time.Millisecond)
+	if err != nil || attempts != 3 {
+		t.Fatalf("attempts %d, err %v", attempts, err)
+	}
+	for _, pr := range problems {
+		if strings.Contains(pr, "deadline") {
+			t.Errorf("attempt ran out of time: %q", pr)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"winopsguard/internal/event"
+	"winopsguard/internal/model"
+	"winopsguard/internal/signature"
+)
+
+// evaluateSignatures matches the prompt input against the catalog. Agent
+// payloads that already carry signature_matches keep them, since the agent
+// matched the full event set before trimming; fromInput is then true.
+func evaluateSignatures(cat *signature.Catalog, raw []byte, input string) (matches []model.SignatureMatch, fromInput bool) {
+	var doc struct {
+		SignatureMatches []model.SignatureMatch `json:"signature_matches"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &doc); err == nil && doc.SignatureMatches != nil {
+		return doc.SignatureMatches, true
+	}
+	return cat.Evaluate(inputObservations(input)), false
+}
+
+// inputObservations splits the input into observations: events of an event
+// document, the sections of an agent payload, or, for anything else, the
+// whole input as one observation so code and text rules still apply.
+func inputObservations(input string) []signature.Observation {
+	if events, err := event.Decode([]byte(input)); err == nil {
+		return signature.FromEvents("events", events)
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(input), &probe); err == nil && probe["eventlog"] != nil {
+		var req model.AIRequest
+		if err := json.Unmarshal([]byte(input), &req); err == nil {
+			return signature.FromRequest(&req)
+		}
+	}
+	return []signature.Observation{{Section: "input", Message: input, Count: 1}}
+}
@@ -1,28 +1,67 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"winopsguard/internal/api"
 	"winopsguard/internal/collector"
 	"winopsguard/internal/config"
+	"winopsguard/internal/errcode"
+	"winopsguard/internal/event"
 	"winopsguard/internal/logging"
+	"winopsguard/internal/model"
 	"winopsguard/internal/sanitizer"
+	"winopsguard/internal/signature"
 	"winopsguard/internal/store"
 	"winopsguard/internal/summarizer"
+	"winopsguard/internal/tokenizer"
 )
 
 func main() {
+	eventsFile := flag.String("events", "", "summarize an event document (winopsguard.exe output) instead of reading the live logs")
+	flag.Parse()
+
 	cfg, err := config.Load("config.json")
 	if err != nil {
 		log.Fatalf("config load failed: %v", err)
 	}
 
-	sysLog, appLog, err := collector.CollectEventLogs(cfg.Window(), cfg.MaxEvents)
+	signatures, err := signature.Load(cfg.SignatureDir)
 	if err != nil {
-		logging.Logger.Fatalf("collect event logs: %v", err)
+		logging.Logger.Printf("load signatures warning: %v; using the built-in catalog", err)
+		signatures = signature.Builtin()
+	}
+	// Events matching a known signature are kept longest when trimming.
+	known := func(ev model.Event) bool {
+		return summarizer.KnownEvent(ev) || signatures.KnownEvent(ev)
+	}
+
+	var (
+		marks          *store.Bookmarks
+		sysLog, appLog model.LogSet
+	)
+	if *eventsFile != "" {
+		sysLog, appLog, err = loadEventDocument(*eventsFile, cfg.MaxEvents)
+		if err != nil {
+			logging.Logger.Fatalf("load events: %v", err)
+		}
+	} else {
+		if cfg.UseBookmarks {
+			marks, err = store.LoadBookmarks(cfg.QueueDir)
+			if err != nil {
+				logging.Logger.Fatalf("load bookmarks: %v", err)
+			}
+			marks.Logf = logging.Logger.Printf
+		}
+		sysLog, appLog, err = collector.CollectEventLogsSince(cfg.Window(), cfg.MaxEvents, marks)
+		if err != nil {
+			logging.Logger.Fatalf("collect event logs: %v", err)
+		}
 	}
 
 	wu, err := collector.CollectWULog(cfg.WULogTempPath, cfg.MaxLogBytes)
@@ -30,7 +69,33 @@ func main() {
 		logging.Logger.Printf("collect windows update log warning: %v", err)
 	}
 
-	req := summarizer.BuildPayload(sysLog, appLog, wu, cfg.MaxSendBytes)
+	servicing, err := collector.CollectServicingLogs(cfg.MaxLogBytes)
+	if err != nil {
+		logging.Logger.Printf("collect servicing logs warning: %v", err)
+	}
+
+	crashes, err := collector.CollectCrashes(appLog.Raw, time.Now().Add(-cfg.Window()))
+	if err != nil {
+		logging.Logger.Printf("collect crash reports warning: %v", err)
+	}
+
+	var reboots model.RebootHistory
+	if *eventsFile != "" {
+		// An event document stands in for the live logs, so the timeline
+		// comes from its System events alone.
+		reboots = collector.SummarizeReboots(sysLog.Raw, nil)
+	} else if reboots, err = collector.CollectRebootHistory(cfg.Window()); err != nil {
+		logging.Logger.Printf("collect reboot history warning: %v", err)
+	}
+
+	req := summarizer.BuildPayload(sysLog, appLog, wu, cfg.MaxSendBytes, known)
+	req.ServicingLogs = servicing
+	req.Crashes = crashes
+	req.RebootHistory = reboots
+	req.PendingReboot = collector.CheckPendingReboot(collector.DefaultRegistry())
+	req.HostHealth = collector.CollectHostHealth(collector.DefaultHealth())
+	errcode.Annotate(&req)
+	req.SignatureMatches = signatures.Evaluate(signature.FromRequest(&req))
 	req.Collection.WindowMinutes = cfg.CollectionWindowMinute
 	req.Collection.MaxEvents = cfg.MaxEvents
 	req.TimestampUTC = time.Now().UTC().Format(time.RFC3339)
@@ -44,12 +109,58 @@ func main() {
 	req.Host.OS = cfg.OSVersion
 
 	sanitizer.MaskRequest(&req)
+	// Refit now that the collector sections are attached.
+	summarizer.FitPayload(&req, cfg.MaxSendBytes, known)
+	if budget := cfg.InputTokenBudget("", ""); budget > 0 {
+		var bpe *tokenizer.BPE
+		if cfg.TokenizerFile != "" {
+			if bpe, err = tokenizer.LoadBPE(cfg.TokenizerFile); err != nil {
+				logging.Logger.Printf("load tokenizer warning: %v", err)
+			}
+		}
+		est := tokenizer.For("", "", bpe)
+		if tokenizer.IsHeuristic(est) {
+			logging.Logger.Printf("token budget warning: no tokenizer_file loaded; counting with the heuristic estimator, which overcounts")
+		}
+		summarizer.FitPayloadTokens(&req, est, budget, known)
+	}
 
 	q := store.NewQueue(cfg.QueueDir)
 	queueReq := q.Enqueue(req)
 
+	// Advance bookmarks only after the payload is queued so nothing is skipped.
+	if marks != nil {
+		if err := marks.Save(); err != nil {
+			logging.Logger.Printf("save bookmarks warning: %v", err)
+		}
+	}
+
 	client := api.NewClient(cfg)
 	go client.SendWithRetry(queueReq, q, 3)
 
 	logging.Logger.Printf("request %s queued", queueReq.ID)
 }
+
+// loadEventDocument summarizes a saved event document. System channel events
+// go to the system log set; every other channel is reported as application.
+func loadEventDocument(path string, maxEvents int) (model.LogSet, model.LogSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return model.LogSet{}, model.LogSet{}, err
+	}
+	events, err := event.Decode(data)
+	if err != nil {
+		return model.LogSet{}, model.LogSet{}, fmt.Errorf("%s: %w", path, err)
+	}
+	var sysEvents, appEvents []model.Event
+	for _, ev := range events {
+		if strings.EqualFold(ev.Channel, "System") {
+			sysEvents = append(sysEvents, ev)
+		} else {
+			appEvents = append(appEvents, ev)
+		}
+	}
+	sysLog := summarizer.SummarizeEvents(summarizer.TrimWhitespace(sysEvents), maxEvents)
+	appLog := summarizer.SummarizeEvents(summarizer.TrimWhitespace(appEvents), maxEvents)
+	return sysLog, appLog, nil
+}
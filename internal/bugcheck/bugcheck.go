@@ -0,0 +1,127 @@
+// Package bugcheck decodes Windows stop (bugcheck) codes.
+package bugcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Info describes one stop code. Params names the four bugcheck parameters
+// where they carry a fixed meaning.
+type Info struct {
+	Code        uint32
+	Name        string
+	Description string
+	Params      [4]string
+}
+
+var table = map[uint32]Info{
+	0x0000000A: {Name: "IRQL_NOT_LESS_OR_EQUAL", Description: "kernel-mode access to paged or invalid memory at raised IRQL, usually a faulty driver",
+		Params: [4]string{"memory referenced", "IRQL", "access (0 read, 1 write)", "instruction address"}},
+	0x0000001A: {Name: "MEMORY_MANAGEMENT", Description: "memory manager detected corruption; suspect RAM or a driver"},
+	0x0000001E: {Name: "KMODE_EXCEPTION_NOT_HANDLED", Description: "unhandled exception in kernel mode",
+		Params: [4]string{"exception code", "exception address", "parameter 0", "parameter 1"}},
+	0x00000024: {Name: "NTFS_FILE_SYSTEM", Description: "NTFS driver failure; check the disk and run chkdsk"},
+	0x0000003B: {Name: "SYSTEM_SERVICE_EXCEPTION", Description: "exception while executing a system service routine",
+		Params: [4]string{"exception code", "exception address", "context record", ""}},
+	0x00000050: {Name: "PAGE_FAULT_IN_NONPAGED_AREA", Description: "reference to invalid system memory; suspect a driver, RAM or antivirus filter",
+		Params: [4]string{"memory referenced", "access type", "instruction address", "type of read"}},
+	0x0000007A: {Name: "KERNEL_DATA_INPAGE_ERROR", Description: "kernel data could not be read from the paging file; suspect disk or storage path",
+		Params: [4]string{"lock type", "error status", "current process", "virtual address"}},
+	0x0000007B: {Name: "INACCESSIBLE_BOOT_DEVICE", Description: "boot volume inaccessible; storage driver or boot configuration problem, often after an update"},
+	0x0000007E: {Name: "SYSTEM_THREAD_EXCEPTION_NOT_HANDLED", Description: "system thread raised an unhandled exception",
+		Params: [4]string{"exception code", "exception address", "exception record", "context record"}},
+	0x0000007F: {Name: "UNEXPECTED_KERNEL_MODE_TRAP", Description: "CPU trap the kernel could not handle; hardware or kernel stack overflow"},
+	0x0000009F: {Name: "DRIVER_POWER_STATE_FAILURE", Description: "driver did not complete a power IRP in time",
+		Params: [4]string{"subcode", "parameter 2", "parameter 3", "parameter 4"}},
+	0x000000A0: {Name: "INTERNAL_POWER_ERROR", Description: "fatal power policy manager error"},
+	0x000000BE: {Name: "ATTEMPTED_WRITE_TO_READONLY_MEMORY", Description: "driver wrote to read-only memory"},
+	0x000000C2: {Name: "BAD_POOL_CALLER", Description: "invalid pool request by a driver"},
+	0x000000C4: {Name: "DRIVER_VERIFIER_DETECTED_VIOLATION", Description: "Driver Verifier caught a driver violation"},
+	0x000000C5: {Name: "DRIVER_CORRUPTED_EXPOOL", Description: "system pool corrupted by a driver"},
+	0x000000D1: {Name: "DRIVER_IRQL_NOT_LESS_OR_EQUAL", Description: "driver accessed pageable memory at raised IRQL",
+		Params: [4]string{"memory referenced", "IRQL", "access (0 read, 1 write)", "instruction address"}},
+	0x000000EF: {Name: "CRITICAL_PROCESS_DIED", Description: "a critical system process terminated",
+		Params: [4]string{"process object", "terminated object (0 process, 1 thread)", "", ""}},
+	0x000000F4: {Name: "CRITICAL_OBJECT_TERMINATION", Description: "a critical process or thread terminated; suspect disk or csrss/wininit failure",
+		Params: [4]string{"object type", "terminating object", "process image name", "explanatory message"}},
+	0x000000FC: {Name: "ATTEMPTED_EXECUTE_OF_NOEXECUTE_MEMORY", Description: "attempt to execute non-executable memory"},
+	0x00000101: {Name: "CLOCK_WATCHDOG_TIMEOUT", Description: "a processor stopped responding to clock interrupts; hardware or firmware"},
+	0x00000109: {Name: "CRITICAL_STRUCTURE_CORRUPTION", Description: "kernel code or data corruption detected (PatchGuard)"},
+	0x00000116: {Name: "VIDEO_TDR_FAILURE", Description: "display driver failed to recover from a timeout"},
+	0x00000124: {Name: "WHEA_UNCORRECTABLE_ERROR", Description: "fatal hardware error reported by WHEA; check CPU, memory and firmware",
+		Params: [4]string{"error source", "WHEA_ERROR_RECORD address", "high 32 bits of MCi_STATUS", "low 32 bits of MCi_STATUS"}},
+	0x00000133: {Name: "DPC_WATCHDOG_VIOLATION", Description: "DPC or ISR ran too long; usually a storage or network driver"},
+	0x00000139: {Name: "KERNEL_SECURITY_CHECK_FAILURE", Description: "kernel detected corruption of a critical data structure"},
+	0x0000013A: {Name: "KERNEL_MODE_HEAP_CORRUPTION", Description: "kernel heap corruption"},
+	0x00000154: {Name: "UNEXPECTED_STORE_EXCEPTION", Description: "store component caught an unexpected exception; suspect disk"},
+	0x0000015F: {Name: "CONNECTED_STANDBY_WATCHDOG_TIMEOUT_LIVEDUMP", Description: "connected standby watchdog timeout"},
+	0x000001CA: {Name: "SYNTHETIC_WATCHDOG_TIMEOUT", Description: "guest watchdog expired; the hypervisor host did not schedule the VM"},
+	0x000001E9: {Name: "ACTIVE_EX_WORKER_THREAD_TERMINATION", Description: "an active executive worker thread was terminated"},
+	0xC000021A: {Name: "STATUS_SYSTEM_PROCESS_TERMINATED", Description: "winlogon or csrss terminated, often after a failed update or corrupt system file"},
+	0xDEADDEAD: {Name: "MANUALLY_INITIATED_CRASH1", Description: "crash initiated manually by a debugger or keyboard"},
+	0x000000E2: {Name: "MANUALLY_INITIATED_CRASH", Description: "crash initiated manually by keyboard or NMI"},
+}
+
+// Lookup returns the table entry for code.
+func Lookup(code uint32) (Info, bool) {
+	info, ok := table[code]
+	if ok {
+		info.Code = code
+	}
+	return info, ok
+}
+
+// Name returns the symbolic name of code, or "UNKNOWN_BUGCHECK" when it is
+// not in the table.
+func Name(code uint32) string {
+	if info, ok := Lookup(code); ok {
+		return info.Name
+	}
+	return "UNKNOWN_BUGCHECK"
+}
+
+// Format renders code the way Windows reports it, e.g. 0x0000009f.
+func Format(code uint32) string {
+	return fmt.Sprintf("0x%08x", code)
+}
+
+// Parse reads a stop code written as hex (0x9F, 0000009f) or, as Kernel-Power
+// 41 stores it, decimal. A bare value is taken as decimal unless it contains
+// hex letters or is zero-padded to eight digits.
+func Parse(s string) (uint32, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	base := 10
+	if rest, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		s, base = rest, 16
+	} else if len(s) == 8 || strings.ContainsAny(strings.ToLower(s), "abcdef") {
+		base = 16
+	}
+	v, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(v), true
+}
+
+// Describe labels the bugcheck parameters with their meaning where known.
+// Parameters that were not recorded are dropped.
+func Describe(code uint32, params []string) []string {
+	info, _ := Lookup(code)
+	out := make([]string, 0, len(params))
+	for i, p := range params {
+		if p == "" {
+			continue
+		}
+		if i < len(info.Params) && info.Params[i] != "" {
+			out = append(out, fmt.Sprintf("%s=%s", info.Params[i], p))
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
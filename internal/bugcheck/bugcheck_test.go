@@ -0,0 +1,76 @@
+package bugcheck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   uint32
+		wantOK bool
+	}{
+		{"0x9F", 0x9F, true},
+		{"0x0000009f", 0x9F, true},
+		{"0000009f", 0x9F, true},
+		{"00000050", 0x50, true},
+		{"159", 0x9F, true},
+		{"9f", 0x9F, true},
+		{" 0xC000021A ", 0xC000021A, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"0x", 0, false},
+		{"stop", 0, false},
+		{"0x1000000000", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Parse(%q) = %#x, %v; want %#x, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	info, ok := Lookup(0x9F)
+	if !ok || info.Code != 0x9F || info.Name != "DRIVER_POWER_STATE_FAILURE" {
+		t.Errorf("Lookup(0x9F) = %+v, %v", info, ok)
+	}
+	if _, ok := Lookup(0x12345); ok {
+		t.Error("Lookup(0x12345) found an entry")
+	}
+	if got := Name(0x12345); got != "UNKNOWN_BUGCHECK" {
+		t.Errorf("Name(0x12345) = %q", got)
+	}
+	if got := Format(0x9F); got != "0x0000009f" {
+		t.Errorf("Format(0x9F) = %q", got)
+	}
+	for code, info := range table {
+		if info.Name == "" || info.Description == "" {
+			t.Errorf("entry %s is missing its name or description", Format(code))
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   uint32
+		params []string
+		want   []string
+	}{
+		{"named parameters", 0xD1, []string{"0x0", "0x2", "0x0", "0xfffff80312345678"},
+			[]string{"memory referenced=0x0", "IRQL=0x2", "access (0 read, 1 write)=0x0", "instruction address=0xfffff80312345678"}},
+		{"unnamed parameter kept", 0xEF, []string{"0xffffc00012340080", "0x0", "0x5", "0x0"},
+			[]string{"process object=0xffffc00012340080", "terminated object (0 process, 1 thread)=0x0", "0x5", "0x0"}},
+		{"missing parameters dropped", 0x9F, []string{"0x3", "", "", ""}, []string{"subcode=0x3"}},
+		{"unknown code", 0x12345, []string{"0x1", "0x2"}, []string{"0x1", "0x2"}},
+		{"no parameters", 0x7B, nil, []string{}},
+	}
+	for _, tt := range tests {
+		if got := Describe(tt.code, tt.params); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Describe = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
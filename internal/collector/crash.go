@@ -0,0 +1,319 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"winopsguard/internal/evtx"
+	"winopsguard/internal/model"
+)
+
+// Crash sources recorded on buckets.
+const (
+	CrashSourceEvent = "event"
+	CrashSourceWER   = "wer"
+)
+
+const maxCrashBuckets = 20
+
+// crashRecord is one crash from an event or a WER report.
+type crashRecord struct {
+	App           string
+	AppVersion    string
+	Module        string
+	ModuleVersion string
+	Exception     string
+	Time          time.Time
+	Source        string
+	// CLR marks an unhandled .NET exception. Its event names only the
+	// runtime and its WER report only the assembly, so the module is left
+	// out of the key and the two meet on application and exception type.
+	CLR bool
+}
+
+func (c crashRecord) key() string {
+	if c.CLR {
+		return strings.ToLower(strings.Join([]string{c.App, "clr", c.Exception}, "|"))
+	}
+	return strings.ToLower(strings.Join([]string{c.App, c.Module, c.ModuleVersion, c.Exception}, "|"))
+}
+
+// CollectCrashes buckets Application Error (1000) and .NET Runtime (1026)
+// events with the WER reports written since the given time under
+// %ProgramData%\Microsoft\Windows\WER.
+func CollectCrashes(appEvents []model.Event, since time.Time) (model.CrashSummary, error) {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	root := filepath.Join(programData, "Microsoft", "Windows", "WER")
+	reports, err := ReadWERReports(root, since)
+	return SummarizeCrashes(appEvents, reports), err
+}
+
+// ReadWERReports parses the Report.wer files of ReportArchive and
+// ReportQueue under root whose report directory changed since the given time.
+func ReadWERReports(root string, since time.Time) ([]model.WERReport, error) {
+	var (
+		reports []model.WERReport
+		errs    []string
+	)
+	for _, sub := range []string{"ReportArchive", "ReportQueue"} {
+		paths, _ := filepath.Glob(filepath.Join(root, sub, "*", "Report.wer"))
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Before(since) {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			rep := ParseWERReport(data)
+			if rep.Time.IsZero() {
+				rep.Time = info.ModTime().UTC()
+			}
+			if !rep.Time.Before(since) {
+				reports = append(reports, rep)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return reports, fmt.Errorf("read WER reports: %s", strings.Join(errs, "; "))
+	}
+	return reports, nil
+}
+
+// ParseWERReport parses a Report.wer file: an INI-style key=value list,
+// normally UTF-16LE with a BOM. Fault details come from the Sig[n] pairs,
+// matched by name for APPCRASH/BEX and by position for CLR20r3.
+func ParseWERReport(data []byte) model.WERReport {
+	text := decodeText(data)
+	values := map[string]string{}
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(strings.TrimRight(sc.Text(), "\r"), "=")
+		if ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	rep := model.WERReport{EventType: values["EventType"], AppPath: values["AppPath"]}
+	if ft, err := strconv.ParseUint(values["EventTime"], 10, 64); err == nil && ft > 0 {
+		rep.Time = evtx.FiletimeToTime(ft)
+	}
+	sigs := map[string]string{}
+	var positional []string
+	for i := 0; ; i++ {
+		name, ok := values[fmt.Sprintf("Sig[%d].Name", i)]
+		if !ok {
+			break
+		}
+		v := values[fmt.Sprintf("Sig[%d].Value", i)]
+		sigs[strings.ToLower(name)] = v
+		positional = append(positional, v)
+	}
+	at := func(i int) string {
+		if i < len(positional) {
+			return positional[i]
+		}
+		return ""
+	}
+	if strings.EqualFold(rep.EventType, "CLR20r3") {
+		// P1 app, P2 app version, P4 assembly, P5 assembly version, P9 exception type.
+		rep.App, rep.AppVersion = at(0), at(1)
+		rep.Module, rep.ModuleVersion = at(3), at(4)
+		rep.ExceptionCode = at(8)
+	} else {
+		rep.App = sigs["application name"]
+		rep.AppVersion = sigs["application version"]
+		rep.Module = sigs["fault module name"]
+		rep.ModuleVersion = sigs["fault module version"]
+		rep.ExceptionCode = normalizeExceptionCode(sigs["exception code"])
+	}
+	return rep
+}
+
+// decodeText decodes UTF-16LE (with BOM) or UTF-8 text.
+func decodeText(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+		data = data[2:]
+		u := make([]uint16, len(data)/2)
+		for i := range u {
+			u[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+		return string(utf16.Decode(u))
+	}
+	return string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}))
+}
+
+func normalizeExceptionCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return ""
+	}
+	if !strings.HasPrefix(code, "0x") {
+		if _, err := strconv.ParseUint(code, 16, 32); err == nil {
+			return "0x" + code
+		}
+	}
+	return code
+}
+
+// crashFromEvent extracts a crash from Application Error 1000 or .NET
+// Runtime 1026. Classic events carry unnamed insertion strings (param1..);
+// newer builds name them.
+func crashFromEvent(ev model.Event) (crashRecord, bool) {
+	get := func(names ...string) string {
+		for _, n := range names {
+			if v := strings.TrimSpace(ev.Data[n]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	switch {
+	case ev.EventID == 1000 && strings.EqualFold(ev.Source, "Application Error"):
+		c := crashRecord{
+			App:           get("AppName", "param1"),
+			AppVersion:    get("AppVersion", "param2"),
+			Module:        get("ModuleName", "param4"),
+			ModuleVersion: get("ModuleVersion", "param5"),
+			Exception:     normalizeExceptionCode(get("ExceptionCode", "param7")),
+			Time:          ev.Time,
+			Source:        CrashSourceEvent,
+		}
+		return c, c.App != ""
+	case ev.EventID == 1026 && strings.EqualFold(ev.Source, ".NET Runtime"):
+		text := get("param1")
+		if text == "" {
+			text = ev.Message
+		}
+		c := crashRecord{Module: "clr", Time: ev.Time, Source: CrashSourceEvent, CLR: true}
+		for _, line := range strings.Split(text, "\n") {
+			k, v, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			v = strings.TrimSpace(v)
+			switch strings.TrimSpace(k) {
+			case "Application":
+				c.App = v
+			case "Framework Version", "CoreCLR Version":
+				c.ModuleVersion = v
+			case "Exception Info":
+				if c.Exception == "" {
+					c.Exception = v
+				}
+			}
+		}
+		return c, c.App != ""
+	}
+	return crashRecord{}, false
+}
+
+// SummarizeCrashes groups crash events and WER reports into buckets keyed by
+// application, faulting module, module version and exception code, or for
+// .NET crashes by application and exception type. A crash
+// usually yields both an event and a report, so a bucket's Count is the
+// larger of the two tallies rather than their sum.
+func SummarizeCrashes(events []model.Event, reports []model.WERReport) model.CrashSummary {
+	var records []crashRecord
+	for _, ev := range events {
+		if c, ok := crashFromEvent(ev); ok {
+			records = append(records, c)
+		}
+	}
+	for _, r := range reports {
+		if r.App == "" {
+			continue
+		}
+		records = append(records, crashRecord{
+			App:           r.App,
+			AppVersion:    r.AppVersion,
+			Module:        r.Module,
+			ModuleVersion: r.ModuleVersion,
+			Exception:     r.ExceptionCode,
+			Time:          r.Time,
+			Source:        CrashSourceWER,
+			CLR:           strings.EqualFold(r.EventType, "CLR20r3"),
+		})
+	}
+
+	buckets := map[string]*model.CrashBucket{}
+	var order []string
+	for _, c := range records {
+		k := c.key()
+		b, ok := buckets[k]
+		if !ok {
+			b = &model.CrashBucket{
+				App:           c.App,
+				Module:        c.Module,
+				ModuleVersion: c.ModuleVersion,
+				ExceptionCode: c.Exception,
+				FirstSeen:     c.Time,
+				LastSeen:      c.Time,
+			}
+			buckets[k] = b
+			order = append(order, k)
+		}
+		if c.AppVersion != "" {
+			b.AppVersion = c.AppVersion
+		}
+		if c.CLR && c.Source == CrashSourceWER && c.Module != "" {
+			// The report names the faulting assembly, the event only the runtime.
+			b.Module, b.ModuleVersion = c.Module, c.ModuleVersion
+		}
+		if c.Source == CrashSourceWER {
+			b.ReportCount++
+		} else {
+			b.EventCount++
+		}
+		b.Count = max(b.EventCount, b.ReportCount)
+		if c.Time.Before(b.FirstSeen) {
+			b.FirstSeen = c.Time
+		}
+		if c.Time.After(b.LastSeen) {
+			b.LastSeen = c.Time
+		}
+	}
+
+	out := model.CrashSummary{Buckets: []model.CrashBucket{}}
+	for _, k := range order {
+		out.Buckets = append(out.Buckets, *buckets[k])
+	}
+	sort.SliceStable(out.Buckets, func(i, j int) bool {
+		if out.Buckets[i].Count == out.Buckets[j].Count {
+			return out.Buckets[i].LastSeen.After(out.Buckets[j].LastSeen)
+		}
+		return out.Buckets[i].Count > out.Buckets[j].Count
+	})
+	if len(out.Buckets) > maxCrashBuckets {
+		out.Buckets = out.Buckets[:maxCrashBuckets]
+	}
+
+	if len(out.Buckets) == 0 {
+		out.Summary = "No application crashes found"
+		return out
+	}
+	var parts []string
+	for i, b := range out.Buckets {
+		if i == 3 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s crashing in %s %s (%dx, last %s)",
+			b.App, b.Module, b.ExceptionCode, b.Count, b.LastSeen.Format(time.RFC3339)))
+	}
+	out.Summary = strings.Join(parts, "; ")
+	return out
+}
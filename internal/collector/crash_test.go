@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"winopsguard/internal/model"
+)
+
+var crashTime = time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+func appError(app, module, code string, at time.Time) model.Event {
+	return model.Event{
+		Time: at, EventID: 1000, Source: "Application Error", Level: "Error",
+		Data: map[string]string{"AppName": app, "AppVersion": "10.0.20348.1", "ModuleName": module, "ModuleVersion": "10.0.20348.2700", "ExceptionCode": code},
+	}
+}
+
+func dotnetError(app, exception string, at time.Time) model.Event {
+	return model.Event{
+		Time: at, EventID: 1026, Source: ".NET Runtime", Level: "Error",
+		Data: map[string]string{"param1": "Application: " + app + "\nFramework Version: v4.0.30319\n" +
+			"Description: The process was terminated due to an unhandled exception.\nException Info: " + exception +
+			"\n   at Contoso.Orders.Worker.Run()"},
+	}
+}
+
+// werReport encodes a Report.wer as Windows writes it: UTF-16LE with a BOM.
+func werReport(eventType string, at time.Time, sigs ...string) []byte {
+	lines := []string{"Version=1", "EventType=" + eventType, fmt.Sprintf("EventTime=%d", at.UnixNano()/100+116444736000000000)}
+	for i := 0; i+1 < len(sigs); i += 2 {
+		lines = append(lines, fmt.Sprintf("Sig[%d].Name=%s", i/2, sigs[i]), fmt.Sprintf("Sig[%d].Value=%s", i/2, sigs[i+1]))
+	}
+	b := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(strings.Join(lines, "\r\n"))) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestParseWERReport(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want model.WERReport
+	}{
+		{"appcrash", werReport("APPCRASH", crashTime,
+			"Application Name", "w3wp.exe", "Application Version", "10.0.20348.1",
+			"Fault Module Name", "ntdll.dll", "Fault Module Version", "10.0.20348.2700", "Exception Code", "c0000374"),
+			model.WERReport{EventType: "APPCRASH", App: "w3wp.exe", AppVersion: "10.0.20348.1", Module: "ntdll.dll",
+				ModuleVersion: "10.0.20348.2700", ExceptionCode: "0xc0000374", Time: crashTime}},
+		{"clr20r3", werReport("CLR20r3", crashTime,
+			"Problem Signature 01", "OrdersWorker.exe", "Problem Signature 02", "2.3.0.0", "Problem Signature 03", "6530c1a2",
+			"Problem Signature 04", "Contoso.Orders", "Problem Signature 05", "2.3.0.0", "Problem Signature 06", "6530c1a2",
+			"Problem Signature 07", "1a", "Problem Signature 08", "2f", "Problem Signature 09", "System.NullReferenceException"),
+			model.WERReport{EventType: "CLR20r3", App: "OrdersWorker.exe", AppVersion: "2.3.0.0", Module: "Contoso.Orders",
+				ModuleVersion: "2.3.0.0", ExceptionCode: "System.NullReferenceException", Time: crashTime}},
+		{"utf-8 without time", []byte("\xEF\xBB\xBFEventType=BEX64\nSig[0].Name=Application Name\nSig[0].Value=app.exe\n"),
+			model.WERReport{EventType: "BEX64", App: "app.exe"}},
+	}
+	for _, tt := range tests {
+		if got := ParseWERReport(tt.data); got != tt.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeCrashes(t *testing.T) {
+	clrReport := ParseWERReport(werReport("CLR20r3", crashTime.Add(time.Second),
+		"P1", "OrdersWorker.exe", "P2", "2.3.0.0", "P3", "6530c1a2", "P4", "Contoso.Orders", "P5", "2.3.0.0",
+		"P6", "6530c1a2", "P7", "1a", "P8", "2f", "P9", "System.NullReferenceException"))
+	nativeReport := ParseWERReport(werReport("APPCRASH", crashTime.Add(time.Second),
+		"Application Name", "w3wp.exe", "Application Version", "10.0.20348.1",
+		"Fault Module Name", "ntdll.dll", "Fault Module Version", "10.0.20348.2700", "Exception Code", "c0000374"))
+
+	tests := []struct {
+		name    string
+		events  []model.Event
+		reports []model.WERReport
+		want    []model.CrashBucket
+	}{
+		{"native event and report meet",
+			[]model.Event{appError("w3wp.exe", "ntdll.dll", "0xc0000374", crashTime)},
+			[]model.WERReport{nativeReport},
+			[]model.CrashBucket{{App: "w3wp.exe", Module: "ntdll.dll", ModuleVersion: "10.0.20348.2700", ExceptionCode: "0xc0000374",
+				Count: 1, EventCount: 1, ReportCount: 1}}},
+		{".NET event and CLR20r3 report meet",
+			[]model.Event{dotnetError("OrdersWorker.exe", "System.NullReferenceException", crashTime)},
+			[]model.WERReport{clrReport},
+			[]model.CrashBucket{{App: "OrdersWorker.exe", Module: "Contoso.Orders", ModuleVersion: "2.3.0.0",
+				ExceptionCode: "System.NullReferenceException", Count: 1, EventCount: 1, ReportCount: 1}}},
+		{"different exception types stay apart",
+			[]model.Event{
+				dotnetError("OrdersWorker.exe", "System.NullReferenceException", crashTime),
+				dotnetError("OrdersWorker.exe", "System.OutOfMemoryException", crashTime.Add(time.Minute)),
+				dotnetError("OrdersWorker.exe", "System.OutOfMemoryException", crashTime.Add(2*time.Minute)),
+			},
+			[]model.WERReport{clrReport},
+			[]model.CrashBucket{
+				{App: "OrdersWorker.exe", Module: "clr", ModuleVersion: "v4.0.30319", ExceptionCode: "System.OutOfMemoryException",
+					Count: 2, EventCount: 2},
+				{App: "OrdersWorker.exe", Module: "Contoso.Orders", ModuleVersion: "2.3.0.0",
+					ExceptionCode: "System.NullReferenceException", Count: 1, EventCount: 1, ReportCount: 1},
+			}},
+		{"other events ignored",
+			[]model.Event{{EventID: 1001, Source: "Windows Error Reporting"}, {EventID: 1000, Source: "Application Hang"}},
+			nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SummarizeCrashes(tt.events, tt.reports)
+			if len(got.Buckets) != len(tt.want) {
+				t.Fatalf("got %d buckets %+v, want %d", len(got.Buckets), got.Buckets, len(tt.want))
+			}
+			for i, b := range got.Buckets {
+				w := tt.want[i]
+				if b.App != w.App || b.Module != w.Module || b.ModuleVersion != w.ModuleVersion || b.ExceptionCode != w.ExceptionCode ||
+					b.Count != w.Count || b.EventCount != w.EventCount || b.ReportCount != w.ReportCount {
+					t.Errorf("bucket %d:\n got %+v\nwant %+v", i, b, w)
+				}
+			}
+			if len(tt.want) == 0 && got.Summary != "No application crashes found" {
+				t.Errorf("summary = %q", got.Summary)
+			}
+		})
+	}
+}
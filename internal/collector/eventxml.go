@@ -0,0 +1,218 @@
+package collector
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+)
+
+// eventXML mirrors the subset of the event schema rendered by EvtRender.
+type eventXML struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID       uint32 `xml:"EventID"`
+		Level         uint32 `xml:"Level"`
+		Task          uint16 `xml:"Task"`
+		Opcode        uint8  `xml:"Opcode"`
+		Keywords      string `xml:"Keywords"`
+		EventRecordID uint64 `xml:"EventRecordID"`
+		Channel       string `xml:"Channel"`
+		Computer      string `xml:"Computer"`
+		TimeCreated   struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		Correlation struct {
+			ActivityID string `xml:"ActivityID,attr"`
+		} `xml:"Correlation"`
+		Execution struct {
+			ProcessID uint32 `xml:"ProcessID,attr"`
+			ThreadID  uint32 `xml:"ThreadID,attr"`
+		} `xml:"Execution"`
+	} `xml:"System"`
+	EventData struct {
+		Data   []dataXML `xml:"Data"`
+		Binary string    `xml:"Binary"`
+	} `xml:"EventData"`
+	UserData struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"UserData"`
+}
+
+type dataXML struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// renderedEvent keeps the raw System values needed for filtering next to the
+// normalized event.
+type renderedEvent struct {
+	event  model.Event
+	fields eventquery.Fields
+}
+
+// ParseEventXML converts rendered event XML (EvtRender, ToXml() or an .evtx
+// record) into a normalized event with its EventData/UserData properties.
+// Message is rebuilt from those properties; callers with publisher metadata
+// should replace it with the formatted message.
+func ParseEventXML(text []byte) (model.Event, error) {
+	r, err := parseEventXML(text)
+	return r.event, err
+}
+
+func parseEventXML(text []byte) (renderedEvent, error) {
+	var parsed eventXML
+	if err := xml.Unmarshal(text, &parsed); err != nil {
+		return renderedEvent{}, fmt.Errorf("parse XML: %w", err)
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, parsed.System.TimeCreated.SystemTime)
+	if err != nil {
+		return renderedEvent{}, fmt.Errorf("parse time: %w", err)
+	}
+	sys := parsed.System
+	keywords, _ := strconv.ParseUint(strings.TrimSpace(sys.Keywords), 0, 64)
+	return renderedEvent{
+		event: model.Event{
+			Time:       timestamp.UTC(),
+			Level:      levelName(sys.Level),
+			EventID:    sys.EventID,
+			Source:     sys.Provider.Name,
+			Message:    dataMessage(parsed),
+			Channel:    sys.Channel,
+			RecordID:   sys.EventRecordID,
+			Computer:   sys.Computer,
+			Task:       sys.Task,
+			Opcode:     sys.Opcode,
+			Keywords:   strings.TrimSpace(sys.Keywords),
+			ProcessID:  sys.Execution.ProcessID,
+			ThreadID:   sys.Execution.ThreadID,
+			ActivityID: sys.Correlation.ActivityID,
+			Data:       eventData(parsed),
+		},
+		fields: eventquery.Fields{
+			Provider: sys.Provider.Name,
+			EventID:  sys.EventID,
+			Level:    eventquery.Level(sys.Level),
+			Keywords: keywords,
+			RecordID: sys.EventRecordID,
+		},
+	}, nil
+}
+
+// eventData collects named values. Unnamed classic insertion strings are keyed
+// param1, param2, ... to match their %1, %2 placeholders.
+func eventData(parsed eventXML) map[string]string {
+	data := map[string]string{}
+	for i, d := range parsed.EventData.Data {
+		key := d.Name
+		if key == "" {
+			key = "param" + strconv.Itoa(i+1)
+		}
+		putData(data, key, strings.TrimSpace(d.Value))
+	}
+	if b := strings.TrimSpace(parsed.EventData.Binary); b != "" {
+		putData(data, "Binary", b)
+	}
+	for _, kv := range userDataPairs(parsed.UserData.Inner) {
+		putData(data, kv[0], kv[1])
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+func putData(data map[string]string, key, value string) {
+	if _, dup := data[key]; !dup {
+		data[key] = value
+		return
+	}
+	for n := 2; ; n++ {
+		k := key + "_" + strconv.Itoa(n)
+		if _, dup := data[k]; !dup {
+			data[k] = value
+			return
+		}
+	}
+}
+
+func dataMessage(parsed eventXML) string {
+	var parts []string
+	for _, d := range parsed.EventData.Data {
+		v := strings.TrimSpace(d.Value)
+		if v == "" {
+			continue
+		}
+		if d.Name != "" {
+			v = d.Name + "=" + v
+		}
+		parts = append(parts, v)
+	}
+	if len(parts) == 0 {
+		for _, kv := range userDataPairs(parsed.UserData.Inner) {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// userDataPairs flattens the provider-defined UserData element into
+// name/value pairs. The wrapper element (e.g. <EventXML>) is dropped and
+// deeper leaves are keyed by their dotted path below it.
+func userDataPairs(inner []byte) [][2]string {
+	if len(inner) == 0 {
+		return nil
+	}
+	var (
+		pairs [][2]string
+		path  []string
+		text  strings.Builder
+	)
+	dec := xml.NewDecoder(strings.NewReader(string(inner)))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return pairs
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(path) > 1 {
+				if v := strings.TrimSpace(text.String()); v != "" {
+					pairs = append(pairs, [2]string{strings.Join(path[1:], "."), v})
+				}
+			}
+			text.Reset()
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+}
+
+func levelName(level uint32) string {
+	switch level {
+	case 1:
+		return "Critical"
+	case 2:
+		return "Error"
+	case 3:
+		return "Warning"
+	case 0, 4:
+		return "Information"
+	case 5:
+		return "Verbose"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/evtx"
+	"winopsguard/internal/model"
+)
+
+// ReadEVTXFile parses an exported .evtx file and returns the newest maxEvents
+// records matching filter, or with filter.AfterRecordID the oldest maxEvents
+// after it, newest first like every EventSource. The filter's time window is
+// not applied to offline data. It does not need wevtapi, so it works on any OS.
+// Damaged chunks and records are skipped; use EVTXSource.Warn to see them.
+func ReadEVTXFile(path string, filter eventquery.Filter, maxEvents int) ([]model.Event, error) {
+	return readEVTXFile(path, filter, maxEvents, nil)
+}
+
+func readEVTXFile(path string, filter eventquery.Filter, maxEvents int, warn func(error)) ([]model.Event, error) {
+	f, err := evtx.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open evtx: %w", err)
+	}
+	defer f.Close()
+
+	var events []model.Event
+	err = f.Records(func(rec *evtx.Record) error {
+		r, err := parseEventXML([]byte(rec.XML()))
+		if err != nil {
+			return fmt.Errorf("record %d: %w", rec.ID, err)
+		}
+		if r.event.RecordID == 0 {
+			r.event.RecordID = rec.ID
+			r.fields.RecordID = rec.ID
+		}
+		if filter.Match(r.fields) {
+			events = append(events, r.event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if warn != nil {
+		for _, skipped := range f.Skipped {
+			warn(fmt.Errorf("%s: %w", path, skipped))
+		}
+	}
+
+	// Chunks of a wrapped log are not stored in record order.
+	sort.Slice(events, func(i, j int) bool { return events[i].RecordID > events[j].RecordID })
+	if maxEvents > 0 && len(events) > maxEvents {
+		if filter.AfterRecordID > 0 {
+			events = events[len(events)-maxEvents:]
+		} else {
+			events = events[:maxEvents]
+		}
+	}
+	return events, nil
+}
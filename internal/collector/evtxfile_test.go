@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+)
+
+// The fixtures are written by the evtx package tests.
+func evtxFixture(name string) string {
+	return filepath.Join("..", "evtx", "testdata", name)
+}
+
+func recordIDs(events []model.Event) []uint64 {
+	var ids []uint64
+	for _, e := range events {
+		ids = append(ids, e.RecordID)
+	}
+	return ids
+}
+
+func TestReadEVTXFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		filter  eventquery.Filter
+		max     int
+		wantIDs []uint64
+	}{
+		{"all", "normal.evtx", eventquery.Filter{}, 0, []uint64{3, 2, 1}},
+		{"event id", "normal.evtx", eventquery.Filter{EventIDs: []eventquery.IDRange{{Min: 20, Max: 20}}}, 0, []uint64{3, 1}},
+		{"level", "normal.evtx", eventquery.Filter{Levels: []eventquery.Level{eventquery.LevelInformation}}, 0, []uint64{2}},
+		{"provider", "normal.evtx", eventquery.Filter{Providers: []string{"Microsoft-Windows-Kernel-Power"}}, 0, nil},
+		{"max keeps newest", "normal.evtx", eventquery.Filter{}, 2, []uint64{3, 2}},
+		{"after record", "normal.evtx", eventquery.Filter{AfterRecordID: 1}, 0, []uint64{3, 2}},
+		{"after record keeps oldest", "normal.evtx", eventquery.Filter{AfterRecordID: 1}, 1, []uint64{2}},
+		{"bad checksum", "bad_checksum.evtx", eventquery.Filter{}, 0, []uint64{4, 3}},
+		{"truncated record", "truncated_record.evtx", eventquery.Filter{}, 0, []uint64{4, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ReadEVTXFile(evtxFixture(tt.file), tt.filter, tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := recordIDs(events); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("record ids = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestReadEVTXFileFields(t *testing.T) {
+	events, err := ReadEVTXFile(evtxFixture("normal.evtx"), eventquery.Filter{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.EventID != 20 || e.Level != "Error" || e.Source != "Microsoft-Windows-WindowsUpdateClient" ||
+		e.Channel != "System" || e.Computer != "web01.contoso.local" || e.ProcessID != 1234 {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e.Data["errorCode"] != "0x800f0922" || e.Data["updateTitle"] != "2026-10 Cumulative Update & SSU" {
+		t.Errorf("event data = %v", e.Data)
+	}
+}
+
+func TestEVTXSourceWarn(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"normal.evtx", ""},
+		{"bad_checksum.evtx", "record checksum mismatch"},
+		{"truncated_record.evtx", "record 2: binxml truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var warnings []error
+			src := EVTXSource{Path: evtxFixture(tt.file), Warn: func(err error) { warnings = append(warnings, err) }}
+			if _, err := src.Read("", eventquery.Filter{}, 0); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if len(warnings) > 0 {
+					t.Errorf("warnings = %v, want none", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), tt.want) ||
+				!strings.Contains(warnings[0].Error(), tt.file) {
+				t.Errorf("warnings = %v, want one naming %s and %q", warnings, tt.file, tt.want)
+			}
+		})
+	}
+}
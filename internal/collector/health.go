@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+// Host health flags raised by CollectHostHealth.
+const (
+	HealthLowDisk        = "low_disk_space"
+	HealthMemoryPressure = "memory_pressure"
+	HealthServiceDown    = "service_disabled_or_stopped"
+)
+
+// Thresholds for the host health flags. Servicing needs several GB free on
+// the system drive; DISM /RestoreHealth stages whole payloads.
+const (
+	lowDiskBytes      = 2 << 30
+	lowDiskPercent    = 5.0
+	memoryPressurePct = 90
+)
+
+// KeyServices are the services update and IIS remediation depend on.
+var KeyServices = []string{"wuauserv", "bits", "cryptsvc", "trustedinstaller", "w3svc", "was"}
+
+// mustRun lists key services that should be running whenever they are
+// installed; the others are trigger- or demand-started and are only flagged
+// when disabled.
+var mustRun = map[string]bool{"cryptsvc": true, "w3svc": true, "was": true}
+
+// HealthProvider reads the host measurements behind model.HostHealth.
+type HealthProvider interface {
+	// SystemDrive returns the system drive root with its free and total bytes.
+	SystemDrive() (root string, free, total uint64, err error)
+	// WinSxSSize estimates the component store size; partial is set when the
+	// walk stopped early.
+	WinSxSSize() (bytes uint64, partial bool, err error)
+	Memory() (model.MemoryHealth, error)
+	Uptime() (time.Duration, error)
+	// Service returns the start type and state of a service; a service that
+	// is not installed is reported with State "not_installed".
+	Service(name string) (model.ServiceHealth, error)
+}
+
+// FakeHealth serves fixed measurements so the host health flags can be
+// exercised without Windows.
+type FakeHealth struct {
+	Root        string
+	Free, Total uint64
+	WinSxSBytes uint64
+	Mem         model.MemoryHealth
+	Up          time.Duration
+	Services    map[string]model.ServiceHealth
+}
+
+func (f FakeHealth) SystemDrive() (string, uint64, uint64, error) {
+	return f.Root, f.Free, f.Total, nil
+}
+
+func (f FakeHealth) WinSxSSize() (uint64, bool, error) { return f.WinSxSBytes, false, nil }
+
+func (f FakeHealth) Memory() (model.MemoryHealth, error) { return f.Mem, nil }
+
+func (f FakeHealth) Uptime() (time.Duration, error) { return f.Up, nil }
+
+func (f FakeHealth) Service(name string) (model.ServiceHealth, error) {
+	if s, ok := f.Services[name]; ok {
+		s.Name = name
+		return s, nil
+	}
+	return model.ServiceHealth{Name: name, State: model.ServiceNotInstalled}, nil
+}
+
+// CollectHostHealth takes a snapshot from p and raises flags for low disk
+// space, memory pressure and key services that are disabled or, for those
+// that must run, stopped. Failed measurements go to Errors.
+func CollectHostHealth(p HealthProvider) model.HostHealth {
+	out := model.HostHealth{Services: []model.ServiceHealth{}, Flags: []string{}}
+	fail := func(what string, err error) {
+		out.Errors = append(out.Errors, fmt.Sprintf("%s: %v", what, err))
+	}
+
+	if root, free, total, err := p.SystemDrive(); err != nil {
+		fail("system drive", err)
+	} else {
+		out.SystemDrive = model.DiskHealth{Root: root, FreeBytes: free, TotalBytes: total}
+		if total > 0 {
+			out.SystemDrive.FreePercent = float64(free) * 100 / float64(total)
+		}
+		if free < lowDiskBytes || (total > 0 && out.SystemDrive.FreePercent < lowDiskPercent) {
+			out.Flags = append(out.Flags, fmt.Sprintf("%s: %s has %s free (%.1f%%)",
+				HealthLowDisk, root, formatBytes(free), out.SystemDrive.FreePercent))
+		}
+	}
+	if n, partial, err := p.WinSxSSize(); err != nil {
+		fail("WinSxS size", err)
+	} else {
+		out.WinSxSBytes, out.WinSxSPartial = n, partial
+	}
+	if mem, err := p.Memory(); err != nil {
+		fail("memory", err)
+	} else {
+		out.Memory = mem
+		if mem.LoadPercent >= memoryPressurePct {
+			out.Flags = append(out.Flags, fmt.Sprintf("%s: %d%% of physical memory in use, %s available",
+				HealthMemoryPressure, mem.LoadPercent, formatBytes(mem.AvailableBytes)))
+		}
+	}
+	if up, err := p.Uptime(); err != nil {
+		fail("uptime", err)
+	} else {
+		out.UptimeSeconds = int64(up / time.Second)
+		out.BootTime = time.Now().UTC().Add(-up).Truncate(time.Second)
+	}
+	for _, name := range KeyServices {
+		s, err := p.Service(name)
+		if err != nil {
+			fail("service "+name, err)
+			continue
+		}
+		out.Services = append(out.Services, s)
+		switch {
+		case s.State == model.ServiceNotInstalled:
+		case strings.EqualFold(s.StartType, model.StartDisabled):
+			out.Flags = append(out.Flags, fmt.Sprintf("%s: %s is disabled", HealthServiceDown, name))
+		case mustRun[name] && s.State != model.ServiceRunning:
+			out.Flags = append(out.Flags, fmt.Sprintf("%s: %s is %s", HealthServiceDown, name, s.State))
+		}
+	}
+	return out
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
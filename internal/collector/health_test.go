@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+const gib = 1 << 30
+
+// healthyHost is a FakeHealth that raises no flags.
+func healthyHost() FakeHealth {
+	return FakeHealth{
+		Root: `C:\`, Free: 40 * gib, Total: 127 * gib,
+		WinSxSBytes: 9 * gib,
+		Mem:         model.MemoryHealth{LoadPercent: 45, TotalBytes: 16 * gib, AvailableBytes: 8 * gib},
+		Up:          36 * time.Hour,
+		Services: map[string]model.ServiceHealth{
+			"wuauserv":         {StartType: model.StartManual, State: model.ServiceStopped},
+			"bits":             {StartType: model.StartDelayed, State: model.ServiceRunning},
+			"cryptsvc":         {StartType: model.StartAutomatic, State: model.ServiceRunning},
+			"trustedinstaller": {StartType: model.StartManual, State: model.ServiceStopped},
+			"w3svc":            {StartType: model.StartAutomatic, State: model.ServiceRunning},
+			"was":              {StartType: model.StartManual, State: model.ServiceRunning},
+		},
+	}
+}
+
+func TestCollectHostHealthFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*FakeHealth)
+		want   []string
+	}{
+		{"healthy", func(*FakeHealth) {}, []string{}},
+		{"two GiB free is enough", func(h *FakeHealth) { h.Free, h.Total = 2*gib, 40*gib }, []string{}},
+		{"under two GiB free", func(h *FakeHealth) { h.Free = 300 << 20 },
+			[]string{`low_disk_space: C:\ has 300.0 MiB free (0.2%)`}},
+		{"under five percent free", func(h *FakeHealth) { h.Free, h.Total = 24*gib, 500*gib },
+			[]string{`low_disk_space: C:\ has 24.0 GiB free (4.8%)`}},
+		{"unknown total", func(h *FakeHealth) { h.Free, h.Total = 10*gib, 0 }, []string{}},
+		{"memory load just under", func(h *FakeHealth) { h.Mem.LoadPercent = 89 }, []string{}},
+		{"memory pressure", func(h *FakeHealth) { h.Mem.LoadPercent, h.Mem.AvailableBytes = 90, 1600<<20 },
+			[]string{"memory_pressure: 90% of physical memory in use, 1.6 GiB available"}},
+		{"demand-start service disabled", func(h *FakeHealth) {
+			h.Services["trustedinstaller"] = model.ServiceHealth{StartType: model.StartDisabled, State: model.ServiceStopped}
+		}, []string{"service_disabled_or_stopped: trustedinstaller is disabled"}},
+		{"must-run service stopped", func(h *FakeHealth) {
+			h.Services["w3svc"] = model.ServiceHealth{StartType: model.StartAutomatic, State: model.ServiceStopped}
+		}, []string{"service_disabled_or_stopped: w3svc is stopped"}},
+		{"start type case ignored", func(h *FakeHealth) {
+			h.Services["bits"] = model.ServiceHealth{StartType: "Disabled", State: model.ServiceStopped}
+		}, []string{"service_disabled_or_stopped: bits is disabled"}},
+		{"web services not installed", func(h *FakeHealth) {
+			delete(h.Services, "w3svc")
+			delete(h.Services, "was")
+		}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := healthyHost()
+			tt.modify(&h)
+			got := CollectHostHealth(h)
+			if !reflect.DeepEqual(got.Flags, tt.want) {
+				t.Errorf("flags = %q, want %q", got.Flags, tt.want)
+			}
+			if len(got.Errors) > 0 {
+				t.Errorf("errors = %q", got.Errors)
+			}
+		})
+	}
+}
+
+func TestCollectHostHealthSnapshot(t *testing.T) {
+	before := time.Now().UTC()
+	got := CollectHostHealth(healthyHost())
+	if got.SystemDrive.Root != `C:\` || got.SystemDrive.FreeBytes != 40*gib || got.SystemDrive.TotalBytes != 127*gib {
+		t.Errorf("system drive = %+v", got.SystemDrive)
+	}
+	if p := got.SystemDrive.FreePercent; p < 31.49 || p > 31.50 {
+		t.Errorf("free percent = %v", p)
+	}
+	if got.WinSxSBytes != 9*gib || got.WinSxSPartial {
+		t.Errorf("winsxs = %d partial %v", got.WinSxSBytes, got.WinSxSPartial)
+	}
+	if got.UptimeSeconds != 36*3600 {
+		t.Errorf("uptime = %d", got.UptimeSeconds)
+	}
+	if boot := got.BootTime.Add(36 * time.Hour); boot.Before(before.Add(-time.Second)) || boot.After(time.Now().UTC()) {
+		t.Errorf("boot time = %v", got.BootTime)
+	}
+	var names []string
+	for _, s := range got.Services {
+		names = append(names, s.Name)
+	}
+	if !reflect.DeepEqual(names, KeyServices) {
+		t.Errorf("services = %v, want %v", names, KeyServices)
+	}
+}
+
+// failingHealth is a FakeHealth whose named measurements fail.
+type failingHealth struct {
+	FakeHealth
+	fail map[string]bool
+}
+
+var errMeasure = errors.New("access is denied")
+
+func (f failingHealth) SystemDrive() (string, uint64, uint64, error) {
+	if f.fail["drive"] {
+		return "", 0, 0, errMeasure
+	}
+	return f.FakeHealth.SystemDrive()
+}
+
+func (f failingHealth) Memory() (model.MemoryHealth, error) {
+	if f.fail["memory"] {
+		return model.MemoryHealth{}, errMeasure
+	}
+	return f.FakeHealth.Memory()
+}
+
+func (f failingHealth) Service(name string) (model.ServiceHealth, error) {
+	if f.fail[name] {
+		return model.ServiceHealth{}, errMeasure
+	}
+	return f.FakeHealth.Service(name)
+}
+
+func TestCollectHostHealthErrors(t *testing.T) {
+	h := healthyHost()
+	h.Services["cryptsvc"] = model.ServiceHealth{StartType: model.StartAutomatic, State: model.ServiceStopped}
+	got := CollectHostHealth(failingHealth{FakeHealth: h, fail: map[string]bool{"drive": true, "memory": true, "wuauserv": true}})
+
+	wantErrors := []string{"system drive: access is denied", "memory: access is denied", "service wuauserv: access is denied"}
+	if !reflect.DeepEqual(got.Errors, wantErrors) {
+		t.Errorf("errors = %q, want %q", got.Errors, wantErrors)
+	}
+	// A failed measurement is not a problem found: no flag for the drive or
+	// memory, and the remaining services are still classified.
+	if want := []string{"service_disabled_or_stopped: cryptsvc is stopped"}; !reflect.DeepEqual(got.Flags, want) {
+		t.Errorf("flags = %q, want %q", got.Flags, want)
+	}
+	if len(got.Services) != len(KeyServices)-1 || got.UptimeSeconds == 0 {
+		t.Errorf("snapshot = %+v", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{0: "0 B", 1023: "1023 B", 1024: "1.0 KiB", 1536: "1.5 KiB", 300 << 20: "300.0 MiB", 5 * gib: "5.0 GiB", 3 << 40: "3.0 TiB"}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+//go:build windows
+
+package collector
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"winopsguard/internal/model"
+)
+
+// winsxsWalkBudget bounds the WinSxS walk; the store holds hundreds of
+// thousands of files and the snapshot must not stall collection.
+const winsxsWalkBudget = 15 * time.Second
+
+var procGlobalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// DefaultHealth measures the local host.
+func DefaultHealth() HealthProvider {
+	return WindowsHealth{}
+}
+
+// WindowsHealth implements HealthProvider with Win32 calls and read-only
+// service control manager access.
+type WindowsHealth struct{}
+
+func (WindowsHealth) SystemDrive() (string, uint64, uint64, error) {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	root := drive + `\`
+	ptr, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return root, 0, 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(ptr, &free, &total, &totalFree); err != nil {
+		return root, 0, 0, err
+	}
+	return root, free, total, nil
+}
+
+// WinSxSSize sums file sizes under %SystemRoot%\WinSxS. Hard links into
+// System32 are counted in full, so this overstates the space the store
+// alone would free, the same way Explorer does.
+func (WindowsHealth) WinSxSSize() (uint64, bool, error) {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	deadline := time.Now().Add(winsxsWalkBudget)
+	errBudget := errors.New("budget exhausted")
+	var total uint64
+	err := filepath.WalkDir(filepath.Join(root, "WinSxS"), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errBudget
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += uint64(info.Size())
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBudget) {
+		return total, true, nil
+	}
+	return total, false, err
+}
+
+func (WindowsHealth) Memory() (model.MemoryHealth, error) {
+	var st memoryStatusEx
+	st.Length = uint32(unsafe.Sizeof(st))
+	r, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&st)))
+	if r == 0 {
+		return model.MemoryHealth{}, err
+	}
+	return model.MemoryHealth{
+		LoadPercent:          int(st.MemoryLoad),
+		TotalBytes:           st.TotalPhys,
+		AvailableBytes:       st.AvailPhys,
+		CommitTotalBytes:     st.TotalPageFile,
+		CommitAvailableBytes: st.AvailPageFile,
+	}, nil
+}
+
+func (WindowsHealth) Uptime() (time.Duration, error) {
+	return windows.DurationSinceBoot(), nil
+}
+
+func (WindowsHealth) Service(name string) (model.ServiceHealth, error) {
+	out := model.ServiceHealth{Name: name}
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return out, err
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return out, err
+	}
+	h, err := windows.OpenService(scm, namePtr, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			out.State = model.ServiceNotInstalled
+			return out, nil
+		}
+		return out, err
+	}
+	s := &mgr.Service{Name: name, Handle: h}
+	defer s.Close()
+
+	cfg, err := s.Config()
+	if err != nil {
+		return out, err
+	}
+	switch cfg.StartType {
+	case mgr.StartAutomatic:
+		out.StartType = model.StartAutomatic
+		if cfg.DelayedAutoStart {
+			out.StartType = model.StartDelayed
+		}
+	case mgr.StartManual:
+		out.StartType = model.StartManual
+	case mgr.StartDisabled:
+		out.StartType = model.StartDisabled
+	default:
+		out.StartType = "other"
+	}
+
+	status, err := s.Query()
+	if err != nil {
+		return out, err
+	}
+	switch status.State {
+	case svc.Running:
+		out.State = model.ServiceRunning
+	case svc.Stopped:
+		out.State = model.ServiceStopped
+	case svc.StartPending, svc.ContinuePending:
+		out.State = "starting"
+	case svc.StopPending:
+		out.State = "stopping"
+	case svc.PausePending, svc.Paused:
+		out.State = "paused"
+	default:
+		out.State = "unknown"
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"winopsguard/internal/model"
+)
+
+// Pending reboot reasons.
+const (
+	RebootReasonCBS            = "cbs_reboot_pending"
+	RebootReasonWindowsUpdate  = "windows_update_reboot_required"
+	RebootReasonFileRename     = "pending_file_rename"
+	RebootReasonComputerRename = "computer_rename"
+	RebootReasonSCCM           = "sccm_reboot_pending"
+)
+
+// Registry paths consulted by CheckPendingReboot, relative to HKLM.
+const (
+	cbsKey            = `SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing`
+	wuAutoUpdateKey   = `SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update`
+	sessionManagerKey = `SYSTEM\CurrentControlSet\Control\Session Manager`
+	activeNameKey     = `SYSTEM\CurrentControlSet\Control\ComputerName\ActiveComputerName`
+	pendingNameKey    = `SYSTEM\CurrentControlSet\Control\ComputerName\ComputerName`
+	sccmRebootKey     = `SOFTWARE\Microsoft\SMS\Mobile Client\Reboot Management\RebootData`
+)
+
+// RegistryReader is the read-only HKLM access the pending-reboot check needs.
+// Paths are relative to HKEY_LOCAL_MACHINE.
+type RegistryReader interface {
+	KeyExists(path string) (bool, error)
+	// Strings returns a REG_SZ, REG_EXPAND_SZ or REG_MULTI_SZ value, or nil
+	// when the key or value does not exist.
+	Strings(path, name string) ([]string, error)
+}
+
+// FakeRegistry serves keys and values from memory so the pending-reboot
+// decision can be exercised without a Windows registry. Values are keyed by
+// path + `\` + name.
+type FakeRegistry struct {
+	Keys   map[string]bool
+	Values map[string][]string
+}
+
+func (r FakeRegistry) KeyExists(path string) (bool, error) {
+	for k := range r.Keys {
+		if strings.EqualFold(k, path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r FakeRegistry) Strings(path, name string) ([]string, error) {
+	for k, v := range r.Values {
+		if strings.EqualFold(k, path+`\`+name) {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckPendingReboot reports every reason Windows is waiting for a restart.
+// A failed lookup is recorded in Errors and does not hide the other reasons.
+func CheckPendingReboot(reg RegistryReader) model.PendingReboot {
+	out := model.PendingReboot{Reasons: []string{}}
+	add := func(reason, detail string) {
+		out.Reasons = append(out.Reasons, reason)
+		if detail != "" {
+			out.Details = append(out.Details, reason+": "+detail)
+		}
+	}
+	keyCheck := func(reason, path, detail string) {
+		ok, err := reg.KeyExists(path)
+		if err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf("%s: %v", path, err))
+			return
+		}
+		if ok {
+			add(reason, detail)
+		}
+	}
+	values := func(path, name string) []string {
+		v, err := reg.Strings(path, name)
+		if err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf(`%s\%s: %v`, path, name, err))
+		}
+		return v
+	}
+
+	keyCheck(RebootReasonCBS, cbsKey+`\RebootPending`, "Component Based Servicing RebootPending key present")
+	keyCheck(RebootReasonWindowsUpdate, wuAutoUpdateKey+`\RebootRequired`, "Windows Update RebootRequired key present")
+
+	// Entries come in source/destination pairs; an empty destination is a
+	// delete, so only the sources are counted.
+	var sources []string
+	for _, name := range []string{"PendingFileRenameOperations", "PendingFileRenameOperations2"} {
+		for i, v := range values(sessionManagerKey, name) {
+			if i%2 == 0 && strings.TrimSpace(v) != "" {
+				sources = append(sources, v)
+			}
+		}
+	}
+	if len(sources) > 0 {
+		add(RebootReasonFileRename, fmt.Sprintf("%d pending file operation(s), first %s", len(sources), sources[0]))
+	}
+
+	active, pending := values(activeNameKey, "ComputerName"), values(pendingNameKey, "ComputerName")
+	if len(active) > 0 && len(pending) > 0 && !strings.EqualFold(active[0], pending[0]) {
+		add(RebootReasonComputerRename, fmt.Sprintf("%s -> %s", active[0], pending[0]))
+	}
+
+	keyCheck(RebootReasonSCCM, sccmRebootKey, "Configuration Manager client has reboot data")
+
+	out.Pending = len(out.Reasons) > 0
+	return out
+}
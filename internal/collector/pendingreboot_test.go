@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckPendingReboot(t *testing.T) {
+	tests := []struct {
+		name        string
+		reg         FakeRegistry
+		wantReasons []string
+		wantDetails []string
+	}{
+		{"clean", FakeRegistry{
+			Keys:   map[string]bool{cbsKey: true, wuAutoUpdateKey: true},
+			Values: map[string][]string{activeNameKey + `\ComputerName`: {"WEB01"}, pendingNameKey + `\ComputerName`: {"web01"}},
+		}, []string{}, nil},
+		{"cbs and windows update", FakeRegistry{
+			Keys: map[string]bool{cbsKey + `\RebootPending`: true, wuAutoUpdateKey + `\RebootRequired`: true},
+		}, []string{RebootReasonCBS, RebootReasonWindowsUpdate}, []string{
+			"cbs_reboot_pending: Component Based Servicing RebootPending key present",
+			"windows_update_reboot_required: Windows Update RebootRequired key present",
+		}},
+		{"key case ignored", FakeRegistry{
+			Keys: map[string]bool{strings.ToUpper(cbsKey) + `\REBOOTPENDING`: true},
+		}, []string{RebootReasonCBS}, []string{"cbs_reboot_pending: Component Based Servicing RebootPending key present"}},
+		{"file renames count sources only", FakeRegistry{
+			Values: map[string][]string{
+				sessionManagerKey + `\PendingFileRenameOperations`: {
+					`\??\C:\Windows\WinSxS\Temp\PendingDeletes\$$DeleteMe.ntdll.dll`, "",
+					`\??\C:\Windows\System32\drivers\new.sys`, `!\??\C:\Windows\System32\drivers\old.sys`,
+				},
+				sessionManagerKey + `\PendingFileRenameOperations2`: {`\??\C:\Temp\x.tmp`, ""},
+			},
+		}, []string{RebootReasonFileRename}, []string{
+			`pending_file_rename: 3 pending file operation(s), first \??\C:\Windows\WinSxS\Temp\PendingDeletes\$$DeleteMe.ntdll.dll`,
+		}},
+		{"blank renames ignored", FakeRegistry{
+			Values: map[string][]string{sessionManagerKey + `\PendingFileRenameOperations`: {" ", ""}},
+		}, []string{}, nil},
+		{"computer rename", FakeRegistry{
+			Values: map[string][]string{activeNameKey + `\ComputerName`: {"WEB01"}, pendingNameKey + `\ComputerName`: {"WEB02"}},
+		}, []string{RebootReasonComputerRename}, []string{"computer_rename: WEB01 -> WEB02"}},
+		{"pending name only", FakeRegistry{
+			Values: map[string][]string{pendingNameKey + `\ComputerName`: {"WEB02"}},
+		}, []string{}, nil},
+		{"sccm", FakeRegistry{Keys: map[string]bool{sccmRebootKey: true}},
+			[]string{RebootReasonSCCM}, []string{"sccm_reboot_pending: Configuration Manager client has reboot data"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckPendingReboot(tt.reg)
+			if !reflect.DeepEqual(got.Reasons, tt.wantReasons) {
+				t.Errorf("reasons = %q, want %q", got.Reasons, tt.wantReasons)
+			}
+			if !reflect.DeepEqual(got.Details, tt.wantDetails) {
+				t.Errorf("details = %q, want %q", got.Details, tt.wantDetails)
+			}
+			if got.Pending != (len(tt.wantReasons) > 0) {
+				t.Errorf("pending = %v with reasons %q", got.Pending, got.Reasons)
+			}
+			if len(got.Errors) > 0 {
+				t.Errorf("errors = %q", got.Errors)
+			}
+		})
+	}
+}
+
+// brokenRegistry fails every lookup under one path prefix.
+type brokenRegistry struct {
+	FakeRegistry
+	prefix string
+}
+
+func (r brokenRegistry) KeyExists(path string) (bool, error) {
+	if strings.HasPrefix(path, r.prefix) {
+		return false, errors.New("access is denied")
+	}
+	return r.FakeRegistry.KeyExists(path)
+}
+
+func (r brokenRegistry) Strings(path, name string) ([]string, error) {
+	if strings.HasPrefix(path, r.prefix) {
+		return nil, errors.New("access is denied")
+	}
+	return r.FakeRegistry.Strings(path, name)
+}
+
+func TestCheckPendingRebootErrors(t *testing.T) {
+	reg := brokenRegistry{
+		FakeRegistry: FakeRegistry{Keys: map[string]bool{wuAutoUpdateKey + `\RebootRequired`: true}},
+		prefix:       `SYSTEM\`,
+	}
+	got := CheckPendingReboot(reg)
+	if !got.Pending || !reflect.DeepEqual(got.Reasons, []string{RebootReasonWindowsUpdate}) {
+		t.Errorf("reasons = %q, want the readable one kept", got.Reasons)
+	}
+	// Both rename values and both computer names failed.
+	if len(got.Errors) != 4 || !strings.Contains(got.Errors[0], `PendingFileRenameOperations: access is denied`) {
+		t.Errorf("errors = %q", got.Errors)
+	}
+}
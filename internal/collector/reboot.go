@@ -0,0 +1,249 @@
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"winopsguard/internal/bugcheck"
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+)
+
+// Reboot timeline entry kinds.
+const (
+	RebootKernelPower = "kernel_power"
+	RebootUnexpected  = "unexpected_shutdown"
+	RebootBugcheck    = "bugcheck"
+	RebootMinidump    = "minidump"
+)
+
+// rebootGap separates incidents: Kernel-Power 41, EventLog 6008 and BugCheck
+// 1001 for one reboot are logged within minutes of each other.
+const rebootGap = 10 * time.Minute
+
+// maxRebootEvents caps the reboot query; a host that crashed more often than
+// this within the window has made its point.
+const maxRebootEvents = 100
+
+// rebootEventIDs selects Kernel-Power 41, BugCheck 1001 and EventLog 6008.
+// The general System read is capped and bookmarked, so the timeline queries
+// for them separately rather than hoping they survived the cut.
+var rebootEventIDs = []eventquery.IDRange{{Min: 41, Max: 41}, {Min: 1001, Max: 1001}, {Min: 6008, Max: 6008}}
+
+var bugcheckRe = regexp.MustCompile(`(?i)0x([0-9a-f]{1,8})\s*\(([^)]*)\)`)
+
+// CollectRebootHistory builds the reboot timeline from the live System log
+// and the crash dumps under %SystemRoot% written within the window.
+func CollectRebootHistory(window time.Duration) (model.RebootHistory, error) {
+	return CollectRebootHistoryFrom(DefaultSource(), window)
+}
+
+// CollectRebootHistoryFrom is CollectRebootHistory reading events from src.
+// It queries only the reboot event IDs, so they are found however busy the
+// System log was.
+func CollectRebootHistoryFrom(src EventSource, window time.Duration) (model.RebootHistory, error) {
+	var errs []error
+	events, err := src.Read("System", eventquery.Filter{EventIDs: rebootEventIDs, Window: window}, maxRebootEvents)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("system log: %w", err))
+	}
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	dumps, err := ReadDumpHeaders(root, time.Now().Add(-window))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return SummarizeReboots(events, dumps), errors.Join(errs...)
+}
+
+// ReadDumpHeaders reads the header of MEMORY.DMP and every Minidump\*.dmp
+// under root modified since the given time.
+func ReadDumpHeaders(root string, since time.Time) ([]model.RebootEvent, error) {
+	paths, _ := filepath.Glob(filepath.Join(root, "Minidump", "*.dmp"))
+	paths = append(paths, filepath.Join(root, "MEMORY.DMP"))
+	var (
+		out  []model.RebootEvent
+		errs []string
+	)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		ev, err := ParseDumpHeader(f)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		ev.Time = info.ModTime().UTC()
+		ev.DumpPath = path
+		out = append(out, ev)
+	}
+	if len(errs) > 0 {
+		return out, fmt.Errorf("read dump headers: %s", strings.Join(errs, "; "))
+	}
+	return out, nil
+}
+
+// ParseDumpHeader decodes the stop code and parameters from a kernel dump
+// header: PAGEDU64 (64-bit, code at 0x38, 8-byte parameters from 0x40) or
+// PAGEDUMP (32-bit, code at 0x28, 4-byte parameters from 0x2C).
+func ParseDumpHeader(r io.Reader) (model.RebootEvent, error) {
+	var hdr [0x60]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return model.RebootEvent{}, fmt.Errorf("read header: %w", err)
+	}
+	var (
+		code   uint32
+		params []string
+	)
+	switch string(hdr[:8]) {
+	case "PAGEDU64":
+		code = binary.LittleEndian.Uint32(hdr[0x38:])
+		for i := 0; i < 4; i++ {
+			params = append(params, fmt.Sprintf("0x%016x", binary.LittleEndian.Uint64(hdr[0x40+8*i:])))
+		}
+	case "PAGEDUMP":
+		code = binary.LittleEndian.Uint32(hdr[0x28:])
+		for i := 0; i < 4; i++ {
+			params = append(params, fmt.Sprintf("0x%08x", binary.LittleEndian.Uint32(hdr[0x2C+4*i:])))
+		}
+	default:
+		return model.RebootEvent{}, fmt.Errorf("not a kernel dump (signature %q)", hdr[:8])
+	}
+	ev := model.RebootEvent{Kind: RebootMinidump}
+	setBugcheck(&ev, code, params)
+	return ev, nil
+}
+
+func setBugcheck(ev *model.RebootEvent, code uint32, params []string) {
+	ev.BugcheckCode = bugcheck.Format(code)
+	ev.BugcheckName = bugcheck.Name(code)
+	ev.Parameters = bugcheck.Describe(code, params)
+	if info, ok := bugcheck.Lookup(code); ok {
+		ev.Detail = info.Description
+	}
+}
+
+// rebootFromEvent maps Kernel-Power 41, EventLog 6008 and BugCheck 1001
+// (Microsoft-Windows-WER-SystemErrorReporting) to timeline entries.
+func rebootFromEvent(ev model.Event) (model.RebootEvent, bool) {
+	get := func(names ...string) string {
+		for _, n := range names {
+			if v := strings.TrimSpace(ev.Data[n]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	out := model.RebootEvent{Time: ev.Time, EventID: int(ev.EventID)}
+	src := strings.ToLower(ev.Source)
+	switch {
+	case ev.EventID == 41 && strings.Contains(src, "kernel-power"):
+		out.Kind = RebootKernelPower
+		code, ok := bugcheck.Parse(get("BugcheckCode"))
+		if !ok || code == 0 {
+			out.Detail = "no bugcheck recorded: power loss, hard hang or forced reset"
+			return out, true
+		}
+		var params []string
+		for i := 1; i <= 4; i++ {
+			params = append(params, get(fmt.Sprintf("BugcheckParameter%d", i)))
+		}
+		setBugcheck(&out, code, params)
+		return out, true
+	case ev.EventID == 6008 && strings.EqualFold(ev.Source, "EventLog"):
+		out.Kind = RebootUnexpected
+		out.Detail = ev.Message
+		if out.Detail == "" {
+			out.Detail = strings.TrimSpace("previous shutdown was unexpected " + get("param2") + " " + get("param1"))
+		}
+		return out, true
+	case ev.EventID == 1001 && (strings.Contains(src, "systemerrorreporting") || src == "bugcheck"):
+		out.Kind = RebootBugcheck
+		out.DumpPath = get("param2")
+		text := get("param1")
+		if text == "" {
+			text = ev.Message
+		}
+		m := bugcheckRe.FindStringSubmatch(text)
+		if m == nil {
+			out.Detail = text
+			return out, true
+		}
+		code, _ := bugcheck.Parse("0x" + m[1])
+		var params []string
+		for _, p := range strings.Split(m[2], ",") {
+			params = append(params, strings.TrimSpace(p))
+		}
+		setBugcheck(&out, code, params)
+		return out, true
+	}
+	return model.RebootEvent{}, false
+}
+
+// SummarizeReboots merges reboot events and dump headers into a timeline,
+// oldest first. Entries within rebootGap of the previous one belong to the
+// same incident, so one crash is counted once however many records it left.
+func SummarizeReboots(events []model.Event, dumps []model.RebootEvent) model.RebootHistory {
+	out := model.RebootHistory{Timeline: []model.RebootEvent{}}
+	for _, ev := range events {
+		if r, ok := rebootFromEvent(ev); ok {
+			out.Timeline = append(out.Timeline, r)
+		}
+	}
+	out.Timeline = append(out.Timeline, dumps...)
+	sort.SliceStable(out.Timeline, func(i, j int) bool {
+		return out.Timeline[i].Time.Before(out.Timeline[j].Time)
+	})
+
+	var (
+		last        time.Time
+		hasBugcheck bool
+		codes       []string
+	)
+	flush := func() {
+		if hasBugcheck {
+			out.BugcheckCount++
+		}
+		hasBugcheck = false
+	}
+	for _, r := range out.Timeline {
+		if last.IsZero() || r.Time.Sub(last) > rebootGap {
+			flush()
+			out.UnexpectedCount++
+		}
+		last = r.Time
+		if r.BugcheckCode != "" {
+			hasBugcheck = true
+			codes = append(codes, r.BugcheckCode+" "+r.BugcheckName)
+		}
+	}
+	flush()
+	out.BugcheckCodes = uniqueSorted(codes)
+
+	if out.UnexpectedCount == 0 {
+		out.Summary = "No unexpected reboots found"
+		return out
+	}
+	out.LastReboot = last
+	out.Summary = fmt.Sprintf("%d unexpected reboot(s), %d with a bugcheck, last %s; stop codes: %s",
+		out.UnexpectedCount, out.BugcheckCount, last.Format(time.RFC3339), joinOrNone(out.BugcheckCodes))
+	return out
+}
@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+// dumpHeader returns the first page of a kernel dump with the given stop code
+// and parameters, 64-bit unless is32 is set.
+func dumpHeader(is32 bool, code uint32, params ...uint64) []byte {
+	hdr := make([]byte, 0x1000)
+	if is32 {
+		copy(hdr, "PAGEDUMP")
+		binary.LittleEndian.PutUint32(hdr[0x28:], code)
+		for i, p := range params {
+			binary.LittleEndian.PutUint32(hdr[0x2C+4*i:], uint32(p))
+		}
+		return hdr
+	}
+	copy(hdr, "PAGEDU64")
+	binary.LittleEndian.PutUint32(hdr[0x38:], code)
+	for i, p := range params {
+		binary.LittleEndian.PutUint64(hdr[0x40+8*i:], p)
+	}
+	return hdr
+}
+
+func TestParseDumpHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    model.RebootEvent
+		wantErr string
+	}{
+		{"64-bit", dumpHeader(false, 0x9F, 3, 0xffffc00012340080, 0xfffff80312345678, 0xffffc00087654321),
+			model.RebootEvent{Kind: RebootMinidump, BugcheckCode: "0x0000009f", BugcheckName: "DRIVER_POWER_STATE_FAILURE",
+				Detail: "driver did not complete a power IRP in time",
+				Parameters: []string{"subcode=0x0000000000000003", "parameter 2=0xffffc00012340080",
+					"parameter 3=0xfffff80312345678", "parameter 4=0xffffc00087654321"}}, ""},
+		{"32-bit", dumpHeader(true, 0x7B, 0x80786b58, 0xc0000034, 0, 0),
+			model.RebootEvent{Kind: RebootMinidump, BugcheckCode: "0x0000007b", BugcheckName: "INACCESSIBLE_BOOT_DEVICE",
+				Detail:     "boot volume inaccessible; storage driver or boot configuration problem, often after an update",
+				Parameters: []string{"0x80786b58", "0xc0000034", "0x00000000", "0x00000000"}}, ""},
+		{"unknown code", dumpHeader(false, 0x12345),
+			model.RebootEvent{Kind: RebootMinidump, BugcheckCode: "0x00012345", BugcheckName: "UNKNOWN_BUGCHECK",
+				Parameters: []string{"0x0000000000000000", "0x0000000000000000", "0x0000000000000000", "0x0000000000000000"}}, ""},
+		{"user-mode minidump", append([]byte("MDMP\x93\xa7\x00\x00"), make([]byte, 0x100)...), model.RebootEvent{}, "not a kernel dump"},
+		{"short", []byte("PAGEDU64"), model.RebootEvent{}, "read header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDumpHeader(strings.NewReader(string(tt.data)))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectRebootHistoryFrom(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	root := t.TempDir()
+	t.Setenv("SystemRoot", root)
+	if err := os.Mkdir(filepath.Join(root, "Minidump"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dumps := map[string][]byte{
+		"101526-8437-01.dmp": dumpHeader(false, 0x9F, 3),
+		"broken.dmp":         []byte("not a dump at all, but long enough to hold a whole kernel dump header of 0x60 bytes..."),
+	}
+	for name, data := range dumps {
+		path := filepath.Join(root, "Minidump", name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-28*time.Minute), now.Add(-28*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A busy System log: the reboot records are buried under service noise
+	// that would fill any capped general read.
+	var events []model.Event
+	for i := range 500 {
+		events = append(events, model.Event{Time: now.Add(-time.Duration(i) * time.Second), EventID: 7036,
+			Source: "Service Control Manager", Level: "Information", RecordID: uint64(10000 - i)})
+	}
+	events = append(events,
+		model.Event{Time: now.Add(-48 * time.Hour), EventID: 41, Source: "Microsoft-Windows-Kernel-Power", RecordID: 10,
+			Data: map[string]string{"BugcheckCode": "0"}},
+		model.Event{Time: now.Add(-3 * time.Hour), EventID: 41, Source: "Microsoft-Windows-Kernel-Power", RecordID: 20,
+			Data: map[string]string{"BugcheckCode": "0"}},
+		model.Event{Time: now.Add(-30 * time.Minute), EventID: 41, Source: "Microsoft-Windows-Kernel-Power", RecordID: 30,
+			Data: map[string]string{"BugcheckCode": "159", "BugcheckParameter1": "0x3"}},
+		model.Event{Time: now.Add(-29 * time.Minute), EventID: 6008, Source: "EventLog", RecordID: 31,
+			Message: "The previous system shutdown at 10:31:02 on 10/15/2026 was unexpected."},
+		model.Event{Time: now.Add(-28 * time.Minute), EventID: 1001, Source: "Microsoft-Windows-WER-SystemErrorReporting", RecordID: 32,
+			Data: map[string]string{"param1": "0x0000009f (0x0000000000000003, 0xffffc00012340080, 0xfffff80312345678, 0xffffc00087654321)",
+				"param2": `C:\Windows\Minidump\101526-8437-01.dmp`}},
+		// Same ID from another provider: not a reboot.
+		model.Event{Time: now.Add(-time.Hour), EventID: 1001, Source: "Microsoft-Windows-Resource-Exhaustion-Detector", RecordID: 40},
+	)
+	src := FakeSource{Events: map[string][]model.Event{"System": events}, Now: now}
+
+	got, err := CollectRebootHistoryFrom(src, 24*time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "broken.dmp") {
+		t.Errorf("err = %v, want the broken dump named", err)
+	}
+	var kinds []string
+	for _, r := range got.Timeline {
+		kinds = append(kinds, r.Kind)
+	}
+	want := []string{RebootKernelPower, RebootKernelPower, RebootUnexpected, RebootBugcheck, RebootMinidump}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("timeline kinds = %v, want %v", kinds, want)
+	}
+	if got.UnexpectedCount != 2 || got.BugcheckCount != 1 {
+		t.Errorf("unexpected = %d, bugcheck = %d; want 2, 1", got.UnexpectedCount, got.BugcheckCount)
+	}
+	if !reflect.DeepEqual(got.BugcheckCodes, []string{"0x0000009f DRIVER_POWER_STATE_FAILURE"}) {
+		t.Errorf("bugcheck codes = %v", got.BugcheckCodes)
+	}
+	if !got.LastReboot.Equal(now.Add(-28 * time.Minute)) {
+		t.Errorf("last reboot = %v", got.LastReboot)
+	}
+}
+
+func TestSummarizeRebootsEmpty(t *testing.T) {
+	got := SummarizeReboots([]model.Event{{EventID: 7036, Source: "Service Control Manager"}}, nil)
+	if got.UnexpectedCount != 0 || len(got.Timeline) != 0 || got.Summary != "No unexpected reboots found" {
+		t.Errorf("got %+v", got)
+	}
+}
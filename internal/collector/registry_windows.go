@@ -0,0 +1,54 @@
+//go:build windows
+
+package collector
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// DefaultRegistry reads the live HKLM hive.
+func DefaultRegistry() RegistryReader {
+	return WindowsRegistry{}
+}
+
+// WindowsRegistry implements RegistryReader over HKEY_LOCAL_MACHINE.
+type WindowsRegistry struct{}
+
+func (WindowsRegistry) KeyExists(path string) (bool, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	k.Close()
+	return true, nil
+}
+
+func (WindowsRegistry) Strings(path, name string) ([]string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer k.Close()
+
+	vals, _, err := k.GetStringsValue(name)
+	if errors.Is(err, registry.ErrUnexpectedType) {
+		var s string
+		s, _, err = k.GetStringValue(name)
+		vals = []string{s}
+	}
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return vals, nil
+}
@@ -0,0 +1,249 @@
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+// Servicing markers attached to CBS/DISM entries.
+const (
+	MarkerCorrupt         = "corrupt"
+	MarkerRepaired        = "repaired"
+	MarkerManifestMissing = "manifest_missing"
+)
+
+// maxServicingEntries bounds the entries kept per log; counts still cover the
+// whole parsed range.
+const maxServicingEntries = 40
+
+var (
+	// 2024-01-15 10:23:45, Error                 CSI    00000012 (F) ...
+	servicingLineRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}), (\w+)\s+(\S+)\s*(.*)$`)
+	hresultRegex       = regexp.MustCompile(`(?i)\b(?:hresult|hr|error|status|result)\s*[:=]?\s*(0x[0-9a-f]{8})\b`)
+	failureCodeRegex   = regexp.MustCompile(`(?i)\b0x[8c][0-9a-f]{7}\b`)
+	// Session labels and zero counters mention corruption without reporting any.
+	corruptNoiseRegex = regexp.MustCompile(`(?i)corruption (?:detecting|repairing)|[\w ]*corruption\s*:?\s*0\b`)
+	corruptRegex      = regexp.MustCompile(`(?i)corrupt|hashes for file member .* do not match|cannot repair member file`)
+	repairedRegex     = regexp.MustCompile(`(?i)\brepaired\b|\(fixed\)|\[SR\] Repairing|repair complete`)
+	manifestRegex     = regexp.MustCompile(`(?i)manifest missing|missing manifest|failed to (?:get|load|find|open) (?:the )?manifest|SXS_ASSEMBLY_MISSING|CBS_E_MANIFEST`)
+	packageRegex      = regexp.MustCompile(`\b[\w.-]+~[0-9a-f]{16}~\w*~[\w-]*~[\d.]+\b`)
+	assemblyRegex     = regexp.MustCompile(`(?i)\b(?:amd64|x86|wow64|msil|arm64)_[\w.-]+_31bf3856ad364e35_[\d.]+_[\w-]+_[0-9a-f]{16}\b`)
+)
+
+// CollectServicingLogs parses CBS.log and dism.log under %WINDIR%\Logs. Each
+// file contributes at most maxBytes from its end, where the latest servicing
+// run is. A missing log is reported but does not discard the other.
+func CollectServicingLogs(maxBytes int64) (model.ServicingLog, error) {
+	windir := os.Getenv("WINDIR")
+	if windir == "" {
+		windir = `C:\Windows`
+	}
+	var (
+		entries []model.ServicingEntry
+		errs    []error
+	)
+	for _, src := range []struct{ name, path string }{
+		{"cbs", filepath.Join(windir, "Logs", "CBS", "CBS.log")},
+		{"dism", filepath.Join(windir, "Logs", "DISM", "dism.log")},
+	} {
+		got, err := ReadServicingLog(src.path, src.name, maxBytes)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries = append(entries, got...)
+	}
+	out := SummarizeServicing(entries)
+	if len(errs) == 2 {
+		return out, fmt.Errorf("servicing logs unavailable: %w", errors.Join(errs...))
+	}
+	return out, errors.Join(errs...)
+}
+
+// ReadServicingLog parses the last maxBytes of a CBS.log or dism.log file.
+// source labels the entries ("cbs" or "dism").
+func ReadServicingLog(path, source string, maxBytes int64) ([]model.ServicingEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := tailReader(f, maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ParseServicingLog(r, source)
+}
+
+// tailReader positions f at most max bytes before its end, skipping the
+// partial first line. It seeks one byte early so a line starting exactly at
+// the cut is kept.
+func tailReader(f *os.File, max int64) (io.Reader, error) {
+	if max <= 0 {
+		max = 5 * 1024 * 1024
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() <= max {
+		return f, nil
+	}
+	if _, err := f.Seek(info.Size()-max-1, io.SeekStart); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return br, nil
+}
+
+// ParseServicingLog returns the entries of a CBS/DISM log that carry a
+// failure code, a servicing marker or an Error/Warning level. Continuation
+// lines without a timestamp inherit the previous line's time and component.
+func ParseServicingLog(r io.Reader, source string) ([]model.ServicingEntry, error) {
+	var (
+		entries []model.ServicingEntry
+		last    model.ServicingEntry
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry := model.ServicingEntry{Source: source}
+		if m := servicingLineRegex.FindStringSubmatch(line); m != nil {
+			t, err := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local)
+			if err == nil {
+				entry.Time = t.UTC()
+			}
+			entry.Level = m[2]
+			entry.Component = m[3]
+			entry.Message = strings.TrimSpace(m[4])
+			last = entry
+		} else {
+			entry.Time = last.Time
+			entry.Level = last.Level
+			entry.Component = last.Component
+			entry.Message = strings.TrimSpace(line)
+		}
+		classifyServicing(&entry)
+		if entry.HRESULT == "" && len(entry.Markers) == 0 &&
+			!strings.EqualFold(entry.Level, "Error") && !strings.EqualFold(entry.Level, "Warning") {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, sc.Err()
+}
+
+func classifyServicing(e *model.ServicingEntry) {
+	msg := e.Message
+	if m := hresultRegex.FindStringSubmatch(msg); m != nil && !isSuccessCode(m[1]) {
+		e.HRESULT = strings.ToLower(m[1])
+	} else if c := failureCodeRegex.FindString(msg); c != "" {
+		e.HRESULT = strings.ToLower(c)
+	}
+	scrubbed := corruptNoiseRegex.ReplaceAllString(msg, "")
+	if corruptRegex.MatchString(scrubbed) {
+		e.Markers = append(e.Markers, MarkerCorrupt)
+	}
+	if repairedRegex.MatchString(scrubbed) {
+		e.Markers = append(e.Markers, MarkerRepaired)
+	}
+	if manifestRegex.MatchString(msg) {
+		e.Markers = append(e.Markers, MarkerManifestMissing)
+	}
+	e.Packages = uniqueSorted(append(packageRegex.FindAllString(msg, -1), assemblyRegex.FindAllString(msg, -1)...))
+}
+
+func isSuccessCode(code string) bool {
+	return strings.Trim(strings.ToLower(code)[2:], "0") == ""
+}
+
+// SummarizeServicing counts markers and codes over all entries and keeps the
+// newest maxServicingEntries per source.
+func SummarizeServicing(entries []model.ServicingEntry) model.ServicingLog {
+	out := model.ServicingLog{}
+	codes := map[string]bool{}
+	var packages []string
+	perSource := map[string][]model.ServicingEntry{}
+	for _, e := range entries {
+		for _, m := range e.Markers {
+			switch m {
+			case MarkerCorrupt:
+				out.CorruptCount++
+			case MarkerRepaired:
+				out.RepairedCount++
+			case MarkerManifestMissing:
+				out.ManifestMissingCount++
+			}
+		}
+		if e.HRESULT != "" {
+			codes[e.HRESULT] = true
+		}
+		packages = append(packages, e.Packages...)
+		perSource[e.Source] = append(perSource[e.Source], e)
+	}
+	for code := range codes {
+		out.ErrorCodes = append(out.ErrorCodes, code)
+	}
+	sort.Strings(out.ErrorCodes)
+	out.Packages = uniqueSorted(packages)
+
+	sources := make([]string, 0, len(perSource))
+	for s := range perSource {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	for _, s := range sources {
+		list := perSource[s]
+		if len(list) > maxServicingEntries {
+			list = list[len(list)-maxServicingEntries:]
+		}
+		out.Entries = append(out.Entries, list...)
+	}
+
+	out.Summary = "No servicing errors found in CBS/DISM logs"
+	if len(entries) > 0 {
+		out.Summary = fmt.Sprintf("CBS/DISM: %d corrupt, %d repaired, %d manifest missing; error codes: %s",
+			out.CorruptCount, out.RepairedCount, out.ManifestMissingCount, joinOrNone(out.ErrorCodes))
+	}
+	return out
+}
+
+func uniqueSorted(in []string) []string {
+	if len(in) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,198 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+func TestClassifyServicing(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      string
+		code     string
+		markers  []string
+		packages []string
+	}{
+		{"hresult label", "Failed to resolve package [HRESULT = 0x800f0831 - CBS_E_STORE_CORRUPTION]",
+			"0x800f0831", []string{MarkerCorrupt}, nil},
+		{"hr in call site", "Failed to restore the image health. - CPackageManagerCLIHandler::ProcessCmdLine_CleanupImage(hr:0x800F081F)",
+			"0x800f081f", nil, nil},
+		{"success code ignored", "Session finalized. Reboot required: no [HRESULT = 0x00000000 - S_OK]", "", nil, nil},
+		{"bare failure code", "Exec: Installation failed 0x80073712", "0x80073712", nil, nil},
+		{"ntstatus", "Could not open file, status 0xc0000034", "0xc0000034", nil, nil},
+		{"not a failure code", "Loaded servicing stack v10.0.19041.3570 with flags 0x00000010", "", nil, nil},
+		{"hash mismatch", "Hashes for file member [l:12]'msvcp140.dll' do not match.", "", []string{MarkerCorrupt}, nil},
+		{"repair", "[SR] Repairing corrupted file \\??\\C:\\Windows\\System32\\msvcp140.dll from store", "",
+			[]string{MarkerCorrupt, MarkerRepaired}, nil},
+		{"fixed", "CSI Payload Corrupt (Fixed) amd64_microsoft-windows-vcruntime_31bf3856ad364e35_10.0.19041.1_none_0123456789abcdef\\msvcp140.dll", "",
+			[]string{MarkerCorrupt, MarkerRepaired}, []string{"amd64_microsoft-windows-vcruntime_31bf3856ad364e35_10.0.19041.1_none_0123456789abcdef"}},
+		{"session label", "Corruption detecting and repairing session started", "", nil, nil},
+		{"zero counter", "Total Detected Corruption:\t0", "", nil, nil},
+		{"manifest", "Failed to get the manifest for package: Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.3636.1.8", "",
+			[]string{MarkerManifestMissing}, []string{"Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.3636.1.8"}},
+		{"assembly missing", "Failed to install: ERROR_SXS_ASSEMBLY_MISSING", "", []string{MarkerManifestMissing}, nil},
+		{"packages sorted and unique", "Package_for_KB2~31bf3856ad364e35~amd64~~1.0 Package_for_KB1~31bf3856ad364e35~amd64~~1.0 Package_for_KB2~31bf3856ad364e35~amd64~~1.0", "", nil,
+			[]string{"Package_for_KB1~31bf3856ad364e35~amd64~~1.0", "Package_for_KB2~31bf3856ad364e35~amd64~~1.0"}},
+	}
+	for _, tt := range tests {
+		e := model.ServicingEntry{Message: tt.msg}
+		classifyServicing(&e)
+		if e.HRESULT != tt.code || !reflect.DeepEqual(e.Markers, tt.markers) || !reflect.DeepEqual(e.Packages, tt.packages) {
+			t.Errorf("%s: got code %q markers %v packages %v, want %q %v %v", tt.name, e.HRESULT, e.Markers, e.Packages, tt.code, tt.markers, tt.packages)
+		}
+	}
+}
+
+// local is a log timestamp: CBS and DISM write local time.
+func local(clock string) time.Time {
+	t, err := time.ParseInLocation(time.DateTime, "2026-10-01 "+clock, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t.UTC()
+}
+
+func TestReadServicingLog(t *testing.T) {
+	type entry struct {
+		time      time.Time
+		level     string
+		component string
+		code      string
+		markers   []string
+	}
+	tests := []struct {
+		file string
+		want []entry
+	}{
+		{"CBS.log", []entry{
+			{local("10:00:07"), "Info", "CSI", "", []string{MarkerCorrupt}},
+			{local("10:00:07"), "Info", "CSI", "", []string{MarkerCorrupt}},
+			{local("10:00:09"), "Info", "CBS", "", []string{MarkerManifestMissing}},
+			{local("10:00:10"), "Error", "CBS", "", nil},
+			// The continuation line inherits the Error line's time and
+			// component and carries the code.
+			{local("10:00:10"), "Error", "CBS", "0x800f0831", []string{MarkerCorrupt}},
+			{local("10:00:14"), "Info", "CSI", "", []string{MarkerCorrupt, MarkerRepaired}},
+			{local("10:00:15"), "Warning", "CBS", "", nil},
+			{local("10:00:16"), "Info", "CBS", "0x800f0922", nil},
+		}},
+		{"dism.log", []entry{
+			{local("10:12:30"), "Error", "DISM", "0x800f081f", nil},
+			{local("10:12:30"), "Error", "DISM", "0x800f081f", nil},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			entries, err := ReadServicingLog(filepath.Join("testdata", tt.file), "cbs", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []entry
+			for _, e := range entries {
+				if e.Source != "cbs" {
+					t.Errorf("source = %q", e.Source)
+				}
+				got = append(got, entry{e.Time, e.Level, e.Component, e.HRESULT, e.Markers})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries:\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := ReadServicingLog(filepath.Join("testdata", "missing.log"), "cbs", 0); err == nil {
+		t.Error("missing file: no error")
+	}
+}
+
+func TestReadServicingLogTail(t *testing.T) {
+	path := filepath.Join("testdata", "dism.log")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := int64(len(data) - strings.Index(string(data), "2026-10-01 10:12:30, Error                 DISM   DISM Package Manager: PID=4242 TID=1234 Failed while"))
+	tests := []struct {
+		name     string
+		maxBytes int64
+		want     int
+	}{
+		{"whole file", int64(len(data)), 2},
+		{"cut on a line boundary", second, 1},
+		{"cut mid-line", second + 10, 1},
+		{"only the closing lines", 100, 0},
+	}
+	for _, tt := range tests {
+		entries, err := ReadServicingLog(path, "dism", tt.maxBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("%s: %d entries, want %d", tt.name, len(entries), tt.want)
+		}
+		if len(entries) == 1 && !strings.Contains(entries[0].Message, "Failed while processing") {
+			t.Errorf("%s: kept %q", tt.name, entries[0].Message)
+		}
+	}
+}
+
+func TestSummarizeServicing(t *testing.T) {
+	cbs, err := ReadServicingLog(filepath.Join("testdata", "CBS.log"), "cbs", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dism, err := ReadServicingLog(filepath.Join("testdata", "dism.log"), "dism", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := SummarizeServicing(append(dism, cbs...))
+	if got.CorruptCount != 4 || got.RepairedCount != 1 || got.ManifestMissingCount != 1 {
+		t.Errorf("counts = %d corrupt, %d repaired, %d manifest missing", got.CorruptCount, got.RepairedCount, got.ManifestMissingCount)
+	}
+	if want := []string{"0x800f081f", "0x800f0831", "0x800f0922"}; !reflect.DeepEqual(got.ErrorCodes, want) {
+		t.Errorf("codes = %v, want %v", got.ErrorCodes, want)
+	}
+	wantPackages := []string{
+		"Package_for_KB5031356~31bf3856ad364e35~amd64~~19041.3570.1.7",
+		"Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.3636.1.8",
+		"amd64_microsoft-windows-vcruntime_31bf3856ad364e35_10.0.19041.1_none_0123456789abcdef",
+	}
+	if !reflect.DeepEqual(got.Packages, wantPackages) {
+		t.Errorf("packages = %v, want %v", got.Packages, wantPackages)
+	}
+	// Entries are grouped by source in name order.
+	if len(got.Entries) != 10 || got.Entries[0].Source != "cbs" || got.Entries[9].Source != "dism" {
+		t.Errorf("entries = %d, first %q last %q", len(got.Entries), got.Entries[0].Source, got.Entries[len(got.Entries)-1].Source)
+	}
+	if want := "CBS/DISM: 4 corrupt, 1 repaired, 1 manifest missing; error codes: 0x800f081f, 0x800f0831, 0x800f0922"; got.Summary != want {
+		t.Errorf("summary = %q, want %q", got.Summary, want)
+	}
+
+	if empty := SummarizeServicing(nil); empty.Summary != "No servicing errors found in CBS/DISM logs" || empty.Entries != nil {
+		t.Errorf("empty = %+v", empty)
+	}
+}
+
+func TestSummarizeServicingCap(t *testing.T) {
+	var entries []model.ServicingEntry
+	for i := range maxServicingEntries + 5 {
+		entries = append(entries, model.ServicingEntry{Source: "cbs", Level: "Error", HRESULT: "0x800f0831", Message: string(rune('a' + i%26))})
+	}
+	entries = append(entries, model.ServicingEntry{Source: "dism", Level: "Error", Markers: []string{MarkerCorrupt}})
+	got := SummarizeServicing(entries)
+	if len(got.Entries) != maxServicingEntries+1 {
+		t.Fatalf("%d entries, want %d", len(got.Entries), maxServicingEntries+1)
+	}
+	// The newest entries of each source are kept; counts cover all of them.
+	if got.Entries[0].Message != entries[5].Message || got.Entries[maxServicingEntries-1].Message != entries[maxServicingEntries+4].Message {
+		t.Errorf("kept %q..%q", got.Entries[0].Message, got.Entries[maxServicingEntries-1].Message)
+	}
+	if got.CorruptCount != 1 || !reflect.DeepEqual(got.ErrorCodes, []string{"0x800f0831"}) {
+		t.Errorf("counts = %+v", got)
+	}
+}
@@ -0,0 +1,229 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+	"winopsguard/internal/store"
+	"winopsguard/internal/summarizer"
+)
+
+// defaultMaxEvents caps a read when the caller passes no budget.
+const defaultMaxEvents = 256
+
+// EventSource reads the events of one channel that match filter, at most max
+// of them. Without a bookmark (filter.AfterRecordID == 0) the newest events
+// are selected; with one, the oldest events after it, so a capped read
+// resumes where it stopped. Either way they are returned newest first, the
+// order the summarizer expects.
+type EventSource interface {
+	Read(channel string, filter eventquery.Filter, max int) ([]model.Event, error)
+}
+
+// NewestRecorder is implemented by sources that can report a channel's
+// newest EventRecordID (0 when it is empty), which ReadChannel uses to notice
+// a cleared log.
+type NewestRecorder interface {
+	NewestRecordID(channel string) (uint64, error)
+}
+
+// FallbackSource tries each source in order and returns the first success.
+type FallbackSource []EventSource
+
+func (s FallbackSource) Read(channel string, filter eventquery.Filter, max int) ([]model.Event, error) {
+	var errs []error
+	for _, src := range s {
+		events, err := src.Read(channel, filter, max)
+		if err == nil {
+			return events, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no event source configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// NewestRecordID asks each source that can tell, in order.
+func (s FallbackSource) NewestRecordID(channel string) (uint64, error) {
+	var errs []error
+	for _, src := range s {
+		nr, ok := src.(NewestRecorder)
+		if !ok {
+			continue
+		}
+		id, err := nr.NewestRecordID(channel)
+		if err == nil {
+			return id, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return 0, errors.New("no source reports record IDs")
+	}
+	return 0, errors.Join(errs...)
+}
+
+// EVTXSource reads an exported .evtx file. The channel argument is ignored:
+// an export holds whatever the file was saved from.
+type EVTXSource struct {
+	Path string
+	// Warn, if set, is called for each damaged chunk or record skipped.
+	Warn func(error)
+}
+
+func (s EVTXSource) Read(_ string, filter eventquery.Filter, max int) ([]model.Event, error) {
+	return readEVTXFile(s.Path, filter, max, s.Warn)
+}
+
+// FakeSource serves events from memory, keyed by channel. It applies the
+// filter the way the live sources do, so collection and summarization can be
+// exercised without a Windows event log.
+type FakeSource struct {
+	Events map[string][]model.Event
+	// Now anchors the filter's time window; zero means time.Now().
+	Now time.Time
+}
+
+func (s FakeSource) Read(channel string, filter eventquery.Filter, max int) ([]model.Event, error) {
+	if max <= 0 {
+		max = defaultMaxEvents
+	}
+	now := s.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var matched []model.Event
+	for name, events := range s.Events {
+		if !strings.EqualFold(name, channel) {
+			continue
+		}
+		for _, ev := range events {
+			if filter.Window > 0 && filter.AfterRecordID == 0 && now.Sub(ev.Time) > filter.Window {
+				continue
+			}
+			if !filter.Match(eventFields(ev)) {
+				continue
+			}
+			if ev.Channel == "" {
+				ev.Channel = channel
+			}
+			matched = append(matched, ev)
+		}
+	}
+	if filter.AfterRecordID > 0 {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].RecordID < matched[j].RecordID })
+		if len(matched) > max {
+			matched = matched[:max]
+		}
+		slices.Reverse(matched)
+		return matched, nil
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Time.After(matched[j].Time) })
+	if len(matched) > max {
+		matched = matched[:max]
+	}
+	return matched, nil
+}
+
+// NewestRecordID returns the highest record ID stored for channel.
+func (s FakeSource) NewestRecordID(channel string) (uint64, error) {
+	var newest uint64
+	for name, events := range s.Events {
+		if !strings.EqualFold(name, channel) {
+			continue
+		}
+		for _, ev := range events {
+			newest = max(newest, ev.RecordID)
+		}
+	}
+	return newest, nil
+}
+
+// eventFields recovers the filterable System values of a normalized event.
+func eventFields(ev model.Event) eventquery.Fields {
+	level, err := eventquery.ParseLevel(ev.Level)
+	if err != nil {
+		level = eventquery.LevelInformation
+	}
+	keywords, _ := strconv.ParseUint(strings.TrimSpace(ev.Keywords), 0, 64)
+	return eventquery.Fields{
+		Provider: ev.Source,
+		EventID:  ev.EventID,
+		Level:    level,
+		Keywords: keywords,
+		RecordID: ev.RecordID,
+	}
+}
+
+// CollectEventLogs returns summarized System and Application logs.
+func CollectEventLogs(window time.Duration, maxEvents int) (model.LogSet, model.LogSet, error) {
+	return CollectEventLogsSince(window, maxEvents, nil)
+}
+
+// CollectEventLogsSince is CollectEventLogs with bookmarks: a bookmarked
+// channel returns only records newer than its bookmark, and the bookmark is
+// advanced in memory. The caller saves marks once the payload is
+// safely queued.
+func CollectEventLogsSince(window time.Duration, maxEvents int, marks *store.Bookmarks) (model.LogSet, model.LogSet, error) {
+	return CollectEventLogsFrom(DefaultSource(), window, maxEvents, marks)
+}
+
+// CollectEventLogsFrom is CollectEventLogsSince reading from src.
+func CollectEventLogsFrom(src EventSource, window time.Duration, maxEvents int, marks *store.Bookmarks) (model.LogSet, model.LogSet, error) {
+	sysEvents, err := ReadChannel(src, "System", eventquery.Filter{Window: window}, maxEvents, marks)
+	if err != nil {
+		return model.LogSet{}, model.LogSet{}, fmt.Errorf("system log: %w", err)
+	}
+
+	appEvents, err := ReadChannel(src, "Application", eventquery.Filter{Window: window}, maxEvents, marks)
+	if err != nil {
+		return model.LogSet{}, model.LogSet{}, fmt.Errorf("application log: %w", err)
+	}
+
+	sysLog := summarizer.SummarizeEvents(summarizer.TrimWhitespace(sysEvents), maxEvents)
+	appLog := summarizer.SummarizeEvents(summarizer.TrimWhitespace(appEvents), maxEvents)
+	return sysLog, appLog, nil
+}
+
+// ReadChannel reads one channel from src. With marks, a bookmarked channel
+// returns only newer records (regardless of the time window) and its bookmark
+// advances to the last record returned. When a bookmarked read finds nothing
+// and src reports the channel's newest record below the bookmark, the log was
+// cleared: the bookmark is dropped and the channel read again as if it had
+// none.
+func ReadChannel(src EventSource, channel string, filter eventquery.Filter, maxEvents int, marks *store.Bookmarks) ([]model.Event, error) {
+	bookmarked := filter
+	if marks != nil {
+		if id, ok := marks.Get(channel); ok {
+			bookmarked.AfterRecordID = id
+			bookmarked.Window = 0
+		}
+	}
+	events, err := src.Read(channel, bookmarked, maxEvents)
+	if err != nil {
+		return nil, err
+	}
+	if nr, ok := src.(NewestRecorder); ok && marks != nil && len(events) == 0 && bookmarked.AfterRecordID > 0 {
+		newest, err := nr.NewestRecordID(channel)
+		if err == nil && marks.Rewind(channel, newest) {
+			if events, err = src.Read(channel, filter, maxEvents); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if marks != nil {
+		for _, ev := range events {
+			marks.Advance(channel, ev.RecordID)
+		}
+	}
+	return events, nil
+}
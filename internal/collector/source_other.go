@@ -0,0 +1,67 @@
+//go:build !windows
+
+package collector
+
+import (
+	"errors"
+	"time"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+)
+
+// DefaultSource has no live event log outside Windows; use EVTXSource or
+// FakeSource instead.
+func DefaultSource() EventSource {
+	return unavailableSource{}
+}
+
+type unavailableSource struct{}
+
+func (unavailableSource) Read(string, eventquery.Filter, int) ([]model.Event, error) {
+	return nil, errors.New("live event log collection requires Windows; use -file with an exported .evtx")
+}
+
+// CollectWULog needs Get-WindowsUpdateLog; outside Windows, parse a copied
+// log with CollectWULogFile instead.
+func CollectWULog(string, int64) (model.WULog, error) {
+	return model.WULog{}, errors.New("Get-WindowsUpdateLog requires Windows; use CollectWULogFile")
+}
+
+// DefaultRegistry has no registry outside Windows; use FakeRegistry instead.
+func DefaultRegistry() RegistryReader {
+	return unavailableRegistry{}
+}
+
+type unavailableRegistry struct{}
+
+func (unavailableRegistry) KeyExists(string) (bool, error) {
+	return false, errors.New("registry access requires Windows")
+}
+
+func (unavailableRegistry) Strings(string, string) ([]string, error) {
+	return nil, errors.New("registry access requires Windows")
+}
+
+// DefaultHealth has no host to measure outside Windows; use FakeHealth instead.
+func DefaultHealth() HealthProvider {
+	return unavailableHealth{}
+}
+
+var errNoHealth = errors.New("host health requires Windows")
+
+type unavailableHealth struct{}
+
+func (unavailableHealth) SystemDrive() (string, uint64, uint64, error) { return "", 0, 0, errNoHealth }
+
+func (unavailableHealth) WinSxSSize() (uint64, bool, error) { return 0, false, errNoHealth }
+
+func (unavailableHealth) Memory() (model.MemoryHealth, error) {
+	return model.MemoryHealth{}, errNoHealth
+}
+
+func (unavailableHealth) Uptime() (time.Duration, error) { return 0, errNoHealth }
+
+func (unavailableHealth) Service(string) (model.ServiceHealth, error) {
+	return model.ServiceHealth{}, errNoHealth
+}
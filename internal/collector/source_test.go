@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+	"winopsguard/internal/store"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// systemEvents returns events with record IDs first..last, one minute apart,
+// the last one a minute before testNow.
+func systemEvents(first, last uint64) []model.Event {
+	var events []model.Event
+	for id := first; id <= last; id++ {
+		events = append(events, model.Event{
+			Time:     testNow.Add(-time.Duration(last-id+1) * time.Minute),
+			Level:    "Error",
+			EventID:  7031,
+			Source:   "Service Control Manager",
+			Message:  "The service terminated unexpectedly.",
+			RecordID: id,
+		})
+	}
+	return events
+}
+
+func TestReadChannelClearedLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   uint64
+		events   []model.Event
+		wantIDs  []uint64
+		wantMark uint64
+		wantLog  bool
+	}{
+		{"new records", 3, systemEvents(1, 5), []uint64{5, 4}, 5, false},
+		{"capped resumes after bookmark", 1, systemEvents(1, 30), []uint64{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}, 11, false},
+		{"caught up", 5, systemEvents(1, 5), nil, 5, false},
+		{"cleared", 5000, systemEvents(1, 3), []uint64{3, 2, 1}, 3, true},
+		{"cleared and empty", 5000, nil, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := FakeSource{Events: map[string][]model.Event{"System": tt.events}, Now: testNow}
+			marks, err := store.LoadBookmarks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			logged := false
+			marks.Logf = func(string, ...any) { logged = true }
+			marks.Advance("System", tt.stored)
+
+			events, err := ReadChannel(src, "System", eventquery.Filter{Window: time.Hour}, 10, marks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := recordIDs(events); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("record ids = %v, want %v", got, tt.wantIDs)
+			}
+			if got, _ := marks.Get("System"); got != tt.wantMark {
+				t.Errorf("bookmark = %d, want %d", got, tt.wantMark)
+			}
+			if logged != tt.wantLog {
+				t.Errorf("logged = %v, want %v", logged, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestFakeSourceOrder(t *testing.T) {
+	src := FakeSource{Events: map[string][]model.Event{"System": systemEvents(1, 6)}, Now: testNow}
+	tests := []struct {
+		name    string
+		channel string
+		filter  eventquery.Filter
+		max     int
+		want    []uint64
+	}{
+		{"newest", "system", eventquery.Filter{}, 3, []uint64{6, 5, 4}},
+		{"window", "System", eventquery.Filter{Window: 150 * time.Second}, 0, []uint64{6, 5}},
+		{"oldest after bookmark", "System", eventquery.Filter{AfterRecordID: 2}, 3, []uint64{5, 4, 3}},
+		{"bookmark ignores window", "System", eventquery.Filter{AfterRecordID: 4, Window: time.Second}, 0, []uint64{6, 5}},
+		{"other channel", "Application", eventquery.Filter{}, 0, nil},
+	}
+	for _, tt := range tests {
+		events, err := src.Read(tt.channel, tt.filter, tt.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := recordIDs(events); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: record ids = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectEventLogsFrom(t *testing.T) {
+	app := []model.Event{
+		{Time: testNow.Add(-3 * time.Hour), Level: "Error", EventID: 1000, Source: "Application Error", Message: "Faulting application name: w3wp.exe", RecordID: 70},
+		{Time: testNow.Add(-20 * time.Minute), Level: "Error", EventID: 1000, Source: "Application Error", Message: "Faulting application name: w3wp.exe", RecordID: 71},
+		{Time: testNow.Add(-10 * time.Minute), Level: "Warning", EventID: 1530, Source: "User Profile Service", Message: "Windows detected your registry file is still in use.", RecordID: 72},
+	}
+	src := FakeSource{Events: map[string][]model.Event{"System": systemEvents(1, 4), "Application": app}, Now: testNow}
+
+	marks, err := store.LoadBookmarks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, appLog, err := CollectEventLogsFrom(src, time.Hour, 10, marks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recordIDs(sys.Raw); !reflect.DeepEqual(got, []uint64{4, 3, 2, 1}) {
+		t.Errorf("system record ids = %v", got)
+	}
+	// The three-hour-old crash is outside the window.
+	if got := recordIDs(appLog.Raw); !reflect.DeepEqual(got, []uint64{72, 71}) {
+		t.Errorf("application record ids = %v", got)
+	}
+	if appLog.LevelCounts["Error"] != 1 || appLog.LevelCounts["Warning"] != 1 {
+		t.Errorf("application level counts = %v", appLog.LevelCounts)
+	}
+	if len(sys.Recent) != 1 || sys.Recent[0].RecordID != 4 {
+		t.Errorf("system recent = %+v, want the newest of the repeated event", sys.Recent)
+	}
+	for ch, want := range map[string]uint64{"System": 4, "Application": 72} {
+		if got, _ := marks.Get(ch); got != want {
+			t.Errorf("%s bookmark = %d, want %d", ch, got, want)
+		}
+	}
+
+	// A second run with bookmarks returns only what is new, ignoring the window.
+	src.Events["Application"] = append(app, model.Event{Time: testNow.Add(-5 * time.Hour), Level: "Error", EventID: 1026, Source: ".NET Runtime", Message: "Application: app.exe", RecordID: 73})
+	_, appLog, err = CollectEventLogsFrom(src, time.Hour, 10, marks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recordIDs(appLog.Raw); !reflect.DeepEqual(got, []uint64{73}) {
+		t.Errorf("bookmarked application record ids = %v, want [73]", got)
+	}
+}
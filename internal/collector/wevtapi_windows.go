@@ -0,0 +1,341 @@
+//go:build windows
+
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+)
+
+const (
+	evtQueryChannelPath              = 0x1
+	evtQueryReverseDirection         = 0x200
+	evtQueryTolerateQueryErrs        = 0x1000
+	evtRenderEventXML                = 1
+	evtFormatMessageEvent            = 1
+	evtNextBatchSize          uint32 = 16
+)
+
+var (
+	modWevtapi                   = windows.NewLazySystemDLL("wevtapi.dll")
+	procEvtQuery                 = modWevtapi.NewProc("EvtQuery")
+	procEvtNext                  = modWevtapi.NewProc("EvtNext")
+	procEvtRender                = modWevtapi.NewProc("EvtRender")
+	procEvtClose                 = modWevtapi.NewProc("EvtClose")
+	procEvtOpenPublisherMetadata = modWevtapi.NewProc("EvtOpenPublisherMetadata")
+	procEvtFormatMessage         = modWevtapi.NewProc("EvtFormatMessage")
+)
+
+type publisherCache struct {
+	mu      sync.Mutex
+	handles map[string]windows.Handle
+}
+
+func (c *publisherCache) get(provider string) (windows.Handle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handles == nil {
+		c.handles = make(map[string]windows.Handle)
+	}
+	if h, ok := c.handles[provider]; ok {
+		return h, nil
+	}
+	ptr, err := windows.UTF16PtrFromString(provider)
+	if err != nil {
+		return 0, fmt.Errorf("publisher UTF16: %w", err)
+	}
+	r, _, callErr := procEvtOpenPublisherMetadata.Call(
+		0, // local session
+		uintptr(unsafe.Pointer(ptr)),
+		0, // log file path
+		0, // locale
+		0, // flags
+	)
+	if r == 0 {
+		// Remember the miss so every event of this provider does not retry.
+		c.handles[provider] = 0
+		return 0, fmt.Errorf("EvtOpenPublisherMetadata: %w", callErr)
+	}
+	h := windows.Handle(r)
+	c.handles[provider] = h
+	return h, nil
+}
+
+func (c *publisherCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, h := range c.handles {
+		evtCloseHandle(h)
+		delete(c.handles, k)
+	}
+}
+
+// DefaultSource reads the live event log through wevtapi.dll, falling back to
+// PowerShell when the native API fails.
+func DefaultSource() EventSource {
+	return FallbackSource{WevtapiSource{}, PowerShellSource{}}
+}
+
+// WevtapiSource reads a channel with EvtQuery and formats messages with the
+// publisher's message table.
+type WevtapiSource struct{}
+
+func (WevtapiSource) Read(logName string, filter eventquery.Filter, maxEvents int) ([]model.Event, error) {
+	if maxEvents <= 0 {
+		maxEvents = defaultMaxEvents
+	}
+
+	queryStr, err := filter.XPath()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+	queryPtr, err := windows.UTF16PtrFromString(queryStr)
+	if err != nil {
+		return nil, fmt.Errorf("query UTF16: %w", err)
+	}
+	pathPtr, err := windows.UTF16PtrFromString(logName)
+	if err != nil {
+		return nil, fmt.Errorf("path UTF16: %w", err)
+	}
+
+	// Newest first, except bookmarked reads which resume oldest first and are
+	// reversed below.
+	flags := uintptr(evtQueryChannelPath | evtQueryTolerateQueryErrs)
+	if filter.AfterRecordID == 0 {
+		flags |= evtQueryReverseDirection
+	}
+	hQuery, err := evtQuery(pathPtr, queryPtr, flags)
+	if err != nil {
+		return nil, err
+	}
+	defer evtCloseHandle(hQuery)
+
+	var (
+		results = make([]model.Event, 0, maxEvents)
+		cache   publisherCache
+	)
+	defer cache.close()
+
+	for len(results) < maxEvents {
+		handles, err := evtNextBatch(hQuery, evtNextBatchSize)
+		if err != nil {
+			if err == windows.ERROR_NO_MORE_ITEMS {
+				break
+			}
+			return nil, err
+		}
+		results, err = appendBatch(results, handles, maxEvents, logName, &cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filter.AfterRecordID > 0 {
+		slices.Reverse(results)
+	}
+	return results, nil
+}
+
+// appendBatch parses the events of one EvtNext batch into results, up to
+// maxEvents in total. Every handle of the batch is closed, including those
+// left unparsed when the cap is reached or an event fails to parse.
+func appendBatch(results []model.Event, handles []windows.Handle, maxEvents int, logName string, cache *publisherCache) ([]model.Event, error) {
+	defer evtCloseHandles(handles)
+	for _, hEvt := range handles {
+		if len(results) >= maxEvents {
+			break
+		}
+		rec, err := parseEvent(hEvt, logName, cache)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rec)
+	}
+	return results, nil
+}
+
+// NewestRecordID reads the record ID of the channel's newest event.
+func (WevtapiSource) NewestRecordID(logName string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(logName)
+	if err != nil {
+		return 0, fmt.Errorf("path UTF16: %w", err)
+	}
+	queryPtr, err := windows.UTF16PtrFromString("*")
+	if err != nil {
+		return 0, fmt.Errorf("query UTF16: %w", err)
+	}
+	hQuery, err := evtQuery(pathPtr, queryPtr, evtQueryChannelPath|evtQueryReverseDirection)
+	if err != nil {
+		return 0, err
+	}
+	defer evtCloseHandle(hQuery)
+
+	handles, err := evtNextBatch(hQuery, 1)
+	if err == windows.ERROR_NO_MORE_ITEMS {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer evtCloseHandle(handles[0])
+	xmlText, err := renderEventXML(handles[0])
+	if err != nil {
+		return 0, err
+	}
+	ev, err := ParseEventXML([]byte(xmlText))
+	if err != nil {
+		return 0, err
+	}
+	return ev.RecordID, nil
+}
+
+func evtQuery(path, query *uint16, flags uintptr) (windows.Handle, error) {
+	r, _, err := procEvtQuery.Call(
+		0,
+		uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(query)),
+		flags,
+	)
+	if r == 0 {
+		return 0, fmt.Errorf("EvtQuery: %w", err)
+	}
+	return windows.Handle(r), nil
+}
+
+func evtNextBatch(hQuery windows.Handle, batch uint32) ([]windows.Handle, error) {
+	handles := make([]windows.Handle, batch)
+	var returned uint32
+	r, _, err := procEvtNext.Call(
+		uintptr(hQuery),
+		uintptr(batch),
+		uintptr(unsafe.Pointer(&handles[0])),
+		2000, // 2s timeout
+		0,
+		uintptr(unsafe.Pointer(&returned)),
+	)
+	if r == 0 {
+		if errno, ok := err.(windows.Errno); ok && errno == windows.ERROR_NO_MORE_ITEMS {
+			return nil, windows.ERROR_NO_MORE_ITEMS
+		}
+		return nil, fmt.Errorf("EvtNext: %w", err)
+	}
+	return handles[:returned], nil
+}
+
+func parseEvent(hEvt windows.Handle, logName string, cache *publisherCache) (model.Event, error) {
+	xmlText, err := renderEventXML(hEvt)
+	if err != nil {
+		return model.Event{}, err
+	}
+	ev, err := ParseEventXML([]byte(xmlText))
+	if err != nil {
+		return model.Event{}, err
+	}
+	if ev.Channel == "" {
+		ev.Channel = logName
+	}
+
+	// Providers without a registered message table (or with a stale one) keep
+	// the message rebuilt from EventData rather than failing the whole read.
+	meta, err := cache.get(ev.Source)
+	if err != nil || meta == 0 {
+		return ev, nil
+	}
+	if msg, err := formatMessage(meta, hEvt); err == nil && msg != "" {
+		ev.Message = msg
+	}
+	return ev, nil
+}
+
+func renderEventXML(hEvt windows.Handle) (string, error) {
+	var bufferUsed uint32
+	var propCount uint32
+
+	r, _, err := procEvtRender.Call(
+		0,
+		uintptr(hEvt),
+		evtRenderEventXML,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&bufferUsed)),
+		uintptr(unsafe.Pointer(&propCount)),
+	)
+	if r == 0 {
+		if errno, ok := err.(windows.Errno); !ok || errno != windows.ERROR_INSUFFICIENT_BUFFER {
+			return "", fmt.Errorf("EvtRender(size): %w", err)
+		}
+	}
+
+	buffer := make([]uint16, bufferUsed)
+	r, _, err = procEvtRender.Call(
+		0,
+		uintptr(hEvt),
+		evtRenderEventXML,
+		uintptr(bufferUsed),
+		uintptr(unsafe.Pointer(&buffer[0])),
+		uintptr(unsafe.Pointer(&bufferUsed)),
+		uintptr(unsafe.Pointer(&propCount)),
+	)
+	if r == 0 {
+		return "", fmt.Errorf("EvtRender: %w", err)
+	}
+
+	return windows.UTF16ToString(buffer), nil
+}
+
+func formatMessage(meta windows.Handle, hEvt windows.Handle) (string, error) {
+	var used uint32
+	r, _, err := procEvtFormatMessage.Call(
+		uintptr(meta),
+		uintptr(hEvt),
+		0,
+		0,
+		0,
+		evtFormatMessageEvent,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&used)),
+	)
+	if r == 0 {
+		if errno, ok := err.(windows.Errno); !ok || errno != windows.ERROR_INSUFFICIENT_BUFFER {
+			return "", fmt.Errorf("EvtFormatMessage(size): %w", err)
+		}
+	}
+
+	buf := make([]uint16, used)
+	r, _, err = procEvtFormatMessage.Call(
+		uintptr(meta),
+		uintptr(hEvt),
+		0,
+		0,
+		0,
+		evtFormatMessageEvent,
+		uintptr(used),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&used)),
+	)
+	if r == 0 {
+		return "", fmt.Errorf("EvtFormatMessage: %w", err)
+	}
+	return strings.TrimSpace(windows.UTF16ToString(buf)), nil
+}
+
+func evtCloseHandle(h windows.Handle) {
+	if h == 0 {
+		return
+	}
+	procEvtClose.Call(uintptr(h))
+}
+
+func evtCloseHandles(handles []windows.Handle) {
+	for _, h := range handles {
+		evtCloseHandle(h)
+	}
+}
@@ -0,0 +1,236 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+// Update results recognised in WindowsUpdate.log lines.
+const (
+	WUResultFailed    = "failed"
+	WUResultSucceeded = "succeeded"
+)
+
+const (
+	maxWUEntries = 40
+	maxWUExcerpt = 5
+	// wuRetryGap separates install attempts: the agent, handler and reporting
+	// lines of one failed attempt are logged within seconds of each other.
+	wuRetryGap = time.Minute
+)
+
+var (
+	// Get-WindowsUpdateLog:
+	//   2024/01/15 10:23:45.1234567 1032  6304  Agent           *FAILED* [8024402C] ...
+	// Legacy WindowsUpdate.log (tab-separated, hex PID/TID):
+	//   2014-03-14	10:23:45:123	 948	c3c	Agent	  * WARNING: ... hr = 80242013
+	wuLineRegex   = regexp.MustCompile(`^(\d{4}[/-]\d{2}[/-]\d{2})[ \t]+(\d{2}:\d{2}:\d{2})(?:[.:]\d+)?\s+[0-9a-fA-F]+\s+[0-9a-fA-F]+\s+(\S+)\s+(.*)$`)
+	wuCodeRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\*FAILED\*\s*\[(?:0x)?([0-9a-f]{8})\]`),
+		regexp.MustCompile(`(?i)\b(?:hr|hresult|error|exit code|result)\s*[:=]?\s*(?:0x)?([0-9a-f]{8})\b`),
+		regexp.MustCompile(`(?i)\b0x([0-9a-f]{8})\b`),
+	}
+	wuUpdateIDRegex = regexp.MustCompile(`(?i)\{?([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})\}?`)
+	wuKBRegex       = regexp.MustCompile(`(?i)\bKB(\d{6,8})\b`)
+	// A line is a failure only when it says so and carries an HRESULT;
+	// WARNING: lines and bare error codes are common in healthy runs.
+	wuFailedRegex  = regexp.MustCompile(`(?i)\*FAILED\*|\bfail(?:ed|ure)\b`)
+	wuSuccessRegex = regexp.MustCompile(`(?i)\bsucceeded\b|installation successful|completed successfully`)
+)
+
+// CollectWULogFile parses an existing WindowsUpdate.log, reading at most
+// maxBytes from its end. Use it for logs produced elsewhere or on Linux.
+func CollectWULogFile(path string, maxBytes int64) (model.WULog, error) {
+	data, err := readTail(path, maxBytes)
+	if err != nil {
+		return model.WULog{}, err
+	}
+	return ParseWULog(data), nil
+}
+
+func readTail(path string, max int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	r, err := tailReader(f, max)
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+// ParseWULog turns WindowsUpdate.log text (either format) into structured
+// records and a summary of failed installs per KB.
+func ParseWULog(content string) model.WULog {
+	var (
+		entries []model.WUEntry
+		parsed  int
+		last    string
+	)
+	kbByUpdate := map[string]string{}
+	for _, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		last = line
+		m := wuLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		parsed++
+		entry := parseWULine(m)
+		if entry.UpdateID != "" && entry.KB != "" {
+			kbByUpdate[entry.UpdateID] = entry.KB
+		}
+		if entry.Result == "" && entry.HRESULT == "" && entry.KB == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	// KB numbers are usually logged once per update, by title; failure lines
+	// only carry the update ID.
+	for i := range entries {
+		if entries[i].KB == "" && entries[i].UpdateID != "" {
+			entries[i].KB = kbByUpdate[entries[i].UpdateID]
+		}
+	}
+
+	out := summarizeWUEntries(entries)
+	if parsed == 0 && last != "" {
+		out.Summary = "Windows Update log collected (unrecognized format)"
+	}
+	for _, e := range entries {
+		if len(out.Excerpt) >= maxWUExcerpt {
+			break
+		}
+		if e.Result == WUResultFailed {
+			out.Excerpt = append(out.Excerpt, e.Raw)
+		}
+	}
+	if last != "" {
+		out.Excerpt = append(out.Excerpt, last)
+	}
+	if len(out.Excerpt) == 0 {
+		out.Excerpt = append(out.Excerpt, "")
+	}
+	return out
+}
+
+func parseWULine(m []string) model.WUEntry {
+	date := strings.ReplaceAll(m[1], "/", "-")
+	entry := model.WUEntry{Component: m[3], Message: strings.TrimSpace(m[4]), Raw: m[0]}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", date+" "+m[2], time.Local); err == nil {
+		entry.Time = t.UTC()
+	}
+	msg := entry.Message
+	for _, re := range wuCodeRegexes {
+		if c := re.FindStringSubmatch(msg); c != nil && !isSuccessCode("0x"+c[1]) {
+			entry.HRESULT = "0x" + strings.ToLower(c[1])
+			break
+		}
+	}
+	if id := wuUpdateIDRegex.FindStringSubmatch(msg); id != nil {
+		entry.UpdateID = strings.ToLower(id[1])
+	}
+	if kb := wuKBRegex.FindStringSubmatch(msg); kb != nil {
+		entry.KB = "KB" + kb[1]
+	}
+	switch {
+	case entry.HRESULT != "" && wuFailedRegex.MatchString(msg):
+		entry.Result = WUResultFailed
+	case wuSuccessRegex.MatchString(msg):
+		entry.Result = WUResultSucceeded
+	}
+	return entry
+}
+
+func summarizeWUEntries(entries []model.WUEntry) model.WULog {
+	out := model.WULog{}
+	codes := map[string]bool{}
+	byKB := map[string]*model.WUFailure{}
+	lastAttempt := map[string]time.Time{}
+	var first, last time.Time
+	for _, e := range entries {
+		if e.Result != WUResultFailed {
+			continue
+		}
+		if e.HRESULT != "" {
+			codes[e.HRESULT] = true
+		}
+		if first.IsZero() || e.Time.Before(first) {
+			first = e.Time
+		}
+		if e.Time.After(last) {
+			last = e.Time
+		}
+		if e.KB == "" {
+			continue
+		}
+		f, ok := byKB[e.KB]
+		if !ok {
+			f = &model.WUFailure{KB: e.KB, UpdateID: e.UpdateID, FirstSeen: e.Time}
+			byKB[e.KB] = f
+		}
+		if prev, seen := lastAttempt[e.KB]; !seen || e.Time.Sub(prev) > wuRetryGap {
+			f.Count++
+		}
+		lastAttempt[e.KB] = e.Time
+		f.LastSeen = e.Time
+		if e.HRESULT != "" && !containsString(f.ErrorCodes, e.HRESULT) {
+			f.ErrorCodes = append(f.ErrorCodes, e.HRESULT)
+		}
+	}
+	for code := range codes {
+		out.ErrorCodes = append(out.ErrorCodes, code)
+	}
+	sort.Strings(out.ErrorCodes)
+	for _, f := range byKB {
+		out.FailedInstalls = append(out.FailedInstalls, *f)
+	}
+	sort.Slice(out.FailedInstalls, func(i, j int) bool {
+		if out.FailedInstalls[i].Count == out.FailedInstalls[j].Count {
+			return out.FailedInstalls[i].KB < out.FailedInstalls[j].KB
+		}
+		return out.FailedInstalls[i].Count > out.FailedInstalls[j].Count
+	})
+	if !first.IsZero() {
+		out.FirstFailure = first.Format(time.RFC3339)
+		out.LastFailure = last.Format(time.RFC3339)
+	}
+	out.Entries = entries
+	if len(out.Entries) > maxWUEntries {
+		out.Entries = append([]model.WUEntry(nil), out.Entries[len(out.Entries)-maxWUEntries:]...)
+	}
+
+	if first.IsZero() {
+		out.Summary = fmt.Sprintf("No Windows Update failures found (%d records of interest)", len(entries))
+		return out
+	}
+	var kbs []string
+	for _, f := range out.FailedInstalls {
+		kbs = append(kbs, fmt.Sprintf("%s x%d (%s)", f.KB, f.Count, joinOrNone(f.ErrorCodes)))
+	}
+	out.Summary = fmt.Sprintf("Failed installs: %s; first failure %s, last failure %s; error codes: %s",
+		joinOrNone(kbs), out.FirstFailure, out.LastFailure, joinOrNone(out.ErrorCodes))
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
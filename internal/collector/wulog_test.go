@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWULine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantResult string
+		wantCode   string
+		wantKB     string
+	}{
+		{"failed with code", "2025/12/18 14:00:01.1234567 1032  6304  Agent           *FAILED* [8024402C] Method failed [CAgentUpdateManager::DetectUpdates:1234]",
+			WUResultFailed, "0x8024402c", ""},
+		{"failure with hr", "2025/12/18 14:00:02.0000000 1032  6304  Handler         CBS installation failure, hr = 0x800f0922 for KB5071547",
+			WUResultFailed, "0x800f0922", "KB5071547"},
+		{"legacy warning", "2014-03-14\t10:23:45:123\t 948\tc3c\tAgent\t  * WARNING: Exit code = 0x8024402C",
+			"", "0x8024402c", ""},
+		{"legacy warning without code", "2014-03-14\t10:23:45:123\t 948\tc3c\tAgent\t  * WARNING: Failed to obtain the proxy settings",
+			"", "", ""},
+		{"failed without code", "2025/12/18 14:00:03.0000000 1032  6304  Misc            Failed to read registry value, ignoring",
+			"", "", ""},
+		{"bare code", "2025/12/18 14:00:04.0000000 1032  6304  ComApi          Reporting status 0x80240022",
+			"", "0x80240022", ""},
+		{"success code ignored", "2025/12/18 14:00:05.0000000 1032  6304  Handler         Installation failed with result 0x00000000",
+			"", "", ""},
+		{"succeeded", "2025/12/18 14:00:06.0000000 1032  6304  Handler         Update KB5071547 install succeeded",
+			WUResultSucceeded, "", "KB5071547"},
+	}
+	for _, tt := range tests {
+		m := wuLineRegex.FindStringSubmatch(tt.line)
+		if m == nil {
+			t.Errorf("%s: line not recognised", tt.name)
+			continue
+		}
+		e := parseWULine(m)
+		if e.Result != tt.wantResult || e.HRESULT != tt.wantCode || e.KB != tt.wantKB {
+			t.Errorf("%s: result %q code %q kb %q; want %q %q %q", tt.name, e.Result, e.HRESULT, e.KB, tt.wantResult, tt.wantCode, tt.wantKB)
+		}
+	}
+}
+
+func TestParseWULog(t *testing.T) {
+	log := strings.Join([]string{
+		"2025/12/18 14:00:00.0000000 1032  6304  Agent           Title = 2025-12 Cumulative Update (KB5071547), UpdateId = {3F1E2D4C-5B6A-4789-8123-456789ABCDEF}.200",
+		"2025/12/18 14:00:01.0000000 1032  6304  Agent           * WARNING: Cached cookie has expired or new PID is available",
+		"2025/12/18 14:00:05.0000000 1032  6304  Handler         *FAILED* [800F0922] Install of {3f1e2d4c-5b6a-4789-8123-456789abcdef} failed",
+		"2025/12/18 14:00:07.0000000 1032  6304  Agent           *FAILED* [800F0922] Update {3f1e2d4c-5b6a-4789-8123-456789abcdef} failed",
+		"2025/12/18 15:30:00.0000000 1032  6304  Handler         *FAILED* [800F0922] Install of {3f1e2d4c-5b6a-4789-8123-456789abcdef} failed",
+		"2025/12/18 15:31:00.0000000 1032  6304  Agent           * WARNING: Exit code = 0x80240022",
+	}, "\n")
+	got := ParseWULog(log)
+
+	if len(got.FailedInstalls) != 1 {
+		t.Fatalf("failed installs = %+v, want one", got.FailedInstalls)
+	}
+	f := got.FailedInstalls[0]
+	if f.KB != "KB5071547" || f.Count != 2 || !reflect.DeepEqual(f.ErrorCodes, []string{"0x800f0922"}) {
+		t.Errorf("failed install = %+v, want KB5071547 x2 (0x800f0922)", f)
+	}
+	// Neither WARNING line says the install failed, so neither counts.
+	if !reflect.DeepEqual(got.ErrorCodes, []string{"0x800f0922"}) {
+		t.Errorf("error codes = %v", got.ErrorCodes)
+	}
+	if !strings.HasPrefix(got.Summary, "Failed installs: KB5071547 x2 (0x800f0922)") {
+		t.Errorf("summary = %q", got.Summary)
+	}
+	if n := len(got.Excerpt); n != 4 || got.Excerpt[n-1] != strings.TrimSpace(log[strings.LastIndex(log, "\n")+1:]) {
+		t.Errorf("excerpt = %q, want three failures and the last line", got.Excerpt)
+	}
+}
+
+func TestParseWULogNoFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		wantSummary string
+	}{
+		{"warnings only", "2014-03-14\t10:23:45:123\t 948\tc3c\tAgent\t  * WARNING: Exit code = 0x8024402C",
+			"No Windows Update failures found (1 records of interest)"},
+		{"unrecognised", "this is not a Windows Update log", "Windows Update log collected (unrecognized format)"},
+	}
+	for _, tt := range tests {
+		got := ParseWULog(tt.log)
+		if got.Summary != tt.wantSummary || len(got.FailedInstalls) != 0 {
+			t.Errorf("%s: summary %q, failed %v; want %q", tt.name, got.Summary, got.FailedInstalls, tt.wantSummary)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -33,55 +32,18 @@ func CollectWULog(tempDir string, maxBytes int64) (model.WULog, error) {
 	cmd.Stderr = &buf
 
 	if err := cmd.Run(); err == nil {
-		data, err := readLimited(tmpFile, maxBytes)
+		data, err := readTail(tmpFile, maxBytes)
 		if err == nil {
-			out.Summary, out.Excerpt = summarizeWULog(data)
+			out = ParseWULog(data)
 			_ = os.Remove(tmpFile)
 			return out, nil
 		}
 	}
 
 	fallback := `C:\Windows\WindowsUpdate.log`
-	data, err := readLimited(fallback, maxBytes)
+	data, err := readTail(fallback, maxBytes)
 	if err != nil {
 		return out, fmt.Errorf("windows update log unavailable: %w", err)
 	}
-	out.Summary, out.Excerpt = summarizeWULog(data)
-	return out, nil
-}
-
-func readLimited(path string, max int64) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-	if max <= 0 {
-		max = 5 * 1024 * 1024
-	}
-	lim := io.LimitedReader{R: f, N: max}
-	b, err := io.ReadAll(&lim)
-	return string(b), err
-}
-
-func summarizeWULog(content string) (string, []string) {
-	lines := bytes.Split([]byte(content), []byte("\n"))
-	var excerpt []string
-	for i, line := range lines {
-		if len(excerpt) >= 5 {
-			break
-		}
-		if bytes.Contains(line, []byte("Error")) || bytes.Contains(line, []byte("0x")) {
-			excerpt = append(excerpt, string(bytes.TrimSpace(line)))
-		}
-		// also take last line for context
-		if i == len(lines)-1 && len(line) > 0 {
-			excerpt = append(excerpt, string(bytes.TrimSpace(line)))
-		}
-	}
-	summary := "Windows Update log collected"
-	if len(excerpt) == 0 {
-		excerpt = append(excerpt, "")
-	}
-	return summary, excerpt
+	return ParseWULog(data), nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,12 +19,75 @@ type Config struct {
 	MaxLogBytes            int64  `json:"max_log_bytes"`
 	WULogTempPath          string `json:"wu_log_temp_path"`
 	QueueDir               string `json:"queue_dir"`
+	UseBookmarks           bool   `json:"use_bookmarks"`
 	Hostname               string `json:"hostname"`
 	OSVersion              string `json:"os_version"`
+	// MaxInputTokens caps the estimated prompt tokens; 0 disables the cap.
+	MaxInputTokens int `json:"max_input_tokens"`
+	// TokenBudgets overrides MaxInputTokens per provider ("openai") or
+	// provider/model ("openai/gpt-4o-mini").
+	TokenBudgets map[string]int `json:"token_budgets"`
+	// TokenizerFile is a tiktoken rank file (e.g. cl100k_base.tiktoken) for
+	// exact OpenAI token counts; without it tokens are estimated.
+	TokenizerFile string `json:"tokenizer_file"`
+	// SignatureDir holds *.json signature files that override or extend the
+	// built-in known-issue catalog.
+	SignatureDir string `json:"signature_dir"`
+	// Providers configures triage LLM providers by the name given to
+	// winopsguard-triage -provider.
+	Providers map[string]ProviderConfig `json:"providers"`
 }
 
-// Load reads config.json then applies environment overrides.
-func Load(path string) (Config, error) {
+// ProviderConfig configures one triage LLM provider.
+type ProviderConfig struct {
+	// Type is the provider implementation ("openai", "gemini", "anthropic",
+	// "azure-openai", "openai-compatible"); it defaults to the entry name.
+	Type    string `json:"type"`
+	BaseURL string `json:"base_url"`
+	Model   string `json:"model"`
+	// Deployment is the Azure OpenAI deployment name; it defaults to Model.
+	Deployment string `json:"deployment"`
+	// APIVersion is the Azure OpenAI api-version or the anthropic-version
+	// header; each provider has a default.
+	APIVersion string `json:"api_version"`
+	// APIKeyEnv names the environment variable holding the API key; the
+	// key itself is never read from the file.
+	APIKeyEnv string `json:"api_key_env"`
+	// Headers are sent with every request; ${VAR} in values is expanded
+	// from the environment.
+	Headers map[string]string `json:"headers"`
+	TLS     TLSConfig         `json:"tls"`
+	// StructuredOutput turns the API's JSON schema mode on or off. It is
+	// on by default for OpenAI, Azure OpenAI and Gemini and off for
+	// openai-compatible servers, which do not all support it.
+	StructuredOutput *bool `json:"structured_output"`
+}
+
+// TLSConfig adjusts certificate checking for on-premises endpoints.
+type TLSConfig struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string `json:"ca_file"`
+	// CertFile and KeyFile are a PEM client certificate for mutual TLS.
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	ServerName         string `json:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+// Provider returns the settings for the named provider; a name without an
+// entry gets zero settings, so the name selects the implementation.
+func (c Config) Provider(name string) ProviderConfig {
+	for key, pc := range c.Providers {
+		if strings.EqualFold(key, name) {
+			return pc
+		}
+	}
+	return ProviderConfig{}
+}
+
+// Read reads config.json, if present, then applies environment overrides.
+// It does not validate; tools that do not talk to the API use it directly.
+func Read(path string) (Config, error) {
 	cfg := defaultConfig()
 
 	if _, err := os.Stat(path); err == nil {
@@ -37,6 +101,15 @@ func Load(path string) (Config, error) {
 	}
 
 	applyEnv(&cfg)
+	return cfg, nil
+}
+
+// Load reads the configuration like Read and requires the API settings.
+func Load(path string) (Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return cfg, err
+	}
 	if cfg.APIURL == "" {
 		return cfg, errors.New("api_url is required")
 	}
@@ -46,6 +119,24 @@ func Load(path string) (Config, error) {
 	return cfg, nil
 }
 
+// InputTokenBudget returns the token budget for provider and model: the
+// provider/model entry of TokenBudgets, then the provider entry, then
+// MaxInputTokens.
+func (c Config) InputTokenBudget(provider, model string) int {
+	provider, model = strings.ToLower(provider), strings.ToLower(model)
+	for key, v := range c.TokenBudgets {
+		if strings.ToLower(key) == provider+"/"+model {
+			return v
+		}
+	}
+	for key, v := range c.TokenBudgets {
+		if strings.ToLower(key) == provider {
+			return v
+		}
+	}
+	return c.MaxInputTokens
+}
+
 // Window returns collection window duration.
 func (c Config) Window() time.Duration {
 	return time.Duration(c.CollectionWindowMinute) * time.Minute
@@ -61,6 +152,7 @@ func defaultConfig() Config {
 		MaxLogBytes:            5 * 1024 * 1024,
 		WULogTempPath:          os.TempDir(),
 		QueueDir:               "queue",
+		UseBookmarks:           false,
 		Hostname:               "",
 		OSVersion:              "",
 	}
@@ -99,10 +191,26 @@ func applyEnv(cfg *Config) {
 	if v := os.Getenv("WINOPSGUARD_QUEUE_DIR"); v != "" {
 		cfg.QueueDir = v
 	}
+	if v := os.Getenv("WINOPSGUARD_USE_BOOKMARKS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.UseBookmarks = b
+		}
+	}
 	if v := os.Getenv("WINOPSGUARD_HOSTNAME"); v != "" {
 		cfg.Hostname = v
 	}
 	if v := os.Getenv("WINOPSGUARD_OS_VERSION"); v != "" {
 		cfg.OSVersion = v
 	}
+	if v := os.Getenv("WINOPSGUARD_MAX_INPUT_TOKENS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			cfg.MaxInputTokens = i
+		}
+	}
+	if v := os.Getenv("WINOPSGUARD_TOKENIZER_FILE"); v != "" {
+		cfg.TokenizerFile = v
+	}
+	if v := os.Getenv("WINOPSGUARD_SIGNATURE_DIR"); v != "" {
+		cfg.SignatureDir = v
+	}
 }
@@ -0,0 +1,293 @@
+// Package errcode decodes HRESULT, Win32, NTSTATUS and exception codes from
+// an offline table, so triage does not depend on the LLM remembering what
+// 0x800f081f means.
+package errcode
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"winopsguard/internal/model"
+)
+
+// Code kinds.
+const (
+	KindHRESULT   = "hresult"
+	KindWin32     = "win32"
+	KindNTSTATUS  = "ntstatus"
+	KindException = "exception"
+)
+
+// maxAnnotations caps decoded_errors in the payload.
+const maxAnnotations = 30
+
+// Info describes one code. Name and Description are empty for codes that
+// are not in the table but whose facility is known.
+type Info struct {
+	Code        uint32
+	Kind        string
+	Name        string
+	Facility    string
+	Description string
+}
+
+type entry struct {
+	kind, name, description string
+}
+
+var (
+	hexRegex     = regexp.MustCompile(`(?i)\b0x([0-9a-f]{8})\b`)
+	keywordRegex = regexp.MustCompile(`(?i)\b(?:hr|hresult|error|status|code|result)\s*[:=]?\s*([0-9a-f]{8})\b`)
+	// WindowsUpdate.log and some WMI providers print HRESULTs as signed
+	// decimals, e.g. -2145124329.
+	signedRegex = regexp.MustCompile(`(?:^|[^\d.])(-2\d{9})\b`)
+)
+
+// Decode looks code up. ok is false when neither the code nor, for an
+// error HRESULT, its facility is known.
+func Decode(code uint32) (Info, bool) {
+	info := Info{Code: code}
+	if e, ok := codes[code]; ok {
+		info.Kind, info.Name, info.Description = e.kind, e.name, e.description
+		if e.kind == KindHRESULT {
+			info.Facility = facilities[facility(code)]
+		}
+		return info, true
+	}
+	if code <= 0xFFFF {
+		if e, ok := win32[code]; ok {
+			info.Kind, info.Name, info.Description = e.kind, e.name, e.description
+			return info, true
+		}
+		return info, false
+	}
+	if code&0xF0000000 == 0xC0000000 {
+		info.Kind = KindNTSTATUS
+		return info, false
+	}
+	if code&0x80000000 == 0 {
+		return info, false
+	}
+	info.Kind = KindHRESULT
+	name, ok := facilities[facility(code)]
+	if !ok {
+		return info, false
+	}
+	info.Facility = name
+	if facility(code) == 7 {
+		if e, ok := win32[code&0xFFFF]; ok {
+			info.Name = "HRESULT_FROM_WIN32(" + e.name + ")"
+			info.Description = e.description
+		}
+	}
+	return info, true
+}
+
+// Describe returns the payload form of code.
+func Describe(code uint32) model.DecodedError {
+	info, _ := Decode(code)
+	return model.DecodedError{
+		Code:        Format(code),
+		Kind:        info.Kind,
+		Name:        info.Name,
+		Facility:    info.Facility,
+		Description: info.Description,
+	}
+}
+
+func facility(code uint32) uint32 {
+	return (code >> 16) & 0x1FFF
+}
+
+// Format renders code as Windows logs it, e.g. 0x800f081f.
+func Format(code uint32) string {
+	return fmt.Sprintf("0x%08x", code)
+}
+
+// Parse reads a code written as hex (0x800F081F, 800f081f), as a signed
+// decimal HRESULT (-2146498529) or as a decimal Win32 error (5). A bare
+// value is taken as hex when it contains hex letters or has eight digits.
+func Parse(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	if rest, ok := strings.CutPrefix(lower, "0x"); ok {
+		v, err := strconv.ParseUint(rest, 16, 32)
+		return uint32(v), err
+	}
+	if strings.HasPrefix(s, "-") {
+		v, err := strconv.ParseInt(s, 10, 32)
+		return uint32(int32(v)), err
+	}
+	if len(s) == 8 || strings.ContainsAny(lower, "abcdef") {
+		v, err := strconv.ParseUint(lower, 16, 32)
+		return uint32(v), err
+	}
+	v, err := strconv.ParseUint(s, 10, 32)
+	return uint32(v), err
+}
+
+// Extract returns the codes found in text worth decoding, in order of first
+// appearance: 0x-prefixed eight-digit values, bare eight-digit values after
+// hr/error/status/code/result, and signed decimal HRESULTs. Values that are
+// neither in the table nor an error HRESULT or NTSTATUS are skipped.
+func Extract(text string) []uint32 {
+	var out []uint32
+	seen := map[uint32]bool{}
+	for _, code := range occurrences(text) {
+		if !seen[code] {
+			seen[code] = true
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+// occurrences returns every code Extract would find, repeats included, in
+// order of appearance.
+func occurrences(text string) []uint32 {
+	hits := map[int]uint32{}
+	add := func(re *regexp.Regexp) {
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			code, err := Parse(text[m[2]:m[3]])
+			if err != nil || !worthDecoding(code) {
+				continue
+			}
+			hits[m[2]] = code
+		}
+	}
+	add(hexRegex)
+	add(keywordRegex)
+	add(signedRegex)
+	pos := make([]int, 0, len(hits))
+	for p := range hits {
+		pos = append(pos, p)
+	}
+	sort.Ints(pos)
+	out := make([]uint32, len(pos))
+	for i, p := range pos {
+		out[i] = hits[p]
+	}
+	return out
+}
+
+func worthDecoding(code uint32) bool {
+	if _, ok := codes[code]; ok {
+		return true
+	}
+	return code&0x80000000 != 0
+}
+
+// tally counts decoded codes in order of first appearance.
+type tally struct {
+	order []uint32
+	found map[uint32]*model.DecodedError
+}
+
+func (t *tally) add(section string, code uint32) {
+	if t.found == nil {
+		t.found = map[uint32]*model.DecodedError{}
+	}
+	d := t.found[code]
+	if d == nil {
+		dec := Describe(code)
+		d = &dec
+		t.found[code] = d
+		t.order = append(t.order, code)
+	}
+	d.Count++
+	if section != "" && !containsString(d.SeenIn, section) {
+		d.SeenIn = append(d.SeenIn, section)
+	}
+}
+
+// ranked returns the tallied codes, those named in the table first and each
+// group most frequent first, at most maxAnnotations of them.
+func (t *tally) ranked() []model.DecodedError {
+	out := make([]model.DecodedError, 0, len(t.order))
+	for _, code := range t.order {
+		d := t.found[code]
+		sort.Strings(d.SeenIn)
+		out = append(out, *d)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if (out[i].Name != "") != (out[j].Name != "") {
+			return out[i].Name != ""
+		}
+		return out[i].Count > out[j].Count
+	})
+	if len(out) > maxAnnotations {
+		out = out[:maxAnnotations]
+	}
+	return out
+}
+
+// DecodeText decodes every code in text, counting each occurrence, ranked
+// like Annotate. It serves inputs that are not agent payloads.
+func DecodeText(text string) []model.DecodedError {
+	var t tally
+	for _, code := range occurrences(text) {
+		t.add("", code)
+	}
+	return t.ranked()
+}
+
+// Annotate decodes every code found in the payload's events, Windows Update
+// and servicing logs and crash buckets into req.DecodedErrors: codes named
+// in the table first, each group most frequent first, counting a code once
+// per message. Call it before trimming so codes of dropped events still
+// count.
+func Annotate(req *model.AIRequest) {
+	var t tally
+	scan := func(section, text string) {
+		for _, code := range Extract(text) {
+			t.add(section, code)
+		}
+	}
+
+	for _, l := range []struct {
+		name string
+		set  *model.LogSet
+	}{
+		{"eventlog.system", &req.EventLog.System},
+		{"eventlog.application", &req.EventLog.Application},
+	} {
+		name, set := l.name, l.set
+		events := set.Raw
+		if len(events) == 0 {
+			events = set.Recent
+		}
+		for _, ev := range events {
+			scan(name, ev.Message)
+			for _, v := range ev.Data {
+				scan(name, v)
+			}
+		}
+	}
+	for _, e := range req.WindowsUpdateLog.Entries {
+		scan("windows_update_log", e.HRESULT+" "+e.Message)
+	}
+	if len(req.WindowsUpdateLog.Entries) == 0 {
+		for _, line := range req.WindowsUpdateLog.Excerpt {
+			scan("windows_update_log", line)
+		}
+	}
+	for _, e := range req.ServicingLogs.Entries {
+		scan("servicing_logs", e.HRESULT+" "+e.Message)
+	}
+	for _, b := range req.Crashes.Buckets {
+		scan("crashes", b.ExceptionCode)
+	}
+	req.DecodedErrors = t.ranked()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package errcode
+
+import (
+	"reflect"
+	"testing"
+
+	"winopsguard/internal/model"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []uint32
+	}{
+		{"hex", "Installation Failure: error 0x800F0922 for KB5034439", []uint32{0x800F0922}},
+		{"keyword bare hex", "WARNING: SyncUpdates failure, error = 8024402C, soap client error = 5", []uint32{0x8024402C}},
+		{"signed decimal", "Update failed with result -2145124329", []uint32{0x80240017}},
+		{"order of appearance, repeats once", "hr=0x800f081f then 0x8024402C then 0x800F081F", []uint32{0x800F081F, 0x8024402C}},
+		{"success codes skipped", "status 0x00000000 result 0x00000001", nil},
+		{"unknown success-range code skipped", "value 0x12345678", nil},
+	}
+	for _, tt := range tests {
+		if got := Extract(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Extract = %#x, want %#x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	text := `{"events":[{"msg":"hr 0x87D00324"},{"msg":"hr 0x87D00324"},{"msg":"hr 0x87D00324"},` +
+		`{"msg":"0x8024402C"},{"msg":"0x800F081F"},{"msg":"0x800F081F"}]}`
+	got := DecodeText(text)
+	var codes []string
+	var counts []int
+	for _, d := range got {
+		codes = append(codes, d.Code)
+		counts = append(counts, d.Count)
+	}
+	// Named codes lead, each group by count; the unnamed code comes last
+	// though it is the most frequent.
+	if want := []string{"0x800f081f", "0x8024402c", "0x87d00324"}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("codes = %v, want %v", codes, want)
+	}
+	if want := []int{2, 1, 3}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if got[0].Name != "CBS_E_SOURCE_MISSING" || got[0].SeenIn != nil {
+		t.Errorf("first = %+v", got[0])
+	}
+	if DecodeText("no codes here") == nil {
+		t.Error("DecodeText without codes returned nil, want an empty list")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	var req model.AIRequest
+	req.EventLog.System.Raw = []model.Event{
+		{Message: "Installation Failure: error 0x800F0922 (0x800f0922)"},
+		{Message: "Installation Failure: error 0x800F0922"},
+		{Message: "Download failed: 0x8024402C"},
+	}
+	req.WindowsUpdateLog.Entries = []model.WUEntry{{HRESULT: "0x8024402c", Message: "*FAILED* [8024402C]"}, {HRESULT: "0x8024402c"}, {HRESULT: "0x8024402c"}}
+	req.Crashes.Buckets = []model.CrashBucket{{ExceptionCode: "0xc0000005"}}
+	Annotate(&req)
+
+	got := map[string]model.DecodedError{}
+	var order []string
+	for _, d := range req.DecodedErrors {
+		got[d.Code] = d
+		order = append(order, d.Code)
+	}
+	// One count per message: the event naming 0x800f0922 twice counts once.
+	if want := []string{"0x8024402c", "0x800f0922", "0xc0000005"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if d := got["0x8024402c"]; d.Count != 4 || !reflect.DeepEqual(d.SeenIn, []string{"eventlog.system", "windows_update_log"}) {
+		t.Errorf("0x8024402c = %+v", d)
+	}
+	if d := got["0x800f0922"]; d.Count != 2 {
+		t.Errorf("0x800f0922 = %+v", d)
+	}
+}
@@ -0,0 +1,181 @@
+package errcode
+
+// facilities names the HRESULT facilities seen in servicing and update
+// failures.
+var facilities = map[uint32]string{
+	0:  "NULL",
+	1:  "RPC",
+	2:  "DISPATCH",
+	3:  "STORAGE",
+	4:  "ITF",
+	7:  "WIN32",
+	8:  "WINDOWS",
+	9:  "SECURITY",
+	10: "CONTROL",
+	11: "CERT",
+	12: "INTERNET",
+	15: "SETUPAPI", // CBS and DISM (0x800F....)
+	19: "HTTP",
+	32: "BACKGROUNDCOPY",
+	33: "CONFIGURATION",
+	36: "WINDOWSUPDATE",
+}
+
+// codes are HRESULTs, NTSTATUS values and exception codes matched exactly.
+var codes = map[uint32]entry{
+	// Generic COM
+	0x80004001: {KindHRESULT, "E_NOTIMPL", "not implemented"},
+	0x80004002: {KindHRESULT, "E_NOINTERFACE", "interface not supported"},
+	0x80004004: {KindHRESULT, "E_ABORT", "operation aborted"},
+	0x80004005: {KindHRESULT, "E_FAIL", "unspecified failure"},
+	0x8000FFFF: {KindHRESULT, "E_UNEXPECTED", "catastrophic failure"},
+	0x80010108: {KindHRESULT, "RPC_E_DISCONNECTED", "the object invoked has disconnected from its clients"},
+
+	// Windows Update agent
+	0x8024000B: {KindHRESULT, "WU_E_CALL_CANCELLED", "operation was cancelled"},
+	0x8024000E: {KindHRESULT, "WU_E_XML_INVALID", "update metadata contains invalid XML"},
+	0x80240016: {KindHRESULT, "WU_E_INSTALL_NOT_ALLOWED", "another installation is in progress or a reboot is pending"},
+	0x80240017: {KindHRESULT, "WU_E_NOT_APPLICABLE", "update is not applicable to this computer"},
+	0x8024001E: {KindHRESULT, "WU_E_SERVICE_STOP", "operation did not complete because the service or system was shutting down"},
+	0x8024001F: {KindHRESULT, "WU_E_NO_CONNECTION", "no network connection was available"},
+	0x80240020: {KindHRESULT, "WU_E_NO_INTERACTIVE_USER", "operation requires a logged-on interactive user"},
+	0x80240022: {KindHRESULT, "WU_E_ALL_UPDATES_FAILED", "every update in the operation failed"},
+	0x8024002D: {KindHRESULT, "WU_E_SOURCE_UNAVAILABLE", "source files are required but unavailable"},
+	0x8024002E: {KindHRESULT, "WU_E_WU_DISABLED", "access to an unmanaged server is blocked by policy"},
+	0x80240031: {KindHRESULT, "WU_E_INVALID_FILE", "file is in the wrong format"},
+	0x80240032: {KindHRESULT, "WU_E_INVALID_CRITERIA", "search criteria string is invalid"},
+	0x80240034: {KindHRESULT, "WU_E_DOWNLOAD_FAILED", "update failed to download"},
+	0x80240438: {KindHRESULT, "WU_E_PT_ENDPOINT_UNREACHABLE", "no route or network connectivity to the update endpoint"},
+	0x80240FFF: {KindHRESULT, "WU_E_UNEXPECTED", "unexpected Windows Update agent error"},
+	0x80244007: {KindHRESULT, "WU_E_PT_SOAPCLIENT_SOAPFAULT", "server returned a SOAP fault; often WSUS or proxy trouble"},
+	0x80244010: {KindHRESULT, "WU_E_PT_EXCEEDED_MAX_SERVER_TRIPS", "too many round trips to the update server"},
+	0x80244017: {KindHRESULT, "WU_E_PT_HTTP_STATUS_DENIED", "HTTP 401 from the update server or proxy"},
+	0x80244018: {KindHRESULT, "WU_E_PT_HTTP_STATUS_FORBIDDEN", "HTTP 403 from the update server or proxy"},
+	0x80244019: {KindHRESULT, "WU_E_PT_HTTP_STATUS_NOT_FOUND", "HTTP 404 from the update server; content missing on WSUS"},
+	0x8024401B: {KindHRESULT, "WU_E_PT_HTTP_STATUS_PROXY_AUTH_REQ", "HTTP 407 proxy authentication required"},
+	0x8024401C: {KindHRESULT, "WU_E_PT_HTTP_STATUS_REQUEST_TIMEOUT", "HTTP 408 request timeout"},
+	0x8024401F: {KindHRESULT, "WU_E_PT_HTTP_STATUS_SERVER_ERROR", "HTTP 500 from the update server"},
+	0x80244022: {KindHRESULT, "WU_E_PT_HTTP_STATUS_SERVICE_UNAVAIL", "HTTP 503 from the update server"},
+	0x8024402C: {KindHRESULT, "WU_E_PT_WINHTTP_NAME_NOT_RESOLVED", "update server or proxy name could not be resolved"},
+	0x8024402F: {KindHRESULT, "WU_E_PT_ECP_SUCCEEDED_WITH_ERRORS", "external cab processing completed with errors"},
+	0x8024200B: {KindHRESULT, "WU_E_UH_INSTALLERFAILURE", "the update handler reported an installer failure"},
+	0x8024200D: {KindHRESULT, "WU_E_UH_NEEDANOTHERDOWNLOAD", "the update needs more content to be downloaded"},
+	0x80242016: {KindHRESULT, "WU_E_UH_POSTREBOOTUNEXPECTEDSTATE", "update was in an unexpected state after the reboot"},
+	0x80246007: {KindHRESULT, "WU_E_DM_NOTDOWNLOADED", "update has not been downloaded"},
+	0x80246008: {KindHRESULT, "WU_E_DM_FAILTOCONNECTTOBITS", "could not connect to BITS; check the bits service"},
+	0x80248007: {KindHRESULT, "WU_E_DS_NODATA", "the requested information is not in the data store"},
+	0x8024A000: {KindHRESULT, "WU_E_AU_NOSERVICE", "Automatic Updates was unable to service incoming requests"},
+
+	// CBS / DISM
+	0x800F0805: {KindHRESULT, "CBS_E_INVALID_PACKAGE", "the update package is invalid"},
+	0x800F080C: {KindHRESULT, "CBS_E_UNKNOWN_UPDATE", "the specified package or feature name is not recognized"},
+	0x800F081E: {KindHRESULT, "CBS_E_NOT_APPLICABLE", "the package is not applicable to this image"},
+	0x800F081F: {KindHRESULT, "CBS_E_SOURCE_MISSING", "source files for a component could not be found; repair needs /Source or Windows Update access"},
+	0x800F0821: {KindHRESULT, "CBS_E_ABORT", "the servicing operation was aborted, often on a timeout"},
+	0x800F0823: {KindHRESULT, "CBS_E_NEW_SERVICING_STACK_REQUIRED", "a newer servicing stack update must be installed first"},
+	0x800F0831: {KindHRESULT, "CBS_E_STORE_CORRUPTION", "component store corruption; a manifest or payload for a prior update is missing"},
+	0x800F0900: {KindHRESULT, "CBS_E_XML_PARSER_FAILURE", "unexpected internal XML parser error"},
+	0x800F0906: {KindHRESULT, "CBS_E_DOWNLOAD_FAILURE", "source files could not be downloaded"},
+	0x800F0907: {KindHRESULT, "CBS_E_GROUPPOLICY_DISALLOWED", "policy blocks downloading repair content from Windows Update"},
+	0x800F0920: {KindHRESULT, "CBS_E_HANG_DETECTED", "a servicing operation hung"},
+	0x800F0922: {KindHRESULT, "CBS_E_INSTALLERS_FAILED", "an advanced installer failed; often a full System Reserved partition or blocked connection"},
+
+	// Certificates and trust
+	0x80092004: {KindHRESULT, "CRYPT_E_NOT_FOUND", "cannot find object or property"},
+	0x80096004: {KindHRESULT, "TRUST_E_CERT_SIGNATURE", "the certificate signature could not be verified"},
+	0x800B0100: {KindHRESULT, "TRUST_E_NOSIGNATURE", "no signature was present in the subject"},
+	0x800B0101: {KindHRESULT, "CERT_E_EXPIRED", "a required certificate is not within its validity period"},
+	0x800B0109: {KindHRESULT, "CERT_E_UNTRUSTEDROOT", "certificate chain terminated in an untrusted root"},
+
+	// NTSTATUS
+	0x80000003: {KindNTSTATUS, "STATUS_BREAKPOINT", "a breakpoint was reached"},
+	0xC0000005: {KindNTSTATUS, "STATUS_ACCESS_VIOLATION", "invalid memory access"},
+	0xC0000006: {KindNTSTATUS, "STATUS_IN_PAGE_ERROR", "page could not be read in; suspect disk, network share or storage path"},
+	0xC0000008: {KindNTSTATUS, "STATUS_INVALID_HANDLE", "an invalid handle was specified"},
+	0xC000000D: {KindNTSTATUS, "STATUS_INVALID_PARAMETER", "an invalid parameter was passed"},
+	0xC000000E: {KindNTSTATUS, "STATUS_NO_SUCH_DEVICE", "the device does not exist"},
+	0xC0000017: {KindNTSTATUS, "STATUS_NO_MEMORY", "not enough virtual memory or paging file quota"},
+	0xC000001D: {KindNTSTATUS, "STATUS_ILLEGAL_INSTRUCTION", "an illegal instruction was executed"},
+	0xC0000022: {KindNTSTATUS, "STATUS_ACCESS_DENIED", "access denied"},
+	0xC0000034: {KindNTSTATUS, "STATUS_OBJECT_NAME_NOT_FOUND", "object name not found"},
+	0xC000007B: {KindNTSTATUS, "STATUS_INVALID_IMAGE_FORMAT", "bad image; a DLL is corrupt or the wrong architecture"},
+	0xC0000094: {KindNTSTATUS, "STATUS_INTEGER_DIVIDE_BY_ZERO", "integer division by zero"},
+	0xC0000096: {KindNTSTATUS, "STATUS_PRIVILEGED_INSTRUCTION", "a privileged instruction was executed in user mode"},
+	0xC000009A: {KindNTSTATUS, "STATUS_INSUFFICIENT_RESOURCES", "insufficient system resources"},
+	0xC000009C: {KindNTSTATUS, "STATUS_DEVICE_DATA_ERROR", "data error on the device; suspect disk"},
+	0xC00000FD: {KindNTSTATUS, "STATUS_STACK_OVERFLOW", "stack overflow, usually unbounded recursion"},
+	0xC0000102: {KindNTSTATUS, "STATUS_FILE_CORRUPT_ERROR", "file or directory is corrupt and unreadable"},
+	0xC0000135: {KindNTSTATUS, "STATUS_DLL_NOT_FOUND", "a required DLL was not found"},
+	0xC0000139: {KindNTSTATUS, "STATUS_ENTRYPOINT_NOT_FOUND", "a DLL entry point was not found; mismatched DLL versions"},
+	0xC0000142: {KindNTSTATUS, "STATUS_DLL_INIT_FAILED", "a DLL failed to initialize, often desktop heap exhaustion"},
+	0xC0000185: {KindNTSTATUS, "STATUS_IO_DEVICE_ERROR", "I/O device error"},
+	0xC000021A: {KindNTSTATUS, "STATUS_SYSTEM_PROCESS_TERMINATED", "winlogon or csrss terminated"},
+	0xC0000374: {KindNTSTATUS, "STATUS_HEAP_CORRUPTION", "heap corruption detected"},
+	0xC0000409: {KindNTSTATUS, "STATUS_STACK_BUFFER_OVERRUN", "stack buffer overrun or fail-fast exception"},
+	0xC0000417: {KindNTSTATUS, "STATUS_INVALID_CRUNTIME_PARAMETER", "invalid parameter passed to a C runtime function"},
+	0xC000041D: {KindNTSTATUS, "STATUS_FATAL_USER_CALLBACK_EXCEPTION", "unhandled exception in a user callback"},
+	0xC0000420: {KindNTSTATUS, "STATUS_ASSERTION_FAILURE", "an assertion failed"},
+	0xC0000602: {KindNTSTATUS, "STATUS_FAIL_FAST_EXCEPTION", "fail-fast exception raised by the process"},
+
+	// Language runtime exceptions
+	0xE0434352: {KindException, "CLR_EXCEPTION", "unhandled .NET exception; see the .NET Runtime 1026 event for the type"},
+	0xE06D7363: {KindException, "MSVC_CPP_EXCEPTION", "unhandled C++ exception"},
+}
+
+// win32 are Win32 error codes, also matched inside HRESULT_FROM_WIN32
+// values (0x8007xxxx).
+var win32 = map[uint32]entry{
+	2:     {KindWin32, "ERROR_FILE_NOT_FOUND", "file not found"},
+	3:     {KindWin32, "ERROR_PATH_NOT_FOUND", "path not found"},
+	5:     {KindWin32, "ERROR_ACCESS_DENIED", "access denied"},
+	6:     {KindWin32, "ERROR_INVALID_HANDLE", "invalid handle"},
+	8:     {KindWin32, "ERROR_NOT_ENOUGH_MEMORY", "not enough memory"},
+	13:    {KindWin32, "ERROR_INVALID_DATA", "invalid data"},
+	14:    {KindWin32, "ERROR_OUTOFMEMORY", "not enough storage to complete the operation"},
+	21:    {KindWin32, "ERROR_NOT_READY", "device not ready"},
+	23:    {KindWin32, "ERROR_CRC", "data error (cyclic redundancy check); suspect disk"},
+	31:    {KindWin32, "ERROR_GEN_FAILURE", "a device attached to the system is not functioning"},
+	32:    {KindWin32, "ERROR_SHARING_VIOLATION", "file is in use by another process"},
+	33:    {KindWin32, "ERROR_LOCK_VIOLATION", "part of the file is locked by another process"},
+	50:    {KindWin32, "ERROR_NOT_SUPPORTED", "request not supported"},
+	87:    {KindWin32, "ERROR_INVALID_PARAMETER", "invalid parameter"},
+	112:   {KindWin32, "ERROR_DISK_FULL", "not enough space on the disk"},
+	122:   {KindWin32, "ERROR_INSUFFICIENT_BUFFER", "data area passed to a system call is too small"},
+	126:   {KindWin32, "ERROR_MOD_NOT_FOUND", "module not found"},
+	127:   {KindWin32, "ERROR_PROC_NOT_FOUND", "procedure not found"},
+	183:   {KindWin32, "ERROR_ALREADY_EXISTS", "file already exists"},
+	193:   {KindWin32, "ERROR_BAD_EXE_FORMAT", "not a valid Win32 application"},
+	1053:  {KindWin32, "ERROR_SERVICE_REQUEST_TIMEOUT", "service did not respond to the start or control request in time"},
+	1058:  {KindWin32, "ERROR_SERVICE_DISABLED", "service is disabled; check wuauserv, bits and cryptsvc start types"},
+	1060:  {KindWin32, "ERROR_SERVICE_DOES_NOT_EXIST", "service is not installed"},
+	1062:  {KindWin32, "ERROR_SERVICE_NOT_ACTIVE", "service has not been started"},
+	1067:  {KindWin32, "ERROR_PROCESS_ABORTED", "process terminated unexpectedly"},
+	1115:  {KindWin32, "ERROR_SHUTDOWN_IN_PROGRESS", "system shutdown is in progress"},
+	1168:  {KindWin32, "ERROR_NOT_FOUND", "element not found"},
+	1223:  {KindWin32, "ERROR_CANCELLED", "operation was cancelled by the user"},
+	1392:  {KindWin32, "ERROR_FILE_CORRUPT", "file or directory is corrupt and unreadable"},
+	1393:  {KindWin32, "ERROR_DISK_CORRUPT", "disk structure is corrupt and unreadable"},
+	1450:  {KindWin32, "ERROR_NO_SYSTEM_RESOURCES", "insufficient system resources"},
+	1460:  {KindWin32, "ERROR_TIMEOUT", "operation timed out"},
+	1603:  {KindWin32, "ERROR_INSTALL_FAILURE", "fatal error during installation"},
+	1618:  {KindWin32, "ERROR_INSTALL_ALREADY_RUNNING", "another installation is in progress"},
+	1722:  {KindWin32, "RPC_S_SERVER_UNAVAILABLE", "RPC server is unavailable"},
+	1726:  {KindWin32, "RPC_S_CALL_FAILED", "remote procedure call failed"},
+	3010:  {KindWin32, "ERROR_SUCCESS_REBOOT_REQUIRED", "succeeded; a reboot is required to complete"},
+	3017:  {KindWin32, "ERROR_FAIL_REBOOT_REQUIRED", "operation failed and a reboot is required to roll back"},
+	12002: {KindWin32, "ERROR_WINHTTP_TIMEOUT", "WinHTTP request timed out"},
+	12007: {KindWin32, "ERROR_WINHTTP_NAME_NOT_RESOLVED", "server or proxy name could not be resolved"},
+	12029: {KindWin32, "ERROR_WINHTTP_CANNOT_CONNECT", "connection to the server failed; check proxy and firewall"},
+	12030: {KindWin32, "ERROR_WINHTTP_CONNECTION_ERROR", "connection was reset or terminated"},
+	12037: {KindWin32, "ERROR_WINHTTP_SECURE_CERT_DATE_INVALID", "server certificate is expired or not yet valid"},
+	12038: {KindWin32, "ERROR_WINHTTP_SECURE_CERT_CN_INVALID", "server certificate name does not match"},
+	12044: {KindWin32, "ERROR_WINHTTP_CLIENT_AUTH_CERT_NEEDED", "server requires a client certificate"},
+	12152: {KindWin32, "ERROR_WINHTTP_INVALID_SERVER_RESPONSE", "server response could not be parsed"},
+	12175: {KindWin32, "ERROR_WINHTTP_SECURE_FAILURE", "TLS failure; check certificates, TLS versions and SSL inspection"},
+	12180: {KindWin32, "ERROR_WINHTTP_AUTODETECTION_FAILED", "proxy auto-detection (WPAD) failed"},
+	14003: {KindWin32, "ERROR_SXS_ASSEMBLY_NOT_FOUND", "a side-by-side assembly was not found"},
+	14028: {KindWin32, "ERROR_SXS_FILE_HASH_MISMATCH", "a component file does not match the manifest hash"},
+	14081: {KindWin32, "ERROR_SXS_ASSEMBLY_MISSING", "a referenced assembly is not installed"},
+	14098: {KindWin32, "ERROR_SXS_COMPONENT_STORE_CORRUPT", "component store is inconsistent; run DISM /RestoreHealth"},
+	14107: {KindWin32, "ERROR_SXS_TRANSACTION_CLOSURE_INCOMPLETE", "one or more required members of the transaction are missing"},
+}
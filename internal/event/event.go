@@ -0,0 +1,246 @@
+// Package event defines the canonical, versioned event schema shared by the
+// collector (winopsguard.exe), the agent and the triage stage.
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is bumped whenever Event or Document changes incompatibly.
+const SchemaVersion = 1
+
+// DocumentKind identifies an event document among other pipeline inputs.
+const DocumentKind = "eventlog"
+
+// ErrNotEvents is returned by Decode when the input is some other document.
+var ErrNotEvents = errors.New("input is not an event document")
+
+// Event is a Windows event log entry after normalization.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	EventID uint32    `json:"event_id"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+	Channel string    `json:"channel,omitempty"`
+	// RecordID is the channel's EventRecordID, used for bookmarks.
+	RecordID   uint64 `json:"record_id,omitempty"`
+	Computer   string `json:"computer,omitempty"`
+	Task       uint16 `json:"task,omitempty"`
+	Opcode     uint8  `json:"opcode,omitempty"`
+	Keywords   string `json:"keywords,omitempty"`
+	ProcessID  uint32 `json:"process_id,omitempty"`
+	ThreadID   uint32 `json:"thread_id,omitempty"`
+	ActivityID string `json:"activity_id,omitempty"`
+	// Data holds EventData/UserData values by name; unnamed values are
+	// keyed param1, param2, ...
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Document is the envelope every CLI emits for a list of events.
+type Document struct {
+	SchemaVersion int       `json:"schema_version"`
+	Kind          string    `json:"kind"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Events        []Event   `json:"events"`
+}
+
+// NewDocument wraps events in the current schema version.
+func NewDocument(events []Event) Document {
+	if events == nil {
+		events = []Event{}
+	}
+	return Document{
+		SchemaVersion: SchemaVersion,
+		Kind:          DocumentKind,
+		GeneratedAt:   time.Now().UTC(),
+		Events:        events,
+	}
+}
+
+// wireEvent accepts the canonical snake_case shape, the legacy camelCase
+// shape emitted by winopsguard.exe before schema_version existed, and the
+// PowerShell ConvertTo-Json date form.
+type wireEvent struct {
+	Time          wireTime          `json:"time"`
+	TimeGenerated wireTime          `json:"timeGenerated"`
+	Level         string            `json:"level"`
+	EventID       *uint32           `json:"event_id"`
+	EventIDCamel  *uint32           `json:"eventId"`
+	Source        string            `json:"source"`
+	Message       string            `json:"message"`
+	Channel       string            `json:"channel"`
+	RecordID      uint64            `json:"record_id"`
+	RecordIDCamel uint64            `json:"recordId"`
+	Computer      string            `json:"computer"`
+	Task          uint16            `json:"task"`
+	Opcode        uint8             `json:"opcode"`
+	Keywords      string            `json:"keywords"`
+	ProcessID     uint32            `json:"process_id"`
+	ProcessCamel  uint32            `json:"processId"`
+	ThreadID      uint32            `json:"thread_id"`
+	ThreadCamel   uint32            `json:"threadId"`
+	ActivityID    string            `json:"activity_id"`
+	ActivityCamel string            `json:"activityId"`
+	Data          map[string]string `json:"data"`
+}
+
+func (w wireEvent) isEvent() bool {
+	return w.EventID != nil || w.EventIDCamel != nil
+}
+
+func (w wireEvent) event() Event {
+	ev := Event{
+		Time:       w.Time.t,
+		Level:      w.Level,
+		Source:     w.Source,
+		Message:    w.Message,
+		Channel:    w.Channel,
+		RecordID:   w.RecordID,
+		Computer:   w.Computer,
+		Task:       w.Task,
+		Opcode:     w.Opcode,
+		Keywords:   w.Keywords,
+		ProcessID:  w.ProcessID,
+		ThreadID:   w.ThreadID,
+		ActivityID: w.ActivityID,
+		Data:       w.Data,
+	}
+	if ev.Time.IsZero() {
+		ev.Time = w.TimeGenerated.t
+	}
+	if w.EventID != nil {
+		ev.EventID = *w.EventID
+	} else if w.EventIDCamel != nil {
+		ev.EventID = *w.EventIDCamel
+	}
+	if ev.RecordID == 0 {
+		ev.RecordID = w.RecordIDCamel
+	}
+	if ev.ProcessID == 0 {
+		ev.ProcessID = w.ProcessCamel
+	}
+	if ev.ThreadID == 0 {
+		ev.ThreadID = w.ThreadCamel
+	}
+	if ev.ActivityID == "" {
+		ev.ActivityID = w.ActivityCamel
+	}
+	return ev
+}
+
+type wireTime struct{ t time.Time }
+
+func (w *wireTime) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		if string(b) == "null" {
+			return nil
+		}
+		return fmt.Errorf("time: %w", err)
+	}
+	t, err := parseTime(s)
+	if err != nil {
+		return err
+	}
+	w.t = t
+	return nil
+}
+
+// parseTime accepts RFC 3339 and Windows PowerShell's "/Date(ms)/" form.
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasPrefix(s, "/Date(") && strings.HasSuffix(s, ")/") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(s, "/Date("), ")/")
+		if inner == "" {
+			return time.Time{}, fmt.Errorf("time %q: no milliseconds", s)
+		}
+		// Drop an optional +hhmm offset; the value is already UTC-based.
+		if i := strings.IndexAny(inner[1:], "+-"); i >= 0 {
+			inner = inner[:i+1]
+		}
+		ms, err := strconv.ParseInt(inner, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time %q: %w", s, err)
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time %q: %w", s, err)
+	}
+	return t.UTC(), nil
+}
+
+// Decode reads a Document, a bare array of events in either the canonical or
+// legacy shape, or a single event object. It returns ErrNotEvents for any
+// other JSON so callers can pass such input through untouched.
+func Decode(data []byte) ([]Event, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, ErrNotEvents
+	}
+	switch data[0] {
+	case '[':
+		return decodeArray(data)
+	case '{':
+		var probe struct {
+			SchemaVersion *int            `json:"schema_version"`
+			SchemaCamel   *int            `json:"schemaVersion"`
+			Kind          string          `json:"kind"`
+			Events        json.RawMessage `json:"events"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, err
+		}
+		if len(probe.Events) > 0 && (probe.Kind == "" || probe.Kind == DocumentKind) {
+			version := 0
+			if probe.SchemaVersion != nil {
+				version = *probe.SchemaVersion
+			} else if probe.SchemaCamel != nil {
+				version = *probe.SchemaCamel
+			}
+			if version > SchemaVersion {
+				return nil, fmt.Errorf("unsupported event schema_version %d (max %d)", version, SchemaVersion)
+			}
+			return decodeArray(probe.Events)
+		}
+		var w wireEvent
+		if err := json.Unmarshal(data, &w); err != nil || !w.isEvent() {
+			return nil, ErrNotEvents
+		}
+		return []Event{w.event()}, nil
+	}
+	return nil, ErrNotEvents
+}
+
+func decodeArray(data []byte) ([]Event, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, ErrNotEvents
+	}
+	events := make([]Event, 0, len(raw))
+	for i, r := range raw {
+		var w wireEvent
+		if err := json.Unmarshal(r, &w); err != nil {
+			if i == 0 {
+				return nil, ErrNotEvents
+			}
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		if !w.isEvent() {
+			return nil, ErrNotEvents
+		}
+		events = append(events, w.event())
+	}
+	return events, nil
+}
@@ -0,0 +1,140 @@
+package event
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2025-12-18T14:00:00Z", time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC), false},
+		{"2025-12-18T14:00:00.1234567Z", time.Date(2025, 12, 18, 14, 0, 0, 123456700, time.UTC), false},
+		{"2025-12-18T15:00:00+01:00", time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC), false},
+		{"/Date(1766066400000)/", time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC), false},
+		{"/Date(1766066400000+0100)/", time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC), false},
+		{"/Date(1766066400000-0500)/", time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC), false},
+		{"/Date(-1000)/", time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC), false},
+		{"/Date()/", time.Time{}, true},
+		{"/Date(-)/", time.Time{}, true},
+		{"/Date(abc)/", time.Time{}, true},
+		{"18/12/2025 14:00", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTime(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeFixtures(t *testing.T) {
+	base := Event{
+		Time:    time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC),
+		Level:   "Error",
+		EventID: 20,
+		Source:  "Microsoft-Windows-WindowsUpdateClient",
+		Message: "Installation Failure: Windows failed to install the following update with error 0x800f081f.",
+	}
+	v1 := base
+	v1.Channel = "System"
+	v1.RecordID = 48213
+	v1.Computer = "web01.contoso.local"
+	v1.Data = map[string]string{
+		"errorCode":   "0x800f081f",
+		"updateTitle": "2025-12 Cumulative Update for Windows Server 2022 (KB5071547)",
+	}
+
+	tests := []struct {
+		file string
+		want []Event
+	}{
+		// The camelCase array winopsguard.exe emitted before schema_version.
+		{"windows_update_corruption.json", []Event{base}},
+		{"windows_update_corruption_v1.json", []Event{v1}},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("..", "..", "testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Decode(data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	at := time.Date(2025, 12, 18, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		in      string
+		want    []Event
+		wantErr string
+	}{
+		{"single canonical event", `{"time":"2025-12-18T14:00:00Z","event_id":41,"level":"Critical","source":"Microsoft-Windows-Kernel-Power"}`,
+			[]Event{{Time: at, EventID: 41, Level: "Critical", Source: "Microsoft-Windows-Kernel-Power"}}, ""},
+		{"powershell date and camelCase ids", `[{"timeGenerated":"/Date(1766066400000)/","eventId":7031,"recordId":9,"processId":4,"threadId":8,"activityId":"{A}"}]`,
+			[]Event{{Time: at, EventID: 7031, RecordID: 9, ProcessID: 4, ThreadID: 8, ActivityID: "{A}"}}, ""},
+		{"canonical wins over legacy", `[{"time":"2025-12-18T14:00:00Z","timeGenerated":"2020-01-01T00:00:00Z","event_id":1,"eventId":2}]`,
+			[]Event{{Time: at, EventID: 1}}, ""},
+		{"null time", `[{"time":null,"event_id":1}]`, []Event{{EventID: 1}}, ""},
+		{"empty document", `{"schema_version":1,"kind":"eventlog","events":[]}`, []Event{}, ""},
+		{"legacy schemaVersion", `{"schemaVersion":1,"events":[{"eventId":5}]}`, []Event{{EventID: 5}}, ""},
+		{"future version", `{"schema_version":2,"kind":"eventlog","events":[{"event_id":1}]}`, nil, "unsupported event schema_version 2"},
+		{"other kind", `{"kind":"cve_assessment","events":[{"event_id":1}]}`, nil, "not an event document"},
+		{"object without event id", `{"incident_type":"windows_update_failure"}`, nil, "not an event document"},
+		{"array of other objects", `[{"cve":"CVE-2025-0001"}]`, nil, "not an event document"},
+		{"bad time in later event", `[{"event_id":1},{"event_id":2,"time":"/Date()/"}]`, nil, "event 1: "},
+		{"text", `CVE-2025-0001 affects KB5071547`, nil, "not an event document"},
+		{"empty", ``, nil, "not an event document"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode([]byte(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrNotEvents(t *testing.T) {
+	if _, err := Decode([]byte(`{"kb":"KB5071547"}`)); !errors.Is(err, ErrNotEvents) {
+		t.Errorf("err = %v, want ErrNotEvents", err)
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	doc := NewDocument(nil)
+	if doc.SchemaVersion != SchemaVersion || doc.Kind != DocumentKind || doc.Events == nil {
+		t.Errorf("unexpected document %+v", doc)
+	}
+}
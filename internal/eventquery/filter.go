@@ -0,0 +1,322 @@
+// Package eventquery compiles structured event filters into the XPath subset
+// accepted by EvtQuery and Get-WinEvent -FilterXPath.
+package eventquery
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Level is the numeric System/Level of an event.
+type Level uint8
+
+const (
+	LevelLogAlways   Level = 0
+	LevelCritical    Level = 1
+	LevelError       Level = 2
+	LevelWarning     Level = 3
+	LevelInformation Level = 4
+	LevelVerbose     Level = 5
+)
+
+// Standard keyword bits shared by all providers.
+const (
+	KeywordResponseTime    uint64 = 0x0001000000000000
+	KeywordWdiContext      uint64 = 0x0002000000000000
+	KeywordWdiDiagnostic   uint64 = 0x0004000000000000
+	KeywordSqm             uint64 = 0x0008000000000000
+	KeywordAuditFailure    uint64 = 0x0010000000000000
+	KeywordAuditSuccess    uint64 = 0x0020000000000000
+	KeywordCorrelationHint uint64 = 0x0040000000000000
+	KeywordClassic         uint64 = 0x0080000000000000
+)
+
+var keywordNames = map[string]uint64{
+	"responsetime":    KeywordResponseTime,
+	"wdicontext":      KeywordWdiContext,
+	"wdidiagnostic":   KeywordWdiDiagnostic,
+	"sqm":             KeywordSqm,
+	"auditfailure":    KeywordAuditFailure,
+	"auditsuccess":    KeywordAuditSuccess,
+	"correlationhint": KeywordCorrelationHint,
+	"classic":         KeywordClassic,
+	"eventlogclassic": KeywordClassic,
+}
+
+// IDRange is an inclusive event ID range; a single ID has Min == Max.
+type IDRange struct {
+	Min uint32
+	Max uint32
+}
+
+// Filter describes which events to return. Empty fields do not restrict.
+type Filter struct {
+	Providers []string
+	EventIDs  []IDRange
+	Levels    []Level
+	// Keywords matches events carrying any of the given bits.
+	Keywords uint64
+	// Window limits results to events created within this duration of now.
+	Window time.Duration
+	// AfterRecordID skips events up to and including this record (bookmark).
+	AfterRecordID uint64
+}
+
+// Fields are the System values Match inspects.
+type Fields struct {
+	Provider string
+	EventID  uint32
+	Level    Level
+	Keywords uint64
+	RecordID uint64
+}
+
+// XPath renders the filter. An empty filter selects every event.
+func (f Filter) XPath() (string, error) {
+	var clauses []string
+
+	if len(f.Providers) > 0 {
+		var parts []string
+		for _, p := range f.Providers {
+			lit, err := quote(p)
+			if err != nil {
+				return "", fmt.Errorf("provider %q: %w", p, err)
+			}
+			parts = append(parts, "@Name="+lit)
+		}
+		clauses = append(clauses, "Provider["+strings.Join(parts, " or ")+"]")
+	}
+
+	if len(f.Levels) > 0 {
+		var parts []string
+		for _, l := range dedupLevels(f.Levels) {
+			if l > LevelVerbose {
+				return "", fmt.Errorf("invalid level %d", l)
+			}
+			parts = append(parts, fmt.Sprintf("Level=%d", l))
+		}
+		clauses = append(clauses, group(parts))
+	}
+
+	if len(f.EventIDs) > 0 {
+		var parts []string
+		for _, r := range f.EventIDs {
+			if r.Min > r.Max {
+				return "", fmt.Errorf("invalid event ID range %d-%d", r.Min, r.Max)
+			}
+			if r.Min == r.Max {
+				parts = append(parts, fmt.Sprintf("EventID=%d", r.Min))
+			} else {
+				parts = append(parts, fmt.Sprintf("(EventID>=%d and EventID<=%d)", r.Min, r.Max))
+			}
+		}
+		clauses = append(clauses, group(parts))
+	}
+
+	if f.Keywords != 0 {
+		clauses = append(clauses, fmt.Sprintf("band(Keywords,%d)", f.Keywords))
+	}
+
+	if f.Window > 0 {
+		clauses = append(clauses, fmt.Sprintf("TimeCreated[timediff(@SystemTime) <= %d]", f.Window.Milliseconds()))
+	}
+
+	if f.AfterRecordID > 0 {
+		clauses = append(clauses, fmt.Sprintf("EventRecordID>%d", f.AfterRecordID))
+	}
+
+	if len(clauses) == 0 {
+		return "*", nil
+	}
+	return "*[System[" + strings.Join(clauses, " and ") + "]]", nil
+}
+
+// Match applies everything but the time window to an already-rendered event,
+// e.g. one read from an exported .evtx file.
+func (f Filter) Match(e Fields) bool {
+	if f.AfterRecordID > 0 && e.RecordID <= f.AfterRecordID {
+		return false
+	}
+	if len(f.Providers) > 0 {
+		ok := false
+		for _, p := range f.Providers {
+			if strings.EqualFold(p, e.Provider) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(f.Levels) > 0 {
+		ok := false
+		for _, l := range dedupLevels(f.Levels) {
+			if l == e.Level {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(f.EventIDs) > 0 {
+		ok := false
+		for _, r := range f.EventIDs {
+			if e.EventID >= r.Min && e.EventID <= r.Max {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.Keywords != 0 && e.Keywords&f.Keywords == 0 {
+		return false
+	}
+	return true
+}
+
+// dedupLevels expands Information to also cover LogAlways (0), which is how
+// classic providers report informational events.
+func dedupLevels(levels []Level) []Level {
+	seen := map[Level]bool{}
+	var out []Level
+	add := func(l Level) {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	for _, l := range levels {
+		add(l)
+		if l == LevelInformation {
+			add(LevelLogAlways)
+		}
+	}
+	return out
+}
+
+func group(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " or ") + ")"
+}
+
+// quote produces an XPath 1.0 string literal. XPath has no escape sequences,
+// so a value containing both quote characters cannot be expressed.
+func quote(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("empty value")
+	}
+	for _, r := range s {
+		if r < 0x20 {
+			return "", errors.New("control character in value")
+		}
+	}
+	switch {
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'", nil
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`, nil
+	default:
+		return "", errors.New("value contains both quote characters")
+	}
+}
+
+// ParseLevels parses a comma-separated list of level names or numbers.
+func ParseLevels(s string) ([]Level, error) {
+	var out []Level
+	for _, item := range splitList(s) {
+		l, err := ParseLevel(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// ParseLevel accepts critical|error|warning|information|verbose or 0-5.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "critical", "crit":
+		return LevelCritical, nil
+	case "error", "err":
+		return LevelError, nil
+	case "warning", "warn":
+		return LevelWarning, nil
+	case "information", "info":
+		return LevelInformation, nil
+	case "verbose":
+		return LevelVerbose, nil
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 8)
+	if err != nil || n > uint64(LevelVerbose) {
+		return 0, fmt.Errorf("unknown level %q (use critical|error|warning|information|verbose)", s)
+	}
+	return Level(n), nil
+}
+
+// ParseEventIDs parses "20,25,1000-1005".
+func ParseEventIDs(s string) ([]IDRange, error) {
+	var out []IDRange
+	for _, item := range splitList(s) {
+		lo, hi, isRange := strings.Cut(item, "-")
+		min, err := strconv.ParseUint(strings.TrimSpace(lo), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event ID %q", item)
+		}
+		max := min
+		if isRange {
+			max, err = strconv.ParseUint(strings.TrimSpace(hi), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid event ID range %q", item)
+			}
+		}
+		if min > max {
+			return nil, fmt.Errorf("invalid event ID range %q", item)
+		}
+		out = append(out, IDRange{Min: uint32(min), Max: uint32(max)})
+	}
+	return out, nil
+}
+
+// ParseKeywords parses keyword names (e.g. AuditFailure, Classic) or hex
+// masks and ORs them together.
+func ParseKeywords(s string) (uint64, error) {
+	var mask uint64
+	for _, item := range splitList(s) {
+		if v, ok := keywordNames[strings.ToLower(item)]; ok {
+			mask |= v
+			continue
+		}
+		v, err := strconv.ParseUint(item, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unknown keyword %q", item)
+		}
+		mask |= v
+	}
+	return mask, nil
+}
+
+// ParseProviders splits a comma-separated provider list.
+func ParseProviders(s string) []string {
+	return splitList(s)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
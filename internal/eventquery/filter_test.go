@@ -0,0 +1,210 @@
+package eventquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		want    string
+		wantErr string
+	}{
+		{"empty", Filter{}, "*", ""},
+		{"provider", Filter{Providers: []string{"Microsoft-Windows-WindowsUpdateClient"}},
+			"*[System[Provider[@Name='Microsoft-Windows-WindowsUpdateClient']]]", ""},
+		{"providers", Filter{Providers: []string{"disk", "Ntfs"}},
+			"*[System[Provider[@Name='disk' or @Name='Ntfs']]]", ""},
+		{"provider with apostrophe", Filter{Providers: []string{"Contoso's Agent"}},
+			`*[System[Provider[@Name="Contoso's Agent"]]]`, ""},
+		{"provider with double quote", Filter{Providers: []string{`The "Agent"`}},
+			`*[System[Provider[@Name='The "Agent"']]]`, ""},
+		{"provider with both quotes", Filter{Providers: []string{`It's "x"`}}, "", "both quote characters"},
+		{"empty provider", Filter{Providers: []string{""}}, "", "empty value"},
+		{"provider with control character", Filter{Providers: []string{"a\nb"}}, "", "control character"},
+		{"single level", Filter{Levels: []Level{LevelError}}, "*[System[Level=2]]", ""},
+		{"levels", Filter{Levels: []Level{LevelCritical, LevelError, LevelCritical}},
+			"*[System[(Level=1 or Level=2)]]", ""},
+		{"information covers log always", Filter{Levels: []Level{LevelInformation}},
+			"*[System[(Level=4 or Level=0)]]", ""},
+		{"invalid level", Filter{Levels: []Level{6}}, "", "invalid level 6"},
+		{"single id", Filter{EventIDs: []IDRange{{20, 20}}}, "*[System[EventID=20]]", ""},
+		{"ids and range", Filter{EventIDs: []IDRange{{20, 20}, {1000, 1005}}},
+			"*[System[(EventID=20 or (EventID>=1000 and EventID<=1005))]]", ""},
+		{"range only", Filter{EventIDs: []IDRange{{41, 43}}}, "*[System[(EventID>=41 and EventID<=43)]]", ""},
+		{"inverted range", Filter{EventIDs: []IDRange{{5, 1}}}, "", "invalid event ID range 5-1"},
+		{"keywords", Filter{Keywords: KeywordAuditFailure | KeywordClassic},
+			"*[System[band(Keywords,40532396646334464)]]", ""},
+		{"window", Filter{Window: 2 * time.Hour}, "*[System[TimeCreated[timediff(@SystemTime) <= 7200000]]]", ""},
+		{"after record", Filter{AfterRecordID: 512}, "*[System[EventRecordID>512]]", ""},
+		{"everything", Filter{
+			Providers:     []string{"Service Control Manager"},
+			Levels:        []Level{LevelCritical, LevelError},
+			EventIDs:      []IDRange{{7031, 7031}, {7034, 7036}},
+			Keywords:      KeywordClassic,
+			Window:        time.Minute,
+			AfterRecordID: 9,
+		}, "*[System[Provider[@Name='Service Control Manager'] and (Level=1 or Level=2)" +
+			" and (EventID=7031 or (EventID>=7034 and EventID<=7036)) and band(Keywords,36028797018963968)" +
+			" and TimeCreated[timediff(@SystemTime) <= 60000] and EventRecordID>9]]", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.XPath()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	ev := Fields{Provider: "Microsoft-Windows-WindowsUpdateClient", EventID: 20, Level: LevelError, Keywords: KeywordClassic, RecordID: 100}
+	tests := []struct {
+		name   string
+		filter Filter
+		fields Fields
+		want   bool
+	}{
+		{"empty", Filter{}, ev, true},
+		{"provider case-insensitive", Filter{Providers: []string{"microsoft-windows-windowsupdateclient"}}, ev, true},
+		{"other provider", Filter{Providers: []string{"disk"}}, ev, false},
+		{"level", Filter{Levels: []Level{LevelWarning, LevelError}}, ev, true},
+		{"other level", Filter{Levels: []Level{LevelCritical}}, ev, false},
+		{"information matches log always", Filter{Levels: []Level{LevelInformation}}, Fields{Level: LevelLogAlways}, true},
+		{"id in range", Filter{EventIDs: []IDRange{{1, 1}, {19, 21}}}, ev, true},
+		{"id outside ranges", Filter{EventIDs: []IDRange{{1, 19}, {21, 30}}}, ev, false},
+		{"keyword bit", Filter{Keywords: KeywordClassic | KeywordSqm}, ev, true},
+		{"no keyword bit", Filter{Keywords: KeywordAuditFailure}, ev, false},
+		{"after record", Filter{AfterRecordID: 99}, ev, true},
+		{"at bookmark", Filter{AfterRecordID: 100}, ev, false},
+		{"window ignored", Filter{Window: time.Nanosecond}, ev, true},
+		{"all clauses", Filter{
+			Providers: []string{"Microsoft-Windows-WindowsUpdateClient"},
+			Levels:    []Level{LevelError},
+			EventIDs:  []IDRange{{20, 20}},
+			Keywords:  KeywordClassic,
+		}, ev, true},
+		{"one clause fails", Filter{
+			Providers: []string{"Microsoft-Windows-WindowsUpdateClient"},
+			Levels:    []Level{LevelError},
+			EventIDs:  []IDRange{{21, 21}},
+		}, ev, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.fields); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Level
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"error", []Level{LevelError}, false},
+		{"Critical, warn ,info", []Level{LevelCritical, LevelWarning, LevelInformation}, false},
+		{"crit,err,verbose", []Level{LevelCritical, LevelError, LevelVerbose}, false},
+		{"0,5", []Level{LevelLogAlways, LevelVerbose}, false},
+		{"6", nil, true},
+		{"-1", nil, true},
+		{"error,fatal", nil, true},
+		{"256", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevels(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevels(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLevels(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseEventIDs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []IDRange
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"20", []IDRange{{20, 20}}, ""},
+		{"20, 25,1000-1005", []IDRange{{20, 20}, {25, 25}, {1000, 1005}}, ""},
+		{"7 - 9", []IDRange{{7, 9}}, ""},
+		{"4294967295", []IDRange{{4294967295, 4294967295}}, ""},
+		{"abc", nil, `invalid event ID "abc"`},
+		{"-5", nil, `invalid event ID "-5"`},
+		{"4294967296", nil, `invalid event ID "4294967296"`},
+		{"10-", nil, `invalid event ID range "10-"`},
+		{"10-x", nil, `invalid event ID range "10-x"`},
+		{"10-5", nil, `invalid event ID range "10-5"`},
+	}
+	for _, tt := range tests {
+		got, err := ParseEventIDs(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParseEventIDs(%q) err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEventIDs(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEventIDs(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeywords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr string
+	}{
+		{"", 0, ""},
+		{"AuditFailure", KeywordAuditFailure, ""},
+		{"classic,EventLogClassic", KeywordClassic, ""},
+		{"auditsuccess, 0x1", KeywordAuditSuccess | 1, ""},
+		{"0x8000000000000000", 0x8000000000000000, ""},
+		{"16", 16, ""},
+		{"Bogus", 0, `unknown keyword "Bogus"`},
+		{"0xZZ", 0, `unknown keyword "0xZZ"`},
+		{"0x10000000000000000", 0, `unknown keyword "0x10000000000000000"`},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeywords(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParseKeywords(%q) err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKeywords(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeywords(%q) = %#x, want %#x", tt.in, got, tt.want)
+		}
+	}
+}
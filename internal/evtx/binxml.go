@@ -0,0 +1,465 @@
+package evtx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BinXML tokens. The 0x40 bit marks "has more data" (attributes follow for
+// elements, another attribute or value follows for the rest).
+const (
+	tokEOF                = 0x00
+	tokOpenStartElement   = 0x01
+	tokCloseStartElement  = 0x02
+	tokCloseEmptyElement  = 0x03
+	tokCloseElement       = 0x04
+	tokValue              = 0x05
+	tokAttribute          = 0x06
+	tokCDataSection       = 0x07
+	tokCharRef            = 0x08
+	tokEntityRef          = 0x09
+	tokPITarget           = 0x0a
+	tokPIData             = 0x0b
+	tokTemplateInstance   = 0x0c
+	tokNormalSubstitution = 0x0d
+	tokOptionalSubst      = 0x0e
+	tokFragmentHeader     = 0x0f
+
+	tokMoreData = 0x40
+)
+
+var errTruncated = errors.New("binxml truncated")
+
+// substitution is one entry of a template instance's value array. The value
+// bytes stay in the chunk so that embedded BinXML can resolve its offsets.
+type substitution struct {
+	typ  byte
+	off  int
+	size int
+}
+
+type parser struct {
+	chunk []byte
+	off   int
+	end   int
+	subs  []substitution
+	depth int
+}
+
+func (p *parser) need(n int) error {
+	if p.off+n > p.end || p.off+n > len(p.chunk) {
+		return errTruncated
+	}
+	return nil
+}
+
+func (p *parser) u8() (byte, error) {
+	if err := p.need(1); err != nil {
+		return 0, err
+	}
+	v := p.chunk[p.off]
+	p.off++
+	return v, nil
+}
+
+func (p *parser) u16() (uint16, error) {
+	if err := p.need(2); err != nil {
+		return 0, err
+	}
+	v := binary.LittleEndian.Uint16(p.chunk[p.off:])
+	p.off += 2
+	return v, nil
+}
+
+func (p *parser) u32() (uint32, error) {
+	if err := p.need(4); err != nil {
+		return 0, err
+	}
+	v := binary.LittleEndian.Uint32(p.chunk[p.off:])
+	p.off += 4
+	return v, nil
+}
+
+func (p *parser) peek() (byte, error) {
+	if err := p.need(1); err != nil {
+		return 0, err
+	}
+	return p.chunk[p.off], nil
+}
+
+// utf16 reads a length-prefixed UTF-16LE string.
+func (p *parser) utf16() (string, error) {
+	n, err := p.u16()
+	if err != nil {
+		return "", err
+	}
+	if err := p.need(int(n) * 2); err != nil {
+		return "", err
+	}
+	s := decodeUTF16(p.chunk[p.off : p.off+int(n)*2])
+	p.off += int(n) * 2
+	return s, nil
+}
+
+// name resolves a chunk-relative name offset. Names are stored inline the
+// first time they are used, in which case the cursor is moved past them.
+func (p *parser) name() (string, error) {
+	off, err := p.u32()
+	if err != nil {
+		return "", err
+	}
+	s, size, err := readName(p.chunk, int(off))
+	if err != nil {
+		return "", err
+	}
+	if int(off) == p.off {
+		p.off += size
+	}
+	return s, nil
+}
+
+func readName(chunk []byte, off int) (string, int, error) {
+	if off < 0 || off+8 > len(chunk) {
+		return "", 0, fmt.Errorf("name offset %d out of range", off)
+	}
+	n := int(binary.LittleEndian.Uint16(chunk[off+6:]))
+	size := 8 + n*2 + 2
+	if off+size > len(chunk) {
+		return "", 0, fmt.Errorf("name at %d truncated", off)
+	}
+	return decodeUTF16(chunk[off+8 : off+8+n*2]), size, nil
+}
+
+// parseFragment reads tokens into parent until EOF or the end of the buffer.
+func (p *parser) parseFragment(parent *Element) error {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > 32 {
+		return errors.New("binxml nesting too deep")
+	}
+	for p.off < p.end {
+		tok, err := p.peek()
+		if err != nil {
+			return err
+		}
+		switch tok &^ tokMoreData {
+		case tokEOF:
+			p.off++
+			return nil
+		case tokFragmentHeader:
+			if err := p.need(4); err != nil {
+				return err
+			}
+			p.off += 4
+		case tokTemplateInstance:
+			if err := p.parseTemplateInstance(parent); err != nil {
+				return err
+			}
+		case tokOpenStartElement:
+			el, err := p.parseElement()
+			if err != nil {
+				return err
+			}
+			if el != nil {
+				parent.Children = append(parent.Children, el)
+			}
+		default:
+			if err := p.parseContent(parent, tok); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseTemplateInstance(parent *Element) error {
+	if err := p.need(10); err != nil {
+		return err
+	}
+	// Token and an unknown byte, then the template id.
+	p.off += 2
+	if _, err := p.u32(); err != nil {
+		return err
+	}
+	defOff, err := p.u32()
+	if err != nil {
+		return err
+	}
+	def := int(defOff)
+	if def+24 > len(p.chunk) {
+		return fmt.Errorf("template offset %d out of range", def)
+	}
+	dataSize := int(binary.LittleEndian.Uint32(p.chunk[def+20:]))
+	if def+24+dataSize > len(p.chunk) {
+		return fmt.Errorf("template at %d truncated", def)
+	}
+	if def == p.off {
+		// Definition is stored inline right after the instance header.
+		p.off = def + 24 + dataSize
+	}
+
+	count, err := p.u32()
+	if err != nil {
+		return err
+	}
+	if count > 4096 {
+		return fmt.Errorf("unreasonable substitution count %d", count)
+	}
+	subs := make([]substitution, count)
+	for i := range subs {
+		size, err := p.u16()
+		if err != nil {
+			return err
+		}
+		typ, err := p.u8()
+		if err != nil {
+			return err
+		}
+		if _, err := p.u8(); err != nil {
+			return err
+		}
+		subs[i] = substitution{typ: typ, size: int(size)}
+	}
+	for i := range subs {
+		if err := p.need(subs[i].size); err != nil {
+			return err
+		}
+		subs[i].off = p.off
+		p.off += subs[i].size
+	}
+
+	body := &parser{chunk: p.chunk, off: def + 24, end: def + 24 + dataSize, subs: subs, depth: p.depth}
+	return body.parseFragment(parent)
+}
+
+func (p *parser) parseElement() (*Element, error) {
+	tok, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.need(6); err != nil {
+		return nil, err
+	}
+	p.off += 6 // dependency id, data size
+	hasAttrs := tok&tokMoreData != 0
+
+	nameOff, err := p.u32()
+	if err != nil {
+		return nil, err
+	}
+	name, size, err := readName(p.chunk, int(nameOff))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasAttrs && int(nameOff) == p.off+4:
+		// Attribute list size precedes the inline name.
+		p.off = int(nameOff) + size
+	default:
+		if int(nameOff) == p.off {
+			p.off += size
+		}
+		if hasAttrs {
+			if err := p.need(4); err != nil { // attribute list size
+				return nil, err
+			}
+			p.off += 4
+		}
+	}
+
+	el := &Element{Name: name}
+	if hasAttrs {
+		for {
+			t, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if t&^tokMoreData != tokAttribute {
+				break
+			}
+			if err := p.parseAttribute(el); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	t, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case tokCloseEmptyElement:
+		return el, nil
+	case tokCloseStartElement:
+	default:
+		return nil, fmt.Errorf("unexpected token 0x%02x after element %q", t, name)
+	}
+
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch t &^ tokMoreData {
+		case tokCloseElement:
+			p.off++
+			return el, nil
+		case tokOpenStartElement:
+			child, err := p.parseElement()
+			if err != nil {
+				return nil, err
+			}
+			if child != nil {
+				el.Children = append(el.Children, child)
+			}
+		default:
+			if err := p.parseContent(el, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (p *parser) parseAttribute(el *Element) error {
+	p.off++
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	holder := &Element{}
+	present := false
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return err
+		}
+		switch t &^ tokMoreData {
+		case tokValue, tokNormalSubstitution, tokOptionalSubst, tokCharRef, tokEntityRef, tokCDataSection:
+		default:
+			if present {
+				el.Attrs = append(el.Attrs, Attr{Name: name, Value: holder.Text})
+			}
+			return nil
+		}
+		ok, err := p.parseValueToken(holder, t)
+		if err != nil {
+			return err
+		}
+		present = present || ok
+	}
+}
+
+// parseContent handles text-like tokens inside an element or fragment.
+func (p *parser) parseContent(el *Element, tok byte) error {
+	_, err := p.parseValueToken(el, tok)
+	return err
+}
+
+// parseValueToken appends text (or embedded elements) for a single value
+// token. It reports false when an optional substitution had no value.
+func (p *parser) parseValueToken(el *Element, tok byte) (bool, error) {
+	switch tok &^ tokMoreData {
+	case tokValue:
+		p.off++
+		typ, err := p.u8()
+		if err != nil {
+			return false, err
+		}
+		var s string
+		switch typ {
+		case typeWString:
+			s, err = p.utf16()
+		case typeString:
+			var n uint16
+			n, err = p.u16()
+			if err == nil {
+				if err = p.need(int(n)); err == nil {
+					s = strings.TrimRight(string(p.chunk[p.off:p.off+int(n)]), "\x00")
+					p.off += int(n)
+				}
+			}
+		default:
+			err = fmt.Errorf("unsupported inline value type 0x%02x", typ)
+		}
+		if err != nil {
+			return false, err
+		}
+		el.Text += s
+		return true, nil
+	case tokNormalSubstitution, tokOptionalSubst:
+		p.off++
+		id, err := p.u16()
+		if err != nil {
+			return false, err
+		}
+		if _, err := p.u8(); err != nil { // declared type; the instance's type wins
+			return false, err
+		}
+		if int(id) >= len(p.subs) {
+			return false, fmt.Errorf("substitution %d out of range", id)
+		}
+		sub := p.subs[id]
+		if tok&^tokMoreData == tokOptionalSubst && (sub.typ == typeNull || sub.size == 0) {
+			return false, nil
+		}
+		if sub.typ == typeBinXML {
+			inner := &parser{chunk: p.chunk, off: sub.off, end: sub.off + sub.size, depth: p.depth}
+			return true, inner.parseFragment(el)
+		}
+		el.Text += formatValue(sub.typ, p.chunk[sub.off:sub.off+sub.size])
+		return true, nil
+	case tokCDataSection:
+		p.off++
+		s, err := p.utf16()
+		if err != nil {
+			return false, err
+		}
+		el.Text += s
+		return true, nil
+	case tokCharRef:
+		p.off++
+		r, err := p.u16()
+		if err != nil {
+			return false, err
+		}
+		el.Text += string(rune(r))
+		return true, nil
+	case tokEntityRef:
+		p.off++
+		name, err := p.name()
+		if err != nil {
+			return false, err
+		}
+		el.Text += entityText(name)
+		return true, nil
+	case tokPITarget:
+		p.off++
+		_, err := p.name()
+		return false, err
+	case tokPIData:
+		p.off++
+		_, err := p.utf16()
+		return false, err
+	default:
+		return false, fmt.Errorf("unexpected token 0x%02x at %d", tok, p.off)
+	}
+}
+
+func entityText(name string) string {
+	switch name {
+	case "amp":
+		return "&"
+	case "lt":
+		return "<"
+	case "gt":
+		return ">"
+	case "quot":
+		return `"`
+	case "apos":
+		return "'"
+	default:
+		return "&" + name + ";"
+	}
+}
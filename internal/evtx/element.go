@@ -0,0 +1,77 @@
+package evtx
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Element is a node of a rendered event. Mixed content is flattened into Text.
+type Element struct {
+	Name     string
+	Attrs    []Attr
+	Children []*Element
+	Text     string
+}
+
+// Attr is an element attribute.
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// Child returns the first direct child with the given name.
+func (e *Element) Child(name string) *Element {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Attr returns the value of the named attribute.
+func (e *Element) Attr(name string) string {
+	if e == nil {
+		return ""
+	}
+	for _, a := range e.Attrs {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// XML serializes the element tree.
+func (e *Element) XML() string {
+	var sb strings.Builder
+	e.writeXML(&sb)
+	return sb.String()
+}
+
+func (e *Element) writeXML(sb *strings.Builder) {
+	sb.WriteByte('<')
+	sb.WriteString(e.Name)
+	for _, a := range e.Attrs {
+		sb.WriteByte(' ')
+		sb.WriteString(a.Name)
+		sb.WriteString(`="`)
+		xml.EscapeText(sb, []byte(a.Value))
+		sb.WriteByte('"')
+	}
+	if e.Text == "" && len(e.Children) == 0 {
+		sb.WriteString("/>")
+		return
+	}
+	sb.WriteByte('>')
+	xml.EscapeText(sb, []byte(e.Text))
+	for _, c := range e.Children {
+		c.writeXML(sb)
+	}
+	sb.WriteString("</")
+	sb.WriteString(e.Name)
+	sb.WriteByte('>')
+}
@@ -0,0 +1,196 @@
+// Package evtx reads exported Windows event log (.evtx) files without wevtapi.
+package evtx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	fileHeaderSize  = 4096
+	chunkSize       = 65536
+	chunkHeaderSize = 512
+	recordHeaderLen = 24
+)
+
+var (
+	fileSignature   = []byte("ElfFile\x00")
+	chunkSignature  = []byte("ElfChnk\x00")
+	recordSignature = []byte{0x2a, 0x2a, 0x00, 0x00}
+)
+
+// File is an opened EVTX file.
+type File struct {
+	r      io.ReaderAt
+	size   int64
+	closer io.Closer
+
+	MajorVersion uint16
+	MinorVersion uint16
+	NextRecordID uint64
+
+	// Skipped lists the damaged chunks and records the last Records call
+	// passed over.
+	Skipped []error
+}
+
+// Record is a single event record with its rendered BinXML tree.
+type Record struct {
+	ID      uint64
+	Written time.Time
+	Root    *Element
+}
+
+// XML renders the record the same way EvtRender(EvtRenderEventXml) does.
+func (r *Record) XML() string {
+	if r.Root == nil {
+		return ""
+	}
+	return r.Root.XML()
+}
+
+// Open opens an EVTX file on disk.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ef, err := NewReader(f, st.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ef.closer = f
+	return ef, nil
+}
+
+// NewReader validates the file header of an EVTX image held in r.
+func NewReader(r io.ReaderAt, size int64) (*File, error) {
+	if size < fileHeaderSize {
+		return nil, errors.New("evtx: file too small")
+	}
+	hdr := make([]byte, 128)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("evtx: read file header: %w", err)
+	}
+	if !bytes.Equal(hdr[:8], fileSignature) {
+		return nil, errors.New("evtx: bad file signature")
+	}
+	return &File{
+		r:            r,
+		size:         size,
+		NextRecordID: binary.LittleEndian.Uint64(hdr[24:32]),
+		MinorVersion: binary.LittleEndian.Uint16(hdr[36:38]),
+		MajorVersion: binary.LittleEndian.Uint16(hdr[38:40]),
+	}, nil
+}
+
+// Close releases the underlying file when opened via Open.
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// Records calls fn for every record in file order. Unused chunks are
+// skipped. Chunks failing their header or record checksum are skipped too,
+// and a malformed record ends its chunk; both are listed in Skipped and
+// reading continues with the next chunk. Only errors reading the file or
+// returned by fn stop it.
+func (f *File) Records(fn func(*Record) error) error {
+	f.Skipped = nil
+	buf := make([]byte, chunkSize)
+	for off := int64(fileHeaderSize); off+chunkSize <= f.size; off += chunkSize {
+		if _, err := f.r.ReadAt(buf, off); err != nil {
+			return fmt.Errorf("evtx: read chunk at %d: %w", off, err)
+		}
+		if !bytes.Equal(buf[:8], chunkSignature) {
+			continue
+		}
+		if err := verifyChunk(buf); err != nil {
+			f.Skipped = append(f.Skipped, fmt.Errorf("evtx: chunk at %d: %w", off, err))
+			continue
+		}
+		damaged, err := readChunk(buf, fn)
+		if err != nil {
+			return err
+		}
+		if damaged != nil {
+			f.Skipped = append(f.Skipped, fmt.Errorf("evtx: chunk at %d: %w", off, damaged))
+		}
+	}
+	return nil
+}
+
+// verifyChunk checks the CRC32 of the chunk header (bytes 0-120 and
+// 128-512) and of the record data up to the free space offset.
+func verifyChunk(data []byte) error {
+	h := crc32.NewIEEE()
+	h.Write(data[:120])
+	h.Write(data[128:chunkHeaderSize])
+	if h.Sum32() != binary.LittleEndian.Uint32(data[124:128]) {
+		return errors.New("header checksum mismatch")
+	}
+	free := int(binary.LittleEndian.Uint32(data[48:52]))
+	if free < chunkHeaderSize || free > len(data) {
+		return fmt.Errorf("free space offset %d out of range", free)
+	}
+	if crc32.ChecksumIEEE(data[chunkHeaderSize:free]) != binary.LittleEndian.Uint32(data[52:56]) {
+		return errors.New("record checksum mismatch")
+	}
+	return nil
+}
+
+// readChunk passes the records of a verified chunk to fn. It stops at the
+// first malformed record and reports it as damaged; err is fn's error.
+func readChunk(data []byte, fn func(*Record) error) (damaged, err error) {
+	free := int(binary.LittleEndian.Uint32(data[48:52]))
+	for off := chunkHeaderSize; off+recordHeaderLen+4 <= free; {
+		if !bytes.Equal(data[off:off+4], recordSignature) {
+			return fmt.Errorf("bad record signature at %d", off), nil
+		}
+		size := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		if size < recordHeaderLen+4 || off+size > free {
+			return fmt.Errorf("record at %d has bad size %d", off, size), nil
+		}
+		rec := &Record{
+			ID:      binary.LittleEndian.Uint64(data[off+8 : off+16]),
+			Written: FiletimeToTime(binary.LittleEndian.Uint64(data[off+16 : off+24])),
+		}
+		p := &parser{chunk: data, off: off + recordHeaderLen, end: off + size - 4}
+		root := &Element{}
+		if err := p.parseFragment(root); err != nil {
+			return fmt.Errorf("record %d: %w", rec.ID, err), nil
+		}
+		if len(root.Children) > 0 {
+			rec.Root = root.Children[0]
+		}
+		if err := fn(rec); err != nil {
+			return nil, err
+		}
+		off += size
+	}
+	return nil, nil
+}
+
+// FiletimeToTime converts a Windows FILETIME to UTC; 0 gives the zero Time.
+func FiletimeToTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	// FILETIME counts 100ns intervals since 1601-01-01.
+	const epochDiff = 116444736000000000
+	return time.Unix(0, int64(ft-epochDiff)*100).UTC()
+}
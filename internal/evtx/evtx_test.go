@@ -0,0 +1,678 @@
+package evtx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+var update = flag.Bool("update", false, "rewrite the .evtx fixtures in testdata")
+
+// The fixtures are built by the writer below; run
+// go test ./internal/evtx -run TestFixtures -update after changing it.
+var fixtures = map[string]func() []byte{
+	// One chunk of three records sharing one template.
+	"normal.evtx": func() []byte {
+		c := newChunk()
+		def := c.record(wuEvent(1))
+		c.recordWith(def, wuEvent(2))
+		c.recordWith(def, wuEvent(3))
+		return evtxFile(c.finish())
+	},
+	// The first chunk's record checksum is wrong; the second is intact.
+	"bad_checksum.evtx": func() []byte {
+		bad := newChunk()
+		def := bad.record(wuEvent(1))
+		bad.recordWith(def, wuEvent(2))
+		damaged := bad.finish()
+		damaged[chunkHeaderSize+100] ^= 0xff
+		good := newChunk()
+		def = good.record(wuEvent(3))
+		good.recordWith(def, wuEvent(4))
+		return evtxFile(damaged, good.finish())
+	},
+	// Record 2 is cut off in its substitution values, so record 3 in the
+	// same chunk is lost; the second chunk is intact.
+	"truncated_record.evtx": func() []byte {
+		c := newChunk()
+		def := c.record(wuEvent(1))
+		c.truncated(def, wuEvent(2))
+		c.recordWith(def, wuEvent(3))
+		good := newChunk()
+		good.record(wuEvent(4))
+		return evtxFile(c.finish(), good.finish())
+	},
+}
+
+func TestFixtures(t *testing.T) {
+	for name, build := range fixtures {
+		path := filepath.Join("testdata", name)
+		want := build()
+		if *update {
+			if err := os.WriteFile(path, want, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale; rerun with -update", path)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		file        string
+		wantIDs     []uint64
+		wantSkipped []string
+	}{
+		{"normal.evtx", []uint64{1, 2, 3}, nil},
+		{"bad_checksum.evtx", []uint64{3, 4}, []string{"chunk at 4096: record checksum mismatch"}},
+		{"truncated_record.evtx", []uint64{1, 4}, []string{"chunk at 4096: record 2: binxml truncated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var ids []uint64
+			err = f.Records(func(r *Record) error {
+				ids = append(ids, r.ID)
+				if r.Root == nil || r.Root.Name != "Event" {
+					t.Errorf("record %d: root %+v, want Event", r.ID, r.Root)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			var skipped []string
+			for _, e := range f.Skipped {
+				skipped = append(skipped, strings.TrimPrefix(e.Error(), "evtx: "))
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %q, want %q", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestRecordXML(t *testing.T) {
+	f, err := Open(filepath.Join("testdata", "normal.evtx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got := map[uint64]string{}
+	if err := f.Records(func(r *Record) error {
+		got[r.ID] = r.XML()
+		if want := time.Date(2026, 10, 15, 9, 30, int(r.ID), 0, time.UTC); !r.Written.Equal(want) {
+			t.Errorf("record %d written %v, want %v", r.ID, r.Written, want)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id   uint64
+		want string
+	}{
+		// Inline template definition, every substitution present.
+		{1, `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event"><System>` +
+			`<Provider Name="Microsoft-Windows-WindowsUpdateClient"/><EventID>20</EventID><Level>2</Level>` +
+			`<Keywords>0x8000000000000000</Keywords><TimeCreated SystemTime="2026-10-15T09:30:01.0000000Z"/>` +
+			`<EventRecordID>1</EventRecordID><Correlation ActivityID="{01234567-89AB-CDEF-0123-456789ABCDEF}"/>` +
+			`<Execution ProcessID="1234"/><Channel>System</Channel><Computer>web01.contoso.local</Computer>` +
+			`<Security UserID="S-1-5-18"/></System><EventData><Data Name="updateTitle">2026-10 Cumulative Update &amp; SSU</Data>` +
+			`<Data Name="errorCode">0x800f0922</Data></EventData></Event>`},
+		// Template referenced by offset; the optional substitutions are
+		// null, so their attributes are left out.
+		{2, `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event"><System>` +
+			`<Provider Name="Microsoft-Windows-WindowsUpdateClient"/><EventID>19</EventID><Level>4</Level>` +
+			`<Keywords>0x8000000000000000</Keywords><TimeCreated SystemTime="2026-10-15T09:30:02.0000000Z"/>` +
+			`<EventRecordID>2</EventRecordID><Correlation/>` +
+			`<Execution ProcessID="1234"/><Channel>System</Channel><Computer>web01.contoso.local</Computer>` +
+			`<Security/></System><EventData><Data Name="updateTitle">2026-10 Cumulative Update &amp; SSU</Data>` +
+			`<Data Name="errorCode">0x0</Data></EventData></Event>`},
+	}
+	for _, tt := range tests {
+		if got[tt.id] != tt.want {
+			t.Errorf("record %d:\n got %s\nwant %s", tt.id, got[tt.id], tt.want)
+		}
+	}
+}
+
+// TestWindowsExports checks logs exported on a real machine against the XML
+// wevtutil rendered from the same file; see testdata/windows/README.md.
+func TestWindowsExports(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "windows", "*.evtx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skip("no Windows-exported logs in testdata/windows")
+	}
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			golden, err := os.ReadFile(strings.TrimSuffix(path, ".evtx") + ".xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, ev := range strings.SplitAfter(string(golden), "</Event>") {
+				if ev = strings.TrimSpace(strings.TrimPrefix(ev, "\ufeff")); ev != "" {
+					want = append(want, ev)
+				}
+			}
+			f, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var got []string
+			if err := f.Records(func(r *Record) error {
+				got = append(got, r.XML())
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Skipped) > 0 {
+				t.Errorf("skipped %v", f.Skipped)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%d records, wevtutil rendered %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("record %d:\n got %s\nwant %s", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	valid := evtxFile(newChunk().finish())
+	badSig := append([]byte(nil), valid...)
+	copy(badSig, "NotEvtx\x00")
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"too small", valid[:100], "file too small"},
+		{"bad signature", badSig, "bad file signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewReader(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if f.MajorVersion != 3 || f.MinorVersion != 1 {
+					t.Errorf("version %d.%d, want 3.1", f.MajorVersion, f.MinorVersion)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSubstitutions renders a one-element template with a single value.
+func TestSubstitutions(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    func(c *chunk)
+		value   subValue
+		want    string
+		wantErr string
+	}{
+		{"normal text", func(c *chunk) { c.textElement("Data", func() { c.subst(0, false) }) },
+			wstr("hello"), "<Data>hello</Data>", ""},
+		{"optional null element", func(c *chunk) { c.textElement("Data", func() { c.subst(0, true) }) },
+			subValue{typ: typeNull}, "<Data/>", ""},
+		{"normal null element", func(c *chunk) { c.textElement("Data", func() { c.subst(0, false) }) },
+			subValue{typ: typeNull}, "<Data/>", ""},
+		{"attribute", func(c *chunk) {
+			c.attrElement("Data", []string{"Name"}, []func(){func() { c.subst(0, false) }})
+		}, u32(7), `<Data Name="7"/>`, ""},
+		{"optional attribute absent", func(c *chunk) {
+			c.attrElement("Data", []string{"Name"}, []func(){func() { c.subst(0, true) }})
+		}, subValue{typ: typeNull}, `<Data/>`, ""},
+		{"mixed text and value", func(c *chunk) {
+			c.textElement("Data", func() { c.text("code="); c.subst(0, false) })
+		}, subValue{typ: typeHexInt32, data: le32(0x800f0922)}, "<Data>code=0x800f0922</Data>", ""},
+		{"embedded binxml", func(c *chunk) { c.textElement("UserData", func() { c.subst(0, false) }) },
+			subValue{typ: typeBinXML, binxml: func(c *chunk) {
+				c.textElement("EventXML", func() { c.textElement("Param", func() { c.text("x") }) })
+			}}, "<UserData><EventXML><Param>x</Param></EventXML></UserData>", ""},
+		{"string array", func(c *chunk) { c.textElement("Data", func() { c.subst(0, false) }) },
+			subValue{typ: typeWString | typeArray, data: utf16le("a\x00b\x00")}, "<Data>a, b</Data>", ""},
+		{"substitution out of range", func(c *chunk) { c.textElement("Data", func() { c.subst(3, false) }) },
+			wstr("x"), "", "substitution 3 out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newChunk()
+			c.recordFn(1, func() {
+				c.instance(-1, tt.body, []subValue{tt.value})
+			})
+			f, err := NewReader(bytes.NewReader(evtxFile(c.finish())), fileHeaderSize+chunkSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if err := f.Records(func(r *Record) error { got = r.XML(); return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if len(f.Skipped) != 1 || !strings.Contains(f.Skipped[0].Error(), tt.wantErr) {
+					t.Fatalf("skipped = %v, want %q", f.Skipped, tt.wantErr)
+				}
+				return
+			}
+			if len(f.Skipped) > 0 {
+				t.Fatalf("skipped = %v", f.Skipped)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		typ  byte
+		data []byte
+		want string
+	}{
+		{typeWString, utf16le("text\x00"), "text"},
+		{typeString, []byte("ansi\x00"), "ansi"},
+		{typeInt8, []byte{0xff}, "-1"},
+		{typeUInt16, []byte{0x14, 0x00}, "20"},
+		{typeInt32, le32(0xfffffffe), "-2"},
+		{typeUInt64, le64(1 << 40), "1099511627776"},
+		{typeBool, le32(1), "true"},
+		{typeBinary, []byte{0xde, 0xad}, "DEAD"},
+		{typeHexInt32, le32(0x80070005), "0x80070005"},
+		{typeHexInt64, le64(0x8000000000000000), "0x8000000000000000"},
+		{typeSizeT, le64(0x10), "0x10"},
+		{typeGUID, guid, "{01234567-89AB-CDEF-0123-456789ABCDEF}"},
+		{typeFileTime, le64(filetime(time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC))), "2026-10-15T09:30:00.0000000Z"},
+		{typeSystemTime, systemTime(2026, 10, 15, 9, 30, 5, 250), "2026-10-15T09:30:05.2500000Z"},
+		{typeSID, localSystem, "S-1-5-18"},
+		{typeUInt16 | typeArray, []byte{1, 0, 2, 0}, "1, 2"},
+		{typeUInt32, []byte{1}, "01"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.typ, tt.data); got != tt.want {
+			t.Errorf("formatValue(0x%02x, % x) = %q, want %q", tt.typ, tt.data, got, tt.want)
+		}
+	}
+}
+
+// --- fixture writer ---
+
+var (
+	guid        = []byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	localSystem = []byte{1, 1, 0, 0, 0, 0, 0, 5, 18, 0, 0, 0}
+)
+
+// subValue is one substitution of a template instance. binxml, when set,
+// writes an embedded fragment in place of data.
+type subValue struct {
+	typ    byte
+	data   []byte
+	binxml func(c *chunk)
+}
+
+type event struct {
+	id   uint64
+	subs []subValue
+}
+
+// wuEvent is a WindowsUpdateClient record; even ids have the optional
+// correlation and user substitutions null.
+func wuEvent(id uint64) event {
+	eventID, level, code := uint16(20), byte(2), uint32(0x800f0922)
+	activity, user := subValue{typ: typeGUID, data: guid}, subValue{typ: typeSID, data: localSystem}
+	if id%2 == 0 {
+		eventID, level, code = 19, 4, 0
+		activity, user = subValue{typ: typeNull}, subValue{typ: typeNull}
+	}
+	return event{id: id, subs: []subValue{
+		wstr("Microsoft-Windows-WindowsUpdateClient"),
+		{typ: typeUInt16, data: le16(eventID)},
+		{typ: typeUInt8, data: []byte{level}},
+		{typ: typeHexInt64, data: le64(0x8000000000000000)},
+		{typ: typeFileTime, data: le64(filetime(written(id)))},
+		{typ: typeUInt64, data: le64(id)},
+		wstr("System"),
+		wstr("web01.contoso.local"),
+		user,
+		{typ: typeBinXML, binxml: func(c *chunk) {
+			c.textElement("EventData", func() {
+				c.attrElementText("Data", "Name", "updateTitle", func() {
+					c.text("2026-10 Cumulative Update ")
+					c.entity("amp")
+					c.text(" SSU")
+				})
+				c.attrElementText("Data", "Name", "errorCode", func() {
+					c.subValueInline(subValue{typ: typeHexInt32, data: le32(code)})
+				})
+			})
+		}},
+		{typ: typeUInt32, data: le32(1234)},
+		activity,
+	}}
+}
+
+func written(id uint64) time.Time {
+	return time.Date(2026, 10, 15, 9, 30, int(id), 0, time.UTC)
+}
+
+// systemTemplate lays out the System element the way providers' manifests
+// do, with values taken from the substitutions of wuEvent.
+func systemTemplate(c *chunk) {
+	c.attrElement("Event", []string{"xmlns"}, []func(){func() { c.text("http://schemas.microsoft.com/win/2004/08/events/event") }}, func() {
+		c.textElement("System", func() {
+			c.attrElement("Provider", []string{"Name"}, []func(){func() { c.subst(0, false) }})
+			c.textElement("EventID", func() { c.subst(1, false) })
+			c.textElement("Level", func() { c.subst(2, false) })
+			c.textElement("Keywords", func() { c.subst(3, false) })
+			c.attrElement("TimeCreated", []string{"SystemTime"}, []func(){func() { c.subst(4, false) }})
+			c.textElement("EventRecordID", func() { c.subst(5, false) })
+			c.attrElement("Correlation", []string{"ActivityID"}, []func(){func() { c.subst(11, true) }})
+			c.attrElement("Execution", []string{"ProcessID"}, []func(){func() { c.subst(10, false) }})
+			c.textElement("Channel", func() { c.subst(6, false) })
+			c.textElement("Computer", func() { c.subst(7, false) })
+			c.attrElement("Security", []string{"UserID"}, []func(){func() { c.subst(8, true) }})
+		})
+		c.subst(9, true)
+	})
+}
+
+// chunk writes one 64 KiB chunk. Offsets in BinXML are chunk-relative, so
+// everything is written in place.
+type chunk struct {
+	buf                []byte
+	firstID, lastID    uint64
+	lastRecordOffset   int
+	pendingDataSizeOff []int
+}
+
+func newChunk() *chunk {
+	return &chunk{buf: make([]byte, chunkHeaderSize)}
+}
+
+func (c *chunk) u8(v byte)    { c.buf = append(c.buf, v) }
+func (c *chunk) u16(v uint16) { c.buf = binary.LittleEndian.AppendUint16(c.buf, v) }
+func (c *chunk) u32(v uint32) { c.buf = binary.LittleEndian.AppendUint32(c.buf, v) }
+func (c *chunk) u64(v uint64) { c.buf = binary.LittleEndian.AppendUint64(c.buf, v) }
+func (c *chunk) patch32(off int, v uint32) {
+	binary.LittleEndian.PutUint32(c.buf[off:], v)
+}
+
+// name writes a name reference followed by the name itself.
+func (c *chunk) name(s string) {
+	c.u32(uint32(len(c.buf) + 4))
+	c.u32(0) // next name in the hash bucket
+	c.u16(0) // hash, not checked by the reader
+	c.u16(uint16(len(utf16.Encode([]rune(s)))))
+	c.buf = append(c.buf, utf16le(s)...)
+	c.u16(0)
+}
+
+func (c *chunk) fragmentHeader() { c.buf = append(c.buf, tokFragmentHeader, 1, 1, 0) }
+
+func (c *chunk) openElement(name string, attrs bool) {
+	tok := byte(tokOpenStartElement)
+	if attrs {
+		tok |= tokMoreData
+	}
+	c.u8(tok)
+	c.u16(0xffff) // dependency id
+	c.u32(0)      // data size, not checked by the reader
+	c.name(name)
+	if attrs {
+		c.u32(0) // attribute list size
+	}
+}
+
+// textElement writes <name>content</name>.
+func (c *chunk) textElement(name string, content func()) {
+	c.openElement(name, false)
+	c.u8(tokCloseStartElement)
+	content()
+	c.u8(tokCloseElement)
+}
+
+// attrElement writes an element with attributes and optional content.
+func (c *chunk) attrElement(name string, attrs []string, values []func(), content ...func()) {
+	c.openElement(name, true)
+	for i, a := range attrs {
+		tok := byte(tokAttribute)
+		if i < len(attrs)-1 {
+			tok |= tokMoreData
+		}
+		c.u8(tok)
+		c.name(a)
+		values[i]()
+	}
+	if len(content) == 0 {
+		c.u8(tokCloseEmptyElement)
+		return
+	}
+	c.u8(tokCloseStartElement)
+	for _, fn := range content {
+		fn()
+	}
+	c.u8(tokCloseElement)
+}
+
+func (c *chunk) attrElementText(name, attr, value string, content func()) {
+	c.attrElement(name, []string{attr}, []func(){func() { c.text(value) }}, content)
+}
+
+func (c *chunk) text(s string) {
+	c.u8(tokValue)
+	c.u8(typeWString)
+	c.u16(uint16(len(utf16.Encode([]rune(s)))))
+	c.buf = append(c.buf, utf16le(s)...)
+}
+
+func (c *chunk) entity(name string) {
+	c.u8(tokEntityRef)
+	c.name(name)
+}
+
+func (c *chunk) subst(id uint16, optional bool) {
+	tok := byte(tokNormalSubstitution)
+	if optional {
+		tok = tokOptionalSubst
+	}
+	c.u8(tok)
+	c.u16(id)
+	c.u8(typeNull) // declared type; the instance's wins
+}
+
+// subValueInline writes a value inside an embedded fragment as literal text.
+func (c *chunk) subValueInline(v subValue) {
+	c.text(formatValue(v.typ, v.data))
+}
+
+// templateDef writes a template definition and returns its offset.
+func (c *chunk) templateDef(body func(c *chunk)) int {
+	def := len(c.buf)
+	c.u32(0) // next template
+	c.buf = append(c.buf, guid...)
+	c.u32(0) // data size, patched below
+	start := len(c.buf)
+	c.fragmentHeader()
+	body(c)
+	c.u8(tokEOF)
+	c.patch32(def+20, uint32(len(c.buf)-start))
+	return def
+}
+
+// instance writes a template instance. def is the offset of a template
+// written earlier, or -1 to define body inline.
+func (c *chunk) instance(def int, body func(c *chunk), subs []subValue) int {
+	c.u8(tokTemplateInstance)
+	c.u8(1)
+	c.u32(1) // template id
+	if def < 0 {
+		c.u32(uint32(len(c.buf) + 4))
+		def = c.templateDef(body)
+	} else {
+		c.u32(uint32(def))
+	}
+	c.u32(uint32(len(subs)))
+	descs := len(c.buf)
+	for _, s := range subs {
+		c.u16(0) // size, patched below
+		c.u8(s.typ)
+		c.u8(0)
+	}
+	for i, s := range subs {
+		start := len(c.buf)
+		if s.binxml != nil {
+			c.fragmentHeader()
+			s.binxml(c)
+			c.u8(tokEOF)
+		} else {
+			c.buf = append(c.buf, s.data...)
+		}
+		binary.LittleEndian.PutUint16(c.buf[descs+i*4:], uint16(len(c.buf)-start))
+	}
+	return def
+}
+
+// recordFn writes a record whose BinXML body is produced by body.
+func (c *chunk) recordFn(id uint64, body func()) {
+	start := len(c.buf)
+	c.buf = append(c.buf, recordSignature...)
+	c.u32(0) // size, patched below
+	c.u64(id)
+	c.u64(filetime(written(id)))
+	c.fragmentHeader()
+	body()
+	c.u8(tokEOF)
+	size := len(c.buf) - start + 4
+	c.u32(uint32(size))
+	c.patch32(start+4, uint32(size))
+	if c.firstID == 0 {
+		c.firstID = id
+	}
+	c.lastID = id
+	c.lastRecordOffset = start
+}
+
+// record writes ev with the template defined inline and returns the
+// template's offset for later records.
+func (c *chunk) record(ev event) int {
+	var def int
+	c.recordFn(ev.id, func() { def = c.instance(-1, systemTemplate, ev.subs) })
+	return def
+}
+
+func (c *chunk) recordWith(def int, ev event) {
+	c.recordFn(ev.id, func() { c.instance(def, nil, ev.subs) })
+}
+
+// truncated writes ev and then cuts its BinXML short, keeping the record
+// framing intact.
+func (c *chunk) truncated(def int, ev event) {
+	start := len(c.buf)
+	c.recordWith(def, ev)
+	keep := recordHeaderLen + 4 + 10 + 4 + 8 // header, fragment, instance, count, two descriptors
+	c.buf = c.buf[:start+keep]
+	size := keep + 4
+	c.u32(uint32(size))
+	c.patch32(start+4, uint32(size))
+}
+
+// finish fills in the chunk header and checksums.
+func (c *chunk) finish() []byte {
+	out := make([]byte, chunkSize)
+	copy(out, c.buf)
+	copy(out, chunkSignature)
+	binary.LittleEndian.PutUint64(out[8:], c.firstID)
+	binary.LittleEndian.PutUint64(out[16:], c.lastID)
+	binary.LittleEndian.PutUint64(out[24:], c.firstID)
+	binary.LittleEndian.PutUint64(out[32:], c.lastID)
+	binary.LittleEndian.PutUint32(out[40:], 128)
+	binary.LittleEndian.PutUint32(out[44:], uint32(c.lastRecordOffset))
+	binary.LittleEndian.PutUint32(out[48:], uint32(len(c.buf)))
+	binary.LittleEndian.PutUint32(out[52:], crc32.ChecksumIEEE(out[chunkHeaderSize:len(c.buf)]))
+	h := crc32.NewIEEE()
+	h.Write(out[:120])
+	h.Write(out[128:chunkHeaderSize])
+	binary.LittleEndian.PutUint32(out[124:], h.Sum32())
+	return out
+}
+
+// evtxFile prepends a version 3.1 file header to the chunks.
+func evtxFile(chunks ...[]byte) []byte {
+	hdr := make([]byte, fileHeaderSize)
+	copy(hdr, fileSignature)
+	binary.LittleEndian.PutUint64(hdr[16:], uint64(len(chunks)-1))
+	binary.LittleEndian.PutUint64(hdr[24:], 5)
+	binary.LittleEndian.PutUint32(hdr[32:], 128)
+	binary.LittleEndian.PutUint16(hdr[36:], 1)
+	binary.LittleEndian.PutUint16(hdr[38:], 3)
+	binary.LittleEndian.PutUint16(hdr[40:], fileHeaderSize)
+	binary.LittleEndian.PutUint16(hdr[42:], uint16(len(chunks)))
+	binary.LittleEndian.PutUint32(hdr[124:], crc32.ChecksumIEEE(hdr[:120]))
+	return append(hdr, bytes.Join(chunks, nil)...)
+}
+
+func wstr(s string) subValue { return subValue{typ: typeWString, data: utf16le(s)} }
+func u32(v uint32) subValue  { return subValue{typ: typeUInt32, data: le32(v)} }
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+func le64(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+func filetime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+func systemTime(y, mo, d, h, mi, s, ms int) []byte {
+	var b []byte
+	for _, v := range []int{y, mo, 0, d, h, mi, s, ms} {
+		b = binary.LittleEndian.AppendUint16(b, uint16(v))
+	}
+	return b
+}
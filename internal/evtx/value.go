@@ -0,0 +1,211 @@
+package evtx
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// BinXML value types (EVT_VARIANT_TYPE). Array variants set 0x80.
+const (
+	typeNull       = 0x00
+	typeWString    = 0x01
+	typeString     = 0x02
+	typeInt8       = 0x03
+	typeUInt8      = 0x04
+	typeInt16      = 0x05
+	typeUInt16     = 0x06
+	typeInt32      = 0x07
+	typeUInt32     = 0x08
+	typeInt64      = 0x09
+	typeUInt64     = 0x0a
+	typeReal32     = 0x0b
+	typeReal64     = 0x0c
+	typeBool       = 0x0d
+	typeBinary     = 0x0e
+	typeGUID       = 0x0f
+	typeSizeT      = 0x10
+	typeFileTime   = 0x11
+	typeSystemTime = 0x12
+	typeSID        = 0x13
+	typeHexInt32   = 0x14
+	typeHexInt64   = 0x15
+	typeBinXML     = 0x21
+
+	typeArray = 0x80
+)
+
+// formatValue renders a substitution value the way the event log service
+// does when producing event XML.
+func formatValue(typ byte, b []byte) string {
+	if typ&typeArray != 0 {
+		return formatArray(typ&^typeArray, b)
+	}
+	switch typ {
+	case typeNull:
+		return ""
+	case typeWString:
+		return decodeUTF16(b)
+	case typeString:
+		return strings.TrimRight(string(b), "\x00")
+	case typeInt8:
+		if len(b) >= 1 {
+			return strconv.FormatInt(int64(int8(b[0])), 10)
+		}
+	case typeUInt8:
+		if len(b) >= 1 {
+			return strconv.FormatUint(uint64(b[0]), 10)
+		}
+	case typeInt16:
+		if len(b) >= 2 {
+			return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(b))), 10)
+		}
+	case typeUInt16:
+		if len(b) >= 2 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(b)), 10)
+		}
+	case typeInt32:
+		if len(b) >= 4 {
+			return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(b))), 10)
+		}
+	case typeUInt32:
+		if len(b) >= 4 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b)), 10)
+		}
+	case typeInt64:
+		if len(b) >= 8 {
+			return strconv.FormatInt(int64(binary.LittleEndian.Uint64(b)), 10)
+		}
+	case typeUInt64:
+		if len(b) >= 8 {
+			return strconv.FormatUint(binary.LittleEndian.Uint64(b), 10)
+		}
+	case typeReal32:
+		if len(b) >= 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32)
+		}
+	case typeReal64:
+		if len(b) >= 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64)
+		}
+	case typeBool:
+		if len(b) >= 4 {
+			return strconv.FormatBool(binary.LittleEndian.Uint32(b) != 0)
+		}
+	case typeBinary:
+		return strings.ToUpper(hex.EncodeToString(b))
+	case typeGUID:
+		if len(b) >= 16 {
+			return formatGUID(b)
+		}
+	case typeSizeT, typeHexInt32, typeHexInt64:
+		switch len(b) {
+		case 4:
+			return fmt.Sprintf("0x%x", binary.LittleEndian.Uint32(b))
+		case 8:
+			return fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(b))
+		}
+	case typeFileTime:
+		if len(b) >= 8 {
+			return formatTime(FiletimeToTime(binary.LittleEndian.Uint64(b)))
+		}
+	case typeSystemTime:
+		if len(b) >= 16 {
+			u := func(i int) int { return int(binary.LittleEndian.Uint16(b[i*2:])) }
+			t := time.Date(u(0), time.Month(u(1)), u(3), u(4), u(5), u(6), u(7)*int(time.Millisecond), time.UTC)
+			return formatTime(t)
+		}
+	case typeSID:
+		return formatSID(b)
+	}
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+func formatArray(elem byte, b []byte) string {
+	var parts []string
+	switch elem {
+	case typeWString:
+		for _, s := range strings.Split(decodeUTF16(b), "\x00") {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+	case typeString:
+		for _, s := range strings.Split(string(b), "\x00") {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+	default:
+		size := fixedSize(elem)
+		if size == 0 {
+			return strings.ToUpper(hex.EncodeToString(b))
+		}
+		for i := 0; i+size <= len(b); i += size {
+			parts = append(parts, formatValue(elem, b[i:i+size]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func fixedSize(typ byte) int {
+	switch typ {
+	case typeInt8, typeUInt8:
+		return 1
+	case typeInt16, typeUInt16:
+		return 2
+	case typeInt32, typeUInt32, typeReal32, typeBool, typeHexInt32:
+		return 4
+	case typeInt64, typeUInt64, typeReal64, typeFileTime, typeHexInt64:
+		return 8
+	case typeGUID, typeSystemTime:
+		return 16
+	}
+	return 0
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.0000000Z")
+}
+
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%04X-%012X}",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10],
+		b[10:16])
+}
+
+func formatSID(b []byte) string {
+	if len(b) < 8 {
+		return strings.ToUpper(hex.EncodeToString(b))
+	}
+	count := int(b[1])
+	if len(b) < 8+count*4 {
+		return strings.ToUpper(hex.EncodeToString(b))
+	}
+	var auth uint64
+	for _, c := range b[2:8] {
+		auth = auth<<8 | uint64(c)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "S-%d-%d", b[0], auth)
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&sb, "-%d", binary.LittleEndian.Uint32(b[8+i*4:]))
+	}
+	return sb.String()
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+}
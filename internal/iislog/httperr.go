@@ -0,0 +1,247 @@
+package iislog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Pool diagnoses derived from HTTPERR reasons.
+const (
+	DiagnosisDeadPool   = "dead_pool"
+	DiagnosisOverloaded = "overloaded"
+)
+
+// deadReasons mean the worker process is gone or the pool was taken offline;
+// overloadReasons mean it is alive but cannot keep up. Anything else (idle
+// timers, malformed client requests) is reported but not diagnosed.
+var (
+	deadReasons = map[string]bool{
+		"AppOffline":                       true,
+		"AppShutdown":                      true,
+		"Connection_Abandoned_By_AppPool":  true,
+		"Connection_Abandoned_By_ReqQueue": true,
+		"Disabled":                         true,
+		"DisabledByIsapi":                  true,
+	}
+	overloadReasons = map[string]bool{
+		"QueueFull":                    true,
+		"AppPoolTimer":                 true,
+		"Timer_AppPool":                true,
+		"ConnLimit":                    true,
+		"Connection_Dropped_List_Full": true,
+	}
+)
+
+// HTTPErrRecord is one HTTP.sys error log line.
+type HTTPErrRecord struct {
+	Time   time.Time
+	SiteID string
+	Status int
+	URI    string
+	Reason string
+	// Queue is the request queue, i.e. the application pool name.
+	Queue string
+}
+
+// HTTPErrStats summarizes HTTPERR logs per request queue.
+type HTTPErrStats struct {
+	Total   int         `json:"total"`
+	Reasons []Count     `json:"reasons"`
+	Pools   []PoolStats `json:"pools"`
+}
+
+// PoolStats is the HTTP.sys view of one application pool.
+type PoolStats struct {
+	Queue      string          `json:"queue"`
+	Total      int             `json:"total"`
+	Dead       int             `json:"dead"`
+	Overloaded int             `json:"overloaded"`
+	Diagnosis  string          `json:"diagnosis,omitempty"`
+	FirstSeen  string          `json:"firstSeen"`
+	LastSeen   string          `json:"lastSeen"`
+	Reasons    []Count         `json:"reasons"`
+	Buckets    []HTTPErrBucket `json:"buckets"`
+}
+
+// HTTPErrBucket is one time slice of a pool's HTTP.sys failures.
+type HTTPErrBucket struct {
+	Start      string `json:"start"`
+	Total      int    `json:"total"`
+	Dead       int    `json:"dead"`
+	Overloaded int    `json:"overloaded"`
+}
+
+// ParseHTTPErr reads an HTTPERR log and calls fn for every record.
+func ParseHTTPErr(r io.Reader, fn func(HTTPErrRecord) error) error {
+	return scanW3C(r, func(get fieldFunc) error {
+		t, ok := parseTime(get)
+		if !ok {
+			return nil
+		}
+		rec := HTTPErrRecord{
+			Time:   t,
+			SiteID: get("s-siteid"),
+			URI:    get("cs-uri"),
+			Reason: get("s-reason"),
+			Queue:  get("s-queuename"),
+		}
+		rec.Status, _ = strconv.Atoi(get("sc-status"))
+		return fn(rec)
+	})
+}
+
+// HTTPErrAnalyzer accumulates HTTPERR records per request queue.
+type HTTPErrAnalyzer struct {
+	Since   time.Time
+	Bucket  time.Duration
+	Top     int
+	total   int
+	reasons map[string]int
+	pools   map[string]*poolAcc
+}
+
+type poolAcc struct {
+	stats       PoolStats
+	first, last time.Time
+	reasons     map[string]int
+	buckets     map[time.Time]*HTTPErrBucket
+}
+
+// NewHTTPErrAnalyzer uses the same defaults as NewAnalyzer.
+func NewHTTPErrAnalyzer(since time.Time, bucket time.Duration, top int) *HTTPErrAnalyzer {
+	if bucket <= 0 {
+		bucket = 5 * time.Minute
+	}
+	if top <= 0 {
+		top = 10
+	}
+	return &HTTPErrAnalyzer{Since: since, Bucket: bucket, Top: top, reasons: map[string]int{}, pools: map[string]*poolAcc{}}
+}
+
+// Add records one HTTP.sys failure. Requests rejected before a queue was
+// chosen are grouped under "-".
+func (a *HTTPErrAnalyzer) Add(rec HTTPErrRecord) {
+	if rec.Time.Before(a.Since) {
+		return
+	}
+	queue := rec.Queue
+	if queue == "" {
+		queue = "-"
+	}
+	acc, ok := a.pools[queue]
+	if !ok {
+		acc = &poolAcc{
+			stats:   PoolStats{Queue: queue},
+			reasons: map[string]int{},
+			buckets: map[time.Time]*HTTPErrBucket{},
+		}
+		a.pools[queue] = acc
+	}
+	if acc.first.IsZero() || rec.Time.Before(acc.first) {
+		acc.first = rec.Time
+	}
+	if rec.Time.After(acc.last) {
+		acc.last = rec.Time
+	}
+	start := rec.Time.Truncate(a.Bucket)
+	b, ok := acc.buckets[start]
+	if !ok {
+		b = &HTTPErrBucket{Start: start.Format(time.RFC3339)}
+		acc.buckets[start] = b
+	}
+
+	a.total++
+	acc.stats.Total++
+	b.Total++
+	if rec.Reason != "" {
+		a.reasons[rec.Reason]++
+		acc.reasons[rec.Reason]++
+	}
+	switch {
+	case deadReasons[rec.Reason]:
+		acc.stats.Dead++
+		b.Dead++
+	case overloadReasons[rec.Reason]:
+		acc.stats.Overloaded++
+		b.Overloaded++
+	}
+}
+
+// ReadLog parses an HTTPERR log into the analyzer.
+func (a *HTTPErrAnalyzer) ReadLog(r io.Reader) error {
+	return ParseHTTPErr(r, func(rec HTTPErrRecord) error {
+		a.Add(rec)
+		return nil
+	})
+}
+
+// ReadDir reads every httperr*.log in dir modified since a.Since, at most
+// maxBytes from the end of each file.
+func (a *HTTPErrAnalyzer) ReadDir(dir string, maxBytes int64) []error {
+	files, err := filepath.Glob(filepath.Join(dir, "httperr*.log"))
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if info.ModTime().Before(a.Since) {
+			continue
+		}
+		r, closeFn, err := openTail(path, maxBytes)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := a.ReadLog(r); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		closeFn()
+	}
+	return errs
+}
+
+// Stats returns the per-pool summary, pools with the most failures first.
+// A pool is diagnosed dead when worker-process loss dominates its failures
+// and overloaded when queue/connection limits do.
+func (a *HTTPErrAnalyzer) Stats() *HTTPErrStats {
+	out := &HTTPErrStats{Total: a.total, Reasons: topCounts(a.reasons, a.Top), Pools: []PoolStats{}}
+	for _, acc := range a.pools {
+		s := acc.stats
+		s.FirstSeen = acc.first.Format(time.RFC3339)
+		s.LastSeen = acc.last.Format(time.RFC3339)
+		s.Reasons = topCounts(acc.reasons, a.Top)
+		switch {
+		case s.Dead > 0 && s.Dead >= s.Overloaded:
+			s.Diagnosis = DiagnosisDeadPool
+		case s.Overloaded > 0:
+			s.Diagnosis = DiagnosisOverloaded
+		}
+		starts := make([]time.Time, 0, len(acc.buckets))
+		for t := range acc.buckets {
+			starts = append(starts, t)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		s.Buckets = make([]HTTPErrBucket, 0, len(starts))
+		for _, t := range starts {
+			s.Buckets = append(s.Buckets, *acc.buckets[t])
+		}
+		out.Pools = append(out.Pools, s)
+	}
+	sort.Slice(out.Pools, func(i, j int) bool {
+		if out.Pools[i].Total == out.Pools[j].Total {
+			return out.Pools[i].Queue < out.Pools[j].Queue
+		}
+		return out.Pools[i].Total > out.Pools[j].Total
+	})
+	return out
+}
@@ -0,0 +1,120 @@
+package iislog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseHTTPErr(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "httperr", "httperr_client.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []HTTPErrRecord
+	if err := ParseHTTPErr(f, func(rec HTTPErrRecord) error {
+		got = append(got, rec)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Connection-level failures have no request: "-" fields read as empty.
+	want := []HTTPErrRecord{
+		{Time: at("10:02:00"), Reason: "Timer_ConnectionIdle"},
+		{Time: at("10:02:30"), Status: 400, URI: "/bad%20path", Reason: "BadRequest"},
+		{Time: at("10:02:31"), SiteID: "1", URI: "/shop", Reason: "Timer_MinBytesPerSecond"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestHTTPErrDiagnosis(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[string]string
+	}{
+		// Worker-process loss outweighs the one QueueFull.
+		{"httperr_dead.log", map[string]string{"ShopPool": DiagnosisDeadPool}},
+		// Requests timing out in the pool queue are overload, not a dead pool.
+		{"httperr_queue_timeout.log", map[string]string{"ApiPool": DiagnosisOverloaded, "ReportPool": DiagnosisOverloaded}},
+		// Idle and malformed connections are reported but not diagnosed.
+		{"httperr_client.log", map[string]string{"-": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "httperr", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			a := NewHTTPErrAnalyzer(at("00:00:00"), 0, 0)
+			if err := a.ReadLog(f); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for _, p := range a.Stats().Pools {
+				got[p.Queue] = p.Diagnosis
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diagnoses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrStats(t *testing.T) {
+	a := NewHTTPErrAnalyzer(at("00:00:00"), 0, 3)
+	if errs := a.ReadDir(filepath.Join("testdata", "httperr"), 0); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	got := a.Stats()
+	if got.Total != 12 {
+		t.Errorf("total = %d, want 12", got.Total)
+	}
+	if want := []Count{{"AppOffline", 3}, {"Timer_AppPool", 3}, {"QueueFull", 2}}; !reflect.DeepEqual(got.Reasons, want) {
+		t.Errorf("reasons = %v, want %v", got.Reasons, want)
+	}
+	var queues []string
+	for _, p := range got.Pools {
+		queues = append(queues, p.Queue)
+	}
+	if want := []string{"ApiPool", "ShopPool", "-", "ReportPool"}; !reflect.DeepEqual(queues, want) {
+		t.Fatalf("pools = %v, want %v", queues, want)
+	}
+	shop := got.Pools[1]
+	want := PoolStats{
+		Queue: "ShopPool", Total: 4, Dead: 3, Overloaded: 1, Diagnosis: DiagnosisDeadPool,
+		FirstSeen: "2026-10-01T10:00:05Z", LastSeen: "2026-10-01T10:06:00Z",
+		Reasons: []Count{{"AppOffline", 2}, {"Connection_Abandoned_By_AppPool", 1}, {"QueueFull", 1}},
+		Buckets: []HTTPErrBucket{
+			{Start: "2026-10-01T10:00:00Z", Total: 3, Dead: 3},
+			{Start: "2026-10-01T10:05:00Z", Total: 1, Overloaded: 1},
+		},
+	}
+	if !reflect.DeepEqual(shop, want) {
+		t.Errorf("ShopPool:\n got %+v\nwant %+v", shop, want)
+	}
+	if api := got.Pools[0]; api.Dead != 1 || api.Overloaded != 3 || api.Diagnosis != DiagnosisOverloaded {
+		t.Errorf("ApiPool = %+v", api)
+	}
+}
+
+func TestHTTPErrSince(t *testing.T) {
+	a := NewHTTPErrAnalyzer(at("10:01:00"), 0, 0)
+	for _, rec := range []HTTPErrRecord{
+		{Time: at("10:00:59"), Reason: "AppOffline", Queue: "ShopPool"},
+		{Time: at("10:01:00"), Reason: "Timer_AppPool", Queue: "ShopPool"},
+	} {
+		a.Add(rec)
+	}
+	got := a.Stats()
+	if got.Total != 1 || len(got.Pools) != 1 || got.Pools[0].Diagnosis != DiagnosisOverloaded {
+		t.Errorf("stats = %+v", got)
+	}
+	if empty := NewHTTPErrAnalyzer(at("00:00:00"), 0, 0).Stats(); empty.Pools == nil || len(empty.Pools) != 0 {
+		t.Errorf("no records: pools = %#v, want empty", empty.Pools)
+	}
+}
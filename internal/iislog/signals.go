@@ -0,0 +1,330 @@
+package iislog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SignalsKind identifies the IIS signal document among pipeline inputs.
+const SignalsKind = "iis_signals"
+
+// Signals is the JSON document emitted by winopsguard-iis-signals and
+// consumed by triage and winopsguard-remediate-iis.
+type Signals struct {
+	Kind          string      `json:"kind"`
+	GeneratedAt   string      `json:"generatedAt"`
+	WindowMinutes int         `json:"windowMinutes"`
+	Sites         []SiteStats `json:"sites"`
+	// HTTPErr attributes HTTP.sys failures to application pools; these
+	// requests never reach the site logs.
+	HTTPErr *HTTPErrStats `json:"httpErr,omitempty"`
+	Errors  []string      `json:"errors"`
+}
+
+// SiteStats summarizes one site's access log over the window.
+type SiteStats struct {
+	Site           string       `json:"site"`
+	Requests       int          `json:"requests"`
+	Errors5xx      int          `json:"errors5xx"`
+	Errors503      int          `json:"errors503"`
+	ErrorRate5xx   float64      `json:"errorRate5xx"`
+	FirstSeen      string       `json:"firstSeen,omitempty"`
+	LastSeen       string       `json:"lastSeen,omitempty"`
+	Buckets        []Bucket     `json:"buckets"`
+	TopFailingURIs []Count      `json:"topFailingUris"`
+	Substatus      []Count      `json:"substatus"`
+	Win32Status    []Count      `json:"win32Status"`
+	TimeTakenMs    *Percentiles `json:"timeTakenMs,omitempty"`
+}
+
+// Bucket is one time slice of a site's traffic.
+type Bucket struct {
+	Start        string  `json:"start"`
+	Requests     int     `json:"requests"`
+	Errors5xx    int     `json:"errors5xx"`
+	Errors503    int     `json:"errors503"`
+	ErrorRate5xx float64 `json:"errorRate5xx"`
+}
+
+// Count is a labelled tally, e.g. a URI or "503.2".
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// Percentiles of time-taken in milliseconds.
+type Percentiles struct {
+	P50 int `json:"p50"`
+	P90 int `json:"p90"`
+	P95 int `json:"p95"`
+	P99 int `json:"p99"`
+	Max int `json:"max"`
+}
+
+// Analyzer accumulates W3C records per site. Records before Since are
+// ignored; Bucket sizes the time series and Top bounds the ranked lists.
+type Analyzer struct {
+	Since  time.Time
+	Bucket time.Duration
+	Top    int
+	sites  map[string]*siteAcc
+}
+
+type siteAcc struct {
+	stats     SiteStats
+	first     time.Time
+	last      time.Time
+	buckets   map[time.Time]*Bucket
+	uris      map[string]int
+	substatus map[string]int
+	win32     map[string]int
+	taken     []int
+}
+
+// NewAnalyzer returns an analyzer with 5-minute buckets and top-10 lists when
+// bucket or top are not positive.
+func NewAnalyzer(since time.Time, bucket time.Duration, top int) *Analyzer {
+	if bucket <= 0 {
+		bucket = 5 * time.Minute
+	}
+	if top <= 0 {
+		top = 10
+	}
+	return &Analyzer{Since: since, Bucket: bucket, Top: top, sites: map[string]*siteAcc{}}
+}
+
+// Add records one request.
+func (a *Analyzer) Add(rec Record) {
+	if rec.Time.Before(a.Since) {
+		return
+	}
+	acc, ok := a.sites[rec.Site]
+	if !ok {
+		acc = &siteAcc{
+			stats:     SiteStats{Site: rec.Site},
+			buckets:   map[time.Time]*Bucket{},
+			uris:      map[string]int{},
+			substatus: map[string]int{},
+			win32:     map[string]int{},
+		}
+		a.sites[rec.Site] = acc
+	}
+	if acc.first.IsZero() || rec.Time.Before(acc.first) {
+		acc.first = rec.Time
+	}
+	if rec.Time.After(acc.last) {
+		acc.last = rec.Time
+	}
+	start := rec.Time.Truncate(a.Bucket)
+	b, ok := acc.buckets[start]
+	if !ok {
+		b = &Bucket{Start: start.Format(time.RFC3339)}
+		acc.buckets[start] = b
+	}
+
+	acc.stats.Requests++
+	b.Requests++
+	if rec.TimeTaken >= 0 {
+		acc.taken = append(acc.taken, rec.TimeTaken)
+	}
+	if rec.Status >= 500 {
+		acc.stats.Errors5xx++
+		b.Errors5xx++
+		if rec.Status == 503 {
+			acc.stats.Errors503++
+			b.Errors503++
+		}
+	}
+	if rec.Status >= 400 {
+		if rec.URI != "" {
+			acc.uris[rec.URI]++
+		}
+		acc.substatus[fmt.Sprintf("%d.%d", rec.Status, rec.Substatus)]++
+		if rec.Win32 != 0 {
+			acc.win32[fmt.Sprintf("%d", rec.Win32)]++
+		}
+	}
+}
+
+// ReadLog parses a W3C log into the analyzer.
+func (a *Analyzer) ReadLog(r io.Reader, site string) error {
+	return ParseW3C(r, site, func(rec Record) error {
+		a.Add(rec)
+		return nil
+	})
+}
+
+// ReadDir reads every *.log under root's site directories (W3SVC1, ...)
+// modified since a.Since, at most maxBytes from the end of each file. Files
+// that cannot be read are returned as errors without stopping the walk.
+func (a *Analyzer) ReadDir(root string, maxBytes int64) []error {
+	var errs []error
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return []error{err}
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		files, _ := filepath.Glob(filepath.Join(root, d.Name(), "*.log"))
+		for _, path := range files {
+			if err := a.readFile(path, d.Name(), maxBytes); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+func (a *Analyzer) readFile(path, site string, maxBytes int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Before(a.Since) {
+		return nil
+	}
+	r, closeFn, err := openTail(path, maxBytes)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	if err := a.ReadLog(r, site); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Sites returns per-site statistics sorted by 5xx count.
+func (a *Analyzer) Sites() []SiteStats {
+	out := make([]SiteStats, 0, len(a.sites))
+	for _, acc := range a.sites {
+		s := acc.stats
+		s.ErrorRate5xx = rate(s.Errors5xx, s.Requests)
+		if !acc.first.IsZero() {
+			s.FirstSeen = acc.first.Format(time.RFC3339)
+			s.LastSeen = acc.last.Format(time.RFC3339)
+		}
+		starts := make([]time.Time, 0, len(acc.buckets))
+		for t := range acc.buckets {
+			starts = append(starts, t)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		s.Buckets = make([]Bucket, 0, len(starts))
+		for _, t := range starts {
+			b := *acc.buckets[t]
+			b.ErrorRate5xx = rate(b.Errors5xx, b.Requests)
+			s.Buckets = append(s.Buckets, b)
+		}
+		s.TopFailingURIs = topCounts(acc.uris, a.Top)
+		s.Substatus = topCounts(acc.substatus, a.Top)
+		s.Win32Status = topCounts(acc.win32, a.Top)
+		s.TimeTakenMs = percentiles(acc.taken)
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Errors5xx == out[j].Errors5xx {
+			return out[i].Site < out[j].Site
+		}
+		return out[i].Errors5xx > out[j].Errors5xx
+	})
+	return out
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(int(float64(n)/float64(total)*10000+0.5)) / 10000
+}
+
+func topCounts(m map[string]int, top int) []Count {
+	out := make([]Count, 0, len(m))
+	for k, v := range m {
+		out = append(out, Count{Key: k, Count: v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count == out[j].Count {
+			return out[i].Key < out[j].Key
+		}
+		return out[i].Count > out[j].Count
+	})
+	if len(out) > top {
+		out = out[:top]
+	}
+	return out
+}
+
+func percentiles(values []int) *Percentiles {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	at := func(p float64) int {
+		i := int(p*float64(len(sorted))+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(sorted) {
+			i = len(sorted) - 1
+		}
+		return sorted[i]
+	}
+	return &Percentiles{P50: at(0.50), P90: at(0.90), P95: at(0.95), P99: at(0.99), Max: sorted[len(sorted)-1]}
+}
+
+// openTail opens path positioned at most maxBytes before its end, on a line
+// boundary. The header directives at the top of the file are replayed first
+// so a tail read still knows the #Fields layout.
+func openTail(path string, maxBytes int64) (io.Reader, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if maxBytes <= 0 || info.Size() <= maxBytes {
+		return f, f.Close, nil
+	}
+	var header strings.Builder
+	hr := bufio.NewReader(io.LimitReader(f, 64*1024))
+	for {
+		line, err := hr.ReadString('\n')
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		header.WriteString(line)
+		if err != nil {
+			break
+		}
+	}
+	// Start one byte early: skipping up to the first newline then drops only
+	// a partial line, not a whole one that starts exactly at the cut.
+	if _, err := f.Seek(info.Size()-maxBytes-1, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	br := bufio.NewReader(f)
+	if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+		f.Close()
+		return nil, nil, err
+	}
+	return io.MultiReader(strings.NewReader(header.String()), br), f.Close, nil
+}
+
+// IsSignals reports whether a decoded JSON object is an IIS signal document.
+func IsSignals(m map[string]any) bool {
+	kind, _ := m["kind"].(string)
+	return strings.EqualFold(kind, SignalsKind)
+}
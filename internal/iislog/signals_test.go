@@ -0,0 +1,218 @@
+package iislog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzerSites(t *testing.T) {
+	a := NewAnalyzer(at("00:00:00"), 0, 0)
+	if errs := a.ReadDir(filepath.Join("testdata", "w3c"), 0); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	got := a.Sites()
+	want := []SiteStats{
+		{
+			Site:         "W3SVC1",
+			Requests:     7,
+			Errors5xx:    4,
+			Errors503:    2,
+			ErrorRate5xx: 0.5714,
+			FirstSeen:    "2026-10-01T10:00:01Z",
+			LastSeen:     "2026-10-01T10:07:00Z",
+			Buckets: []Bucket{
+				{Start: "2026-10-01T10:00:00Z", Requests: 4, Errors5xx: 3, Errors503: 1, ErrorRate5xx: 0.75},
+				{Start: "2026-10-01T10:05:00Z", Requests: 3, Errors5xx: 1, Errors503: 1, ErrorRate5xx: 0.3333},
+			},
+			TopFailingURIs: []Count{{"/shop/cart", 3}, {"/index.html", 1}, {"/shop/checkout", 1}},
+			Substatus:      []Count{{"500.19", 2}, {"404.0", 1}, {"503.0", 1}, {"503.2", 1}},
+			Win32Status:    []Count{{"5", 2}, {"1236", 1}, {"2", 1}},
+			TimeTakenMs:    &Percentiles{P50: 95, P90: 3000, P95: 3000, P99: 3000, Max: 3000},
+		},
+		{
+			Site:           "W3SVC2",
+			Requests:       2,
+			FirstSeen:      "2026-10-01T10:00:20Z",
+			LastSeen:       "2026-10-01T10:03:20Z",
+			Buckets:        []Bucket{{Start: "2026-10-01T10:00:00Z", Requests: 2}},
+			TopFailingURIs: []Count{},
+			Substatus:      []Count{},
+			Win32Status:    []Count{},
+			TimeTakenMs:    &Percentiles{P50: 3, P90: 5, P95: 5, P99: 5, Max: 5},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sites:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestAnalyzerSinceAndTop(t *testing.T) {
+	a := NewAnalyzer(at("10:04:00"), time.Minute, 1)
+	f, err := os.Open(filepath.Join("testdata", "w3c", "W3SVC1", "u_ex261001.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := a.ReadLog(f, "W3SVC1"); err != nil {
+		t.Fatal(err)
+	}
+	got := a.Sites()
+	if len(got) != 1 {
+		t.Fatalf("%d sites", len(got))
+	}
+	s := got[0]
+	if s.Requests != 4 || s.Errors5xx != 2 || s.FirstSeen != "2026-10-01T10:04:59Z" {
+		t.Errorf("records before Since counted: %+v", s)
+	}
+	var starts []string
+	for _, b := range s.Buckets {
+		starts = append(starts, b.Start)
+	}
+	if want := []string{"2026-10-01T10:04:00Z", "2026-10-01T10:05:00Z", "2026-10-01T10:06:00Z", "2026-10-01T10:07:00Z"}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("bucket starts = %v, want %v", starts, want)
+	}
+	if want := []Count{{"/shop/cart", 2}}; !reflect.DeepEqual(s.TopFailingURIs, want) {
+		t.Errorf("top URIs = %v, want %v", s.TopFailingURIs, want)
+	}
+	if len(s.Substatus) != 1 || len(s.Win32Status) != 1 {
+		t.Errorf("lists not cut to Top: %v %v", s.Substatus, s.Win32Status)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	hundred := make([]int, 100)
+	for i := range hundred {
+		hundred[i] = 100 - i
+	}
+	tests := []struct {
+		name   string
+		values []int
+		want   *Percentiles
+	}{
+		{"none", nil, nil},
+		{"one", []int{42}, &Percentiles{42, 42, 42, 42, 42}},
+		{"1 to 100 unsorted", hundred, &Percentiles{P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}},
+		{"outlier", []int{10, 10, 10, 10, 10, 10, 10, 10, 10, 5000}, &Percentiles{P50: 10, P90: 10, P95: 5000, P99: 5000, Max: 5000}},
+	}
+	for _, tt := range tests {
+		if got := percentiles(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: percentiles = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if hundred[0] != 100 {
+		t.Error("percentiles sorted its input")
+	}
+}
+
+// writeTailLog writes a log with a header and n records, one second apart,
+// of equal length, and returns its path and the length of one record line.
+func writeTailLog(t *testing.T, dir string, n int) (string, int) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("#Software: Microsoft Internet Information Services 10.0\r\n#Version: 1.0\r\n")
+	b.WriteString("#Fields: date time cs-method cs-uri-stem sc-status time-taken\r\n")
+	var line string
+	for i := range n {
+		line = fmt.Sprintf("%s GET /item/%03d 500 %03d\r\n", at("10:00:00").Add(time.Duration(i)*time.Second).Format(time.DateTime), i, i)
+		b.WriteString(line)
+	}
+	path := filepath.Join(dir, "u_ex261001.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, len(line)
+}
+
+func TestOpenTail(t *testing.T) {
+	path, lineLen := writeTailLog(t, t.TempDir(), 100)
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		wantFirst string
+		wantCount int
+	}{
+		{"whole file", 0, "/item/000", 100},
+		{"larger than file", 1 << 20, "/item/000", 100},
+		// Ten lines and part of the one before; the partial line is skipped.
+		{"mid-line cut", int64(10*lineLen + 5), "/item/090", 10},
+		{"on a line boundary", int64(10 * lineLen), "/item/090", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, closeFn, err := openTail(path, tt.maxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeFn()
+			var got []Record
+			// The replayed header lets the tail be parsed at all.
+			if err := ParseW3C(r, "W3SVC1", func(rec Record) error {
+				got = append(got, rec)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantCount || got[0].URI != tt.wantFirst {
+				t.Errorf("%d records from %s, want %d from %s", len(got), got[0].URI, tt.wantCount, tt.wantFirst)
+			}
+		})
+	}
+	if _, _, err := openTail(filepath.Join(t.TempDir(), "missing.log"), 10); err == nil {
+		t.Error("missing file: no error")
+	}
+}
+
+func TestOpenTailHeaderReplay(t *testing.T) {
+	path, lineLen := writeTailLog(t, t.TempDir(), 20)
+	r, closeFn, err := openTail(path, int64(2*lineLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#Software: Microsoft Internet Information Services 10.0\r\n#Version: 1.0\r\n" +
+		"#Fields: date time cs-method cs-uri-stem sc-status time-taken\r\n" +
+		"2026-10-01 10:00:18 GET /item/018 500 018\r\n" +
+		"2026-10-01 10:00:19 GET /item/019 500 019\r\n"
+	if string(b) != want {
+		t.Errorf("tail =\n%q\nwant\n%q", b, want)
+	}
+}
+
+func TestReadDirTail(t *testing.T) {
+	root := t.TempDir()
+	site := filepath.Join(root, "W3SVC7")
+	if err := os.Mkdir(site, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_, lineLen := writeTailLog(t, site, 50)
+	// An old file is skipped by its modification time.
+	old := filepath.Join(site, "u_ex260901.log")
+	if err := os.WriteFile(old, []byte("#Fields: date time sc-status\r\n2026-10-01 10:00:00 500\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	since := at("09:00:00")
+	if err := os.Chtimes(old, since.Add(-time.Hour), since.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnalyzer(since, 0, 0)
+	if errs := a.ReadDir(root, int64(5*lineLen)); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	got := a.Sites()
+	if len(got) != 1 || got[0].Site != "W3SVC7" || got[0].Requests != 5 || got[0].FirstSeen != "2026-10-01T10:00:45Z" || got[0].LastSeen != "2026-10-01T10:00:49Z" {
+		t.Errorf("sites = %+v", got)
+	}
+	if errs := NewAnalyzer(since, 0, 0).ReadDir(filepath.Join(root, "missing"), 0); len(errs) != 1 {
+		t.Errorf("missing root: %v", errs)
+	}
+}
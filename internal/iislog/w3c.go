@@ -0,0 +1,118 @@
+// Package iislog parses IIS W3C access logs and HTTP.sys error logs into
+// per-site signals for triage and IIS remediation.
+package iislog
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is one W3C log line. Fields absent from the #Fields directive are
+// left zero; Status is 0 when sc-status is not logged.
+type Record struct {
+	Time      time.Time
+	Site      string
+	Method    string
+	URI       string
+	Status    int
+	Substatus int
+	Win32     uint32
+	// TimeTaken is in milliseconds; -1 when time-taken is not logged.
+	TimeTaken int
+}
+
+// ParseW3C reads a W3C extended log and calls fn for every record. The
+// #Fields directive may change mid-file (IIS rewrites it after a config
+// change) and is honored line by line. site labels records whose log has no
+// s-sitename field.
+func ParseW3C(r io.Reader, site string, fn func(Record) error) error {
+	return scanW3C(r, func(get fieldFunc) error {
+		rec, ok := parseW3CLine(get, site)
+		if !ok {
+			return nil
+		}
+		return fn(rec)
+	})
+}
+
+// fieldFunc returns a field of the current line by its #Fields name, or ""
+// when the field is not logged or is "-".
+type fieldFunc func(name string) string
+
+// scanW3C walks the data lines of any log using W3C #Fields directives
+// (IIS site logs, HTTPERR).
+func scanW3C(r io.Reader, fn func(get fieldFunc) error) error {
+	var index map[string]int
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if rest, ok := strings.CutPrefix(line, "#Fields:"); ok {
+				index = map[string]int{}
+				for i, name := range strings.Fields(rest) {
+					index[strings.ToLower(name)] = i
+				}
+			}
+			continue
+		}
+		if index == nil {
+			continue
+		}
+		values := strings.Fields(line)
+		get := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(values) || values[i] == "-" {
+				return ""
+			}
+			return values[i]
+		}
+		if err := fn(get); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// parseTime reads the date and time fields, which W3C logs write in UTC
+// regardless of the server time zone.
+func parseTime(get fieldFunc) (time.Time, bool) {
+	date, clock := get("date"), get("time")
+	if date == "" || clock == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", date+" "+clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+func parseW3CLine(get fieldFunc, site string) (Record, bool) {
+	rec := Record{Site: site, TimeTaken: -1}
+	t, ok := parseTime(get)
+	if !ok {
+		return rec, false
+	}
+	rec.Time = t
+	if s := get("s-sitename"); s != "" {
+		rec.Site = s
+	}
+	rec.Method = get("cs-method")
+	rec.URI = get("cs-uri-stem")
+	rec.Status, _ = strconv.Atoi(get("sc-status"))
+	rec.Substatus, _ = strconv.Atoi(get("sc-substatus"))
+	if w, err := strconv.ParseUint(get("sc-win32-status"), 10, 32); err == nil {
+		rec.Win32 = uint32(w)
+	}
+	if ms, err := strconv.Atoi(get("time-taken")); err == nil {
+		rec.TimeTaken = ms
+	}
+	return rec, true
+}
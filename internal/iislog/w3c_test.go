@@ -0,0 +1,92 @@
+package iislog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(clock string) time.Time {
+	t, err := time.Parse(time.DateTime, "2026-10-01 "+clock)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseW3C(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "w3c", "W3SVC1", "u_ex261001.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Record
+	err = ParseW3C(f, "fallback", func(rec Record) error {
+		got = append(got, rec)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second #Fields directive adds s-sitename and drops the win32 status
+	// and time-taken; the line without a date is skipped.
+	want := []Record{
+		{Time: at("10:00:01"), Site: "fallback", Method: "GET", URI: "/shop/cart", Status: 200, TimeTaken: 15},
+		{Time: at("10:01:10"), Site: "fallback", Method: "GET", URI: "/shop/cart", Status: 500, Substatus: 19, Win32: 5, TimeTaken: 120},
+		{Time: at("10:02:00"), Site: "fallback", Method: "POST", URI: "/shop/checkout", Status: 503, Substatus: 2, Win32: 1236, TimeTaken: 3000},
+		{Time: at("10:04:59"), Site: "fallback", Method: "GET", URI: "/shop/cart", Status: 500, Substatus: 19, Win32: 5, TimeTaken: 95},
+		{Time: at("10:05:00"), Site: "fallback", Method: "GET", URI: "/index.html", Status: 404, Win32: 2, TimeTaken: 4},
+		{Time: at("10:06:30"), Site: "W3SVC1", Method: "GET", URI: "/shop/cart", Status: 503, TimeTaken: -1},
+		{Time: at("10:07:00"), Site: "W3SVC1", Method: "GET", URI: "/shop/cart", TimeTaken: -1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseW3CEdgeCases(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want []Record
+	}{
+		{
+			name: "data before any directive",
+			log:  "2026-10-01 10:00:00 GET / 200\n#Fields: date time cs-method cs-uri-stem sc-status\n2026-10-01 10:00:01 GET / 200\n",
+			want: []Record{{Time: at("10:00:01"), Site: "s", Method: "GET", URI: "/", Status: 200, TimeTaken: -1}},
+		},
+		{
+			name: "short line",
+			log:  "#Fields: date time cs-method cs-uri-stem sc-status time-taken\n2026-10-01 10:00:01 GET\n",
+			want: []Record{{Time: at("10:00:01"), Site: "s", Method: "GET", TimeTaken: -1}},
+		},
+		{
+			name: "bad time",
+			log:  "#Fields: date time sc-status\n2026-10-01 25:99:00 500\n\n2026-10-01 10:00:02 500\n",
+			want: []Record{{Time: at("10:00:02"), Site: "s", Status: 500, TimeTaken: -1}},
+		},
+		{
+			name: "field names are case-insensitive",
+			log:  "#Fields: Date Time SC-Status Time-Taken\n2026-10-01 10:00:03 502 7\n",
+			want: []Record{{Time: at("10:00:03"), Site: "s", Status: 502, TimeTaken: 7}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Record
+			err := ParseW3C(strings.NewReader(tt.log), "s", func(rec Record) error {
+				got = append(got, rec)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records:\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"winopsguard/internal/config"
+)
+
+const (
+	anthropicBaseURL      = "https://api.anthropic.com"
+	defaultAnthropicModel = "claude-3-5-haiku-latest"
+	defaultAnthropicAPI   = "2023-06-01"
+	// anthropicMaxTokens is sent when the request sets none; the Messages
+	// API requires max_tokens.
+	anthropicMaxTokens = 1024
+)
+
+func init() {
+	Register("anthropic", func(name string, cfg config.ProviderConfig) (Provider, error) {
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = anthropicBaseURL
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultAnthropicModel
+		}
+		if cfg.APIVersion == "" {
+			cfg.APIVersion = defaultAnthropicAPI
+		}
+		key, env := apiKey(cfg, "ANTHROPIC_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("%w: %s is not set", ErrNoCredentials, env)
+		}
+		h, err := newHTTPClient("Anthropic", cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &anthropic{
+			name:     name,
+			model:    cfg.Model,
+			endpoint: strings.TrimRight(cfg.BaseURL, "/") + "/v1/messages",
+			auth:     map[string]string{"x-api-key": key, "anthropic-version": cfg.APIVersion},
+			http:     h,
+		}, nil
+	})
+}
+
+// anthropic speaks the Messages API.
+type anthropic struct {
+	name     string
+	model    string
+	endpoint string
+	auth     map[string]string
+	http     *httpClient
+}
+
+func (p *anthropic) Name() string         { return p.name }
+func (p *anthropic) DefaultModel() string { return p.model }
+
+func (p *anthropic) Complete(ctx context.Context, req Request) (string, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicMaxTokens
+	}
+	body := struct {
+		Model       string        `json:"model"`
+		MaxTokens   int           `json:"max_tokens"`
+		Temperature float64       `json:"temperature"`
+		System      string        `json:"system,omitempty"`
+		Messages    []chatMessage `json:"messages"`
+	}{
+		Model:     req.Model,
+		MaxTokens: maxTokens,
+		System:    req.System,
+		Messages:  []chatMessage{{Role: "user", Content: req.User}},
+	}
+	respBody, err := p.http.postJSON(ctx, p.endpoint, body, p.auth)
+	if err != nil {
+		return "", err
+	}
+
+	var decoded struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return "", fmt.Errorf("decode Anthropic response: %w", err)
+	}
+	var text strings.Builder
+	for _, c := range decoded.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("Anthropic response has no text (stop_reason %q)", decoded.StopReason)
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"winopsguard/internal/config"
+)
+
+func TestAnthropicRequest(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+	tests := []struct {
+		name          string
+		cfg           config.ProviderConfig
+		maxTokens     int
+		wantVersion   string
+		wantMaxTokens float64
+	}{
+		{name: "defaults", wantVersion: defaultAnthropicAPI, wantMaxTokens: anthropicMaxTokens},
+		{name: "configured", cfg: config.ProviderConfig{APIVersion: "2024-10-22"}, maxTokens: 300, wantVersion: "2024-10-22", wantMaxTokens: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got captured
+			reply := `{"content": [{"type": "text", "text": "{\"ok\":"}, {"type": "text", "text": " true}"}], "stop_reason": "end_turn"}`
+			srv := httptest.NewServer(recorder(t, &got, http.StatusOK, reply))
+			defer srv.Close()
+			cfg := tt.cfg
+			cfg.BaseURL = srv.URL + "/"
+			cfg.Headers = map[string]string{"x-api-key": "from-headers"}
+			p, err := New("anthropic", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := p.Complete(context.Background(), Request{Model: p.DefaultModel(), System: "sys", User: "usr", MaxTokens: tt.maxTokens})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != `{"ok": true}` {
+				t.Errorf("reply = %q", out)
+			}
+			if got.path != "/v1/messages" {
+				t.Errorf("path = %q", got.path)
+			}
+			if k := got.header.Get("x-api-key"); k != "sk-ant-test" {
+				t.Errorf("x-api-key = %q, want the key from ANTHROPIC_API_KEY", k)
+			}
+			if v := got.header.Get("anthropic-version"); v != tt.wantVersion {
+				t.Errorf("anthropic-version = %q, want %q", v, tt.wantVersion)
+			}
+			if got.body["max_tokens"] != tt.wantMaxTokens {
+				t.Errorf("max_tokens = %v, want %v", got.body["max_tokens"], tt.wantMaxTokens)
+			}
+			if got.body["model"] != defaultAnthropicModel || got.body["system"] != "sys" {
+				t.Errorf("body = %v", got.body)
+			}
+		})
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+	tests := []struct {
+		name   string
+		status int
+		reply  string
+		want   string
+	}{
+		{
+			name:   "4xx error body",
+			status: http.StatusBadRequest,
+			reply:  `{"type": "error", "error": {"type": "invalid_request_error", "message": "max_tokens: must be at most 8192"}}`,
+			want:   "Anthropic HTTP 400: invalid_request_error: max_tokens: must be at most 8192",
+		},
+		{
+			name:   "no text",
+			status: http.StatusOK,
+			reply:  `{"content": [], "stop_reason": "max_tokens"}`,
+			want:   `Anthropic response has no text (stop_reason "max_tokens")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got captured
+			srv := httptest.NewServer(recorder(t, &got, tt.status, tt.reply))
+			defer srv.Close()
+			p, err := New("anthropic", config.ProviderConfig{BaseURL: srv.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.Complete(context.Background(), Request{Model: "m", User: "u"})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicNoKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := New("anthropic", config.ProviderConfig{}); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("err = %v, want ErrNoCredentials", err)
+	}
+}
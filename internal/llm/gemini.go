@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"winopsguard/internal/config"
+)
+
+const (
+	geminiBaseURL      = "https://generativelanguage.googleapis.com/v1beta"
+	defaultGeminiModel = "gemini-1.5-flash"
+)
+
+func init() {
+	Register("gemini", func(name string, cfg config.ProviderConfig) (Provider, error) {
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = geminiBaseURL
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultGeminiModel
+		}
+		key, env := apiKey(cfg, "GEMINI_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("%w: %s is not set", ErrNoCredentials, env)
+		}
+		h, err := newHTTPClient("Gemini", cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &gemini{
+			name:    name,
+			model:   cfg.Model,
+			baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+			key:     key,
+			schemas: structured(cfg, true),
+			http:    h,
+		}, nil
+	})
+}
+
+// gemini speaks the generateContent API.
+type gemini struct {
+	name    string
+	model   string
+	baseURL string
+	key     string
+	// schemas sends Request.Schema as responseSchema.
+	schemas bool
+	http    *httpClient
+}
+
+func (p *gemini) Name() string         { return p.name }
+func (p *gemini) DefaultModel() string { return p.model }
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+func (p *gemini) Complete(ctx context.Context, req Request) (string, error) {
+	body := struct {
+		SystemInstruction geminiContent   `json:"systemInstruction"`
+		Contents          []geminiContent `json:"contents"`
+		GenerationConfig  struct {
+			Temperature      float64        `json:"temperature,omitempty"`
+			MaxOutputTokens  int            `json:"maxOutputTokens,omitempty"`
+			ResponseMimeType string         `json:"responseMimeType,omitempty"`
+			ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
+		} `json:"generationConfig,omitempty"`
+	}{
+		SystemInstruction: geminiContent{Parts: []geminiPart{{Text: req.System}}},
+		Contents:          []geminiContent{{Role: "user", Parts: []geminiPart{{Text: req.User}}}},
+	}
+	body.GenerationConfig.MaxOutputTokens = req.MaxTokens
+	if p.schemas && req.Schema != nil {
+		body.GenerationConfig.ResponseMimeType = "application/json"
+		body.GenerationConfig.ResponseSchema = geminiSchema(req.Schema.JSON)
+	}
+
+	// The key goes in a header rather than the query string, so transport
+	// errors that quote the URL do not leak it.
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", p.baseURL, url.PathEscape(req.Model))
+	respBody, err := p.http.postJSON(ctx, endpoint, body, map[string]string{"x-goog-api-key": p.key})
+	if err != nil {
+		return "", err
+	}
+
+	var decoded struct {
+		Candidates []struct {
+			Content geminiContent `json:"content"`
+		} `json:"candidates"`
+	}
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return "", fmt.Errorf("decode Gemini response: %w", err)
+	}
+	if len(decoded.Candidates) == 0 || len(decoded.Candidates[0].Content.Parts) == 0 {
+		return "", errors.New("Gemini response has no text")
+	}
+	return decoded.Candidates[0].Content.Parts[0].Text, nil
+}
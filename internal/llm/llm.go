@@ -0,0 +1,234 @@
+// Package llm is the provider layer of the triage stage: a Provider sends a
+// system and user prompt to one LLM API and returns the text of the reply.
+// Implementations register a factory under a type name; config.json selects
+// and configures them per provider name.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"winopsguard/internal/config"
+)
+
+// Request is one completion.
+type Request struct {
+	Model     string
+	System    string
+	User      string
+	MaxTokens int
+	// Input is the signals JSON User embeds, before any trimming to the
+	// token budget, for providers that read the evidence directly instead
+	// of prompting a model.
+	Input string
+	// Schema, when set, is the shape of the expected reply.
+	Schema *Schema
+}
+
+// Provider completes prompts against one LLM API.
+type Provider interface {
+	// Name is the name the provider was selected by.
+	Name() string
+	// DefaultModel is the model used when none is requested; it may be
+	// empty for self-hosted endpoints.
+	DefaultModel() string
+	Complete(ctx context.Context, req Request) (string, error)
+}
+
+// Factory builds a provider named name from its settings.
+type Factory func(name string, cfg config.ProviderConfig) (Provider, error)
+
+// ErrNoCredentials is returned by New when the provider needs an API key
+// and none is set.
+var ErrNoCredentials = errors.New("no API key")
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a provider type available to New.
+func Register(typ string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[strings.ToLower(typ)] = f
+}
+
+// Types lists the registered provider types.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, len(factories))
+	for t := range factories {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// New builds the provider called name. cfg.Type selects the implementation
+// and defaults to name, so "openai" and "gemini" need no configuration.
+func New(name string, cfg config.ProviderConfig) (Provider, error) {
+	typ := strings.ToLower(strings.TrimSpace(cfg.Type))
+	if typ == "" {
+		typ = strings.ToLower(strings.TrimSpace(name))
+	}
+	mu.RLock()
+	f, ok := factories[typ]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s (types: %s)", name, strings.Join(Types(), ", "))
+	}
+	return f(name, cfg)
+}
+
+// structured reports whether the provider should use its native structured
+// output mode: cfg.StructuredOutput if set, otherwise def.
+func structured(cfg config.ProviderConfig, def bool) bool {
+	if cfg.StructuredOutput != nil {
+		return *cfg.StructuredOutput
+	}
+	return def
+}
+
+// apiKey reads the key from cfg.APIKeyEnv, or defaultEnv when unset.
+func apiKey(cfg config.ProviderConfig, defaultEnv string) (string, string) {
+	env := cfg.APIKeyEnv
+	if env == "" {
+		env = defaultEnv
+	}
+	if env == "" {
+		return "", ""
+	}
+	return strings.TrimSpace(os.Getenv(env)), env
+}
+
+// httpClient posts JSON with the provider's extra headers and TLS settings.
+type httpClient struct {
+	label   string
+	client  *http.Client
+	headers map[string]string
+}
+
+func newHTTPClient(label string, cfg config.ProviderConfig) (*httpClient, error) {
+	tlsCfg, err := tlsConfig(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("%s TLS settings: %w", label, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsCfg != nil {
+		transport.TLSClientConfig = tlsCfg
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	// The caller's context carries the deadline.
+	return &httpClient{label: label, client: &http.Client{Transport: transport}, headers: headers}, nil
+}
+
+func tlsConfig(c config.TLSConfig) (*tls.Config, error) {
+	if c == (config.TLSConfig{}) {
+		return nil, nil
+	}
+	out := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificates", c.CAFile)
+		}
+		out.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		out.Certificates = []tls.Certificate{cert}
+	}
+	return out, nil
+}
+
+// postJSON sends body to url and returns the response body of a 2xx reply.
+// auth headers are set after the configured ones, so configured headers
+// cannot silently replace the credentials.
+func (h *httpClient) postJSON(ctx context.Context, url string, body any, auth map[string]string) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s request: %w", h.label, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("create %s request: %w", h.label, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range auth {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", h.label, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s response: %w", h.label, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s HTTP %d: %s", h.label, resp.StatusCode, errorMessage(respBody))
+	}
+	return respBody, nil
+}
+
+// errorMessage extracts the {"error": {...}} body that OpenAI, Azure OpenAI,
+// Anthropic and Gemini return on failure, falling back to the raw body.
+func errorMessage(body []byte) string {
+	var e struct {
+		Error struct {
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil || e.Error.Message == "" {
+		return strings.TrimSpace(string(body))
+	}
+	kind := e.Error.Type
+	if code, ok := e.Error.Code.(string); ok && code != "" {
+		kind = code
+	}
+	if kind == "" {
+		kind = e.Error.Status
+	}
+	if kind == "" {
+		return e.Error.Message
+	}
+	return kind + ": " + e.Error.Message
+}
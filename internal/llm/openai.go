@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"winopsguard/internal/config"
+)
+
+const (
+	openAIBaseURL      = "https://api.openai.com/v1"
+	defaultOpenAIModel = "gpt-4o-mini"
+	defaultAzureAPI    = "2024-10-21"
+)
+
+func init() {
+	Register("openai", func(name string, cfg config.ProviderConfig) (Provider, error) {
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = openAIBaseURL
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultOpenAIModel
+		}
+		key, env := apiKey(cfg, "OPENAI_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("%w: %s is not set", ErrNoCredentials, env)
+		}
+		p, err := newOpenAI(name, "OpenAI", cfg, map[string]string{"Authorization": "Bearer " + key})
+		if err != nil {
+			return nil, err
+		}
+		p.schemas = structured(cfg, true)
+		return p, nil
+	})
+	// openai-compatible serves self-hosted endpoints with the OpenAI chat
+	// completions API: Ollama, llama.cpp server, vLLM, LM Studio. The key
+	// is optional.
+	Register("openai-compatible", func(name string, cfg config.ProviderConfig) (Provider, error) {
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("provider %s: base_url is required (e.g. http://localhost:11434/v1)", name)
+		}
+		var auth map[string]string
+		if key, _ := apiKey(cfg, ""); key != "" {
+			auth = map[string]string{"Authorization": "Bearer " + key}
+		}
+		p, err := newOpenAI(name, name, cfg, auth)
+		if err != nil {
+			return nil, err
+		}
+		p.schemas = structured(cfg, false)
+		return p, nil
+	})
+	// azure-openai addresses a deployment of an Azure OpenAI resource:
+	// {base_url}/openai/deployments/{deployment}/chat/completions?api-version=...
+	// with the key in the api-key header. The model in the request is the
+	// deployment name.
+	Register("azure-openai", func(name string, cfg config.ProviderConfig) (Provider, error) {
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = os.Getenv("AZURE_OPENAI_ENDPOINT")
+		}
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("provider %s: base_url (or AZURE_OPENAI_ENDPOINT) is required, e.g. https://<resource>.openai.azure.com", name)
+		}
+		if cfg.Deployment != "" {
+			cfg.Model = cfg.Deployment
+		}
+		if cfg.APIVersion == "" {
+			cfg.APIVersion = defaultAzureAPI
+		}
+		key, env := apiKey(cfg, "AZURE_OPENAI_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("%w: %s is not set", ErrNoCredentials, env)
+		}
+		p, err := newOpenAI(name, "Azure OpenAI", cfg, map[string]string{"api-key": key})
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimRight(cfg.BaseURL, "/")
+		version := url.QueryEscape(cfg.APIVersion)
+		p.endpoint = func(deployment string) string {
+			return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s", base, url.PathEscape(deployment), version)
+		}
+		p.sendModel = false
+		p.schemas = structured(cfg, true)
+		return p, nil
+	})
+}
+
+// openAI speaks the chat completions API.
+type openAI struct {
+	name     string
+	model    string
+	endpoint func(model string) string
+	// sendModel is false where the URL selects the model (Azure).
+	sendModel bool
+	// schemas sends Request.Schema as a strict json_schema response_format.
+	schemas bool
+	auth    map[string]string
+	http    *httpClient
+}
+
+func newOpenAI(name, label string, cfg config.ProviderConfig, auth map[string]string) (*openAI, error) {
+	h, err := newHTTPClient(label, cfg)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimRight(cfg.BaseURL, "/") + "/chat/completions"
+	return &openAI{
+		name:      name,
+		model:     cfg.Model,
+		endpoint:  func(string) string { return endpoint },
+		sendModel: true,
+		auth:      auth,
+		http:      h,
+	}, nil
+}
+
+func (p *openAI) Name() string         { return p.name }
+func (p *openAI) DefaultModel() string { return p.model }
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string         `json:"name"`
+		Strict bool           `json:"strict"`
+		Schema map[string]any `json:"schema"`
+	} `json:"json_schema"`
+}
+
+func (p *openAI) Complete(ctx context.Context, req Request) (string, error) {
+	body := struct {
+		Model          string          `json:"model,omitempty"`
+		Temperature    float64         `json:"temperature"`
+		MaxTokens      int             `json:"max_tokens,omitempty"`
+		Messages       []chatMessage   `json:"messages"`
+		ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	}{
+		Temperature: 0,
+		MaxTokens:   req.MaxTokens,
+		Messages: []chatMessage{
+			{Role: "system", Content: req.System},
+			{Role: "user", Content: req.User},
+		},
+	}
+	if p.sendModel {
+		body.Model = req.Model
+	}
+	if p.schemas && req.Schema != nil {
+		rf := &responseFormat{Type: "json_schema"}
+		rf.JSONSchema.Name = req.Schema.Name
+		rf.JSONSchema.Strict = true
+		rf.JSONSchema.Schema = req.Schema.JSON
+		body.ResponseFormat = rf
+	}
+	respBody, err := p.http.postJSON(ctx, p.endpoint(req.Model), body, p.auth)
+	if err != nil {
+		return "", err
+	}
+
+	var decoded struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+				Refusal string `json:"refusal"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return "", fmt.Errorf("decode %s response: %w", p.http.label, err)
+	}
+	if len(decoded.Choices) == 0 {
+		return "", errors.New(p.http.label + " response has no choices")
+	}
+	if c := decoded.Choices[0]; c.Message.Content == "" && c.FinishReason == "content_filter" {
+		return "", errors.New(p.http.label + " response was blocked by the content filter")
+	}
+	if c := decoded.Choices[0]; c.Message.Content == "" && c.Message.Refusal != "" {
+		return "", fmt.Errorf("%s refused: %s", p.http.label, c.Message.Refusal)
+	}
+	return decoded.Choices[0].Message.Content, nil
+}
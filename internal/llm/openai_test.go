@@ -0,0 +1,305 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"winopsguard/internal/config"
+)
+
+// captured is one request as the test server saw it.
+type captured struct {
+	path   string
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+// recorder answers every request with status and reply and keeps the last
+// request in *got.
+func recorder(t *testing.T, got *captured, status int, reply string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		*got = captured{path: r.URL.Path, query: r.URL.RawQuery, header: r.Header.Clone()}
+		if err := json.Unmarshal(b, &got.body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}
+}
+
+const chatReply = `{"choices": [{"message": {"content": "{\"ok\": true}"}, "finish_reason": "stop"}]}`
+
+func TestOpenAICompatibleRequest(t *testing.T) {
+	t.Setenv("LOCAL_LLM_KEY", "sk-local")
+	t.Setenv("TEAM", "ops")
+	tests := []struct {
+		name     string
+		baseURL  string
+		keyEnv   string
+		headers  map[string]string
+		wantPath string
+		wantAuth string
+	}{
+		{name: "no key", baseURL: "/v1", wantPath: "/v1/chat/completions"},
+		{name: "trailing slash", baseURL: "/v1/", wantPath: "/v1/chat/completions"},
+		{name: "bearer key", baseURL: "/api/v1", keyEnv: "LOCAL_LLM_KEY", wantPath: "/api/v1/chat/completions", wantAuth: "Bearer sk-local"},
+		{name: "unset key env", baseURL: "/v1", keyEnv: "LOCAL_LLM_UNSET", wantPath: "/v1/chat/completions"},
+		{
+			name:     "configured headers do not replace auth",
+			baseURL:  "/v1",
+			keyEnv:   "LOCAL_LLM_KEY",
+			headers:  map[string]string{"Authorization": "Bearer other", "X-Team": "${TEAM}"},
+			wantPath: "/v1/chat/completions",
+			wantAuth: "Bearer sk-local",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got captured
+			srv := httptest.NewServer(recorder(t, &got, http.StatusOK, chatReply))
+			defer srv.Close()
+			p, err := New("local", config.ProviderConfig{
+				Type:      "openai-compatible",
+				BaseURL:   srv.URL + tt.baseURL,
+				APIKeyEnv: tt.keyEnv,
+				Headers:   tt.headers,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := p.Complete(context.Background(), Request{Model: "llama3", System: "sys", User: "usr", Schema: &Schema{Name: "verdict"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != `{"ok": true}` {
+				t.Errorf("reply = %q", out)
+			}
+			if got.path != tt.wantPath {
+				t.Errorf("path = %q, want %q", got.path, tt.wantPath)
+			}
+			if a := got.header.Get("Authorization"); a != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", a, tt.wantAuth)
+			}
+			if tt.headers != nil && got.header.Get("X-Team") != "ops" {
+				t.Errorf("X-Team = %q, want expanded %q", got.header.Get("X-Team"), "ops")
+			}
+			if got.body["model"] != "llama3" {
+				t.Errorf("model = %v", got.body["model"])
+			}
+			// Structured output is off by default for compatible servers.
+			if _, ok := got.body["response_format"]; ok {
+				t.Error("response_format sent to an openai-compatible server")
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleBaseURLRequired(t *testing.T) {
+	if _, err := New("local", config.ProviderConfig{Type: "openai-compatible"}); err == nil || !strings.Contains(err.Error(), "base_url is required") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// writeCA saves the test server's certificate as a PEM bundle.
+func writeCA(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenAICompatibleTLS(t *testing.T) {
+	var got captured
+	srv := httptest.NewTLSServer(recorder(t, &got, http.StatusOK, chatReply))
+	defer srv.Close()
+	ca := writeCA(t, srv)
+
+	// The httptest certificate is issued for 127.0.0.1 and example.com.
+	tests := []struct {
+		name    string
+		tls     config.TLSConfig
+		wantErr string
+	}{
+		{name: "system roots only", wantErr: "certificate"},
+		{name: "ca file", tls: config.TLSConfig{CAFile: ca}},
+		{name: "ca file and server name", tls: config.TLSConfig{CAFile: ca, ServerName: "example.com"}},
+		{name: "server name not in certificate", tls: config.TLSConfig{CAFile: ca, ServerName: "llm.internal"}, wantErr: "llm.internal"},
+		{name: "insecure", tls: config.TLSConfig{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New("local", config.ProviderConfig{Type: "openai-compatible", BaseURL: srv.URL + "/v1", TLS: tt.tls})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.Complete(context.Background(), Request{Model: "m", User: "u"})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("err = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		tls  config.TLSConfig
+		want string
+	}{
+		{"missing ca file", config.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}, "missing.pem"},
+		{"no certificates", config.TLSConfig{CAFile: notPEM}, "no PEM certificates"},
+		{"missing client key", config.TLSConfig{CertFile: notPEM}, "TLS settings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("local", config.ProviderConfig{Type: "openai-compatible", BaseURL: "https://llm.internal/v1", TLS: tt.tls})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleHTTPError(t *testing.T) {
+	var got captured
+	reply := `{"error": {"message": "model 'llama9' not found", "type": "invalid_request_error", "code": "model_not_found"}}`
+	srv := httptest.NewServer(recorder(t, &got, http.StatusNotFound, reply))
+	defer srv.Close()
+	p, err := New("local", config.ProviderConfig{Type: "openai-compatible", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Complete(context.Background(), Request{Model: "llama9", User: "u"})
+	if want := "local HTTP 404: model_not_found: model 'llama9' not found"; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"openai code", `{"error": {"message": "Incorrect API key", "type": "invalid_request_error", "code": "invalid_api_key"}}`, "invalid_api_key: Incorrect API key"},
+		{"openai null code", `{"error": {"message": "Rate limit", "type": "requests", "code": null}}`, "requests: Rate limit"},
+		{"azure numeric code", `{"error": {"message": "Deployment not found", "code": 404}}`, "Deployment not found"},
+		{"anthropic", `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`, "overloaded_error: Overloaded"},
+		{"gemini", `{"error": {"code": 400, "message": "API key not valid", "status": "INVALID_ARGUMENT"}}`, "INVALID_ARGUMENT: API key not valid"},
+		{"plain text", "  upstream connect error\n", "upstream connect error"},
+		{"json without message", `{"detail": "Not Found"}`, `{"detail": "Not Found"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorMessage([]byte(tt.body)); got != tt.want {
+				t.Errorf("errorMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAzureOpenAIRequest(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+	tests := []struct {
+		name      string
+		cfg       config.ProviderConfig
+		model     string
+		wantPath  string
+		wantQuery string
+	}{
+		{
+			name:      "deployment and default api version",
+			cfg:       config.ProviderConfig{Deployment: "triage-gpt4o"},
+			wantPath:  "/openai/deployments/triage-gpt4o/chat/completions",
+			wantQuery: "api-version=" + defaultAzureAPI,
+		},
+		{
+			name:      "model names the deployment",
+			cfg:       config.ProviderConfig{Model: "gpt-4o", APIVersion: "2025-01-01-preview"},
+			wantPath:  "/openai/deployments/gpt-4o/chat/completions",
+			wantQuery: "api-version=2025-01-01-preview",
+		},
+		{
+			name:      "requested model overrides the default",
+			cfg:       config.ProviderConfig{Deployment: "triage-gpt4o"},
+			model:     "night shift",
+			wantPath:  "/openai/deployments/night shift/chat/completions",
+			wantQuery: "api-version=" + defaultAzureAPI,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got captured
+			srv := httptest.NewServer(recorder(t, &got, http.StatusOK, chatReply))
+			defer srv.Close()
+			cfg := tt.cfg
+			cfg.Type = "azure-openai"
+			cfg.BaseURL = srv.URL + "/"
+			cfg.Headers = map[string]string{"api-key": "from-headers"}
+			p, err := New("azure", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := tt.model
+			if model == "" {
+				model = p.DefaultModel()
+			}
+			if _, err := p.Complete(context.Background(), Request{Model: model, User: "u", Schema: &Schema{Name: "verdict", JSON: map[string]any{"type": "object"}}}); err != nil {
+				t.Fatal(err)
+			}
+			if got.path != tt.wantPath || got.query != tt.wantQuery {
+				t.Errorf("url = %s?%s, want %s?%s", got.path, got.query, tt.wantPath, tt.wantQuery)
+			}
+			if k := got.header.Get("api-key"); k != "azure-key" {
+				t.Errorf("api-key = %q, want the key from AZURE_OPENAI_API_KEY", k)
+			}
+			if a := got.header.Get("Authorization"); a != "" {
+				t.Errorf("Authorization = %q, want none", a)
+			}
+			if _, ok := got.body["model"]; ok {
+				t.Errorf("body has model %v; the deployment selects it", got.body["model"])
+			}
+			if _, ok := got.body["response_format"]; !ok {
+				t.Error("no response_format; structured output is on by default for Azure")
+			}
+		})
+	}
+}
+
+func TestAzureOpenAIConfigErrors(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	if _, err := New("azure", config.ProviderConfig{Type: "azure-openai"}); err == nil || !strings.Contains(err.Error(), "AZURE_OPENAI_ENDPOINT") {
+		t.Errorf("no endpoint: err = %v", err)
+	}
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://contoso.openai.azure.com")
+	if _, err := New("azure", config.ProviderConfig{Type: "azure-openai"}); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("no key: err = %v, want ErrNoCredentials", err)
+	}
+}
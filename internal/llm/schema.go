@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema asks a provider for a structured response. Providers with a native
+// structured output mode constrain the reply to it; the others ignore it and
+// rely on the prompt.
+type Schema struct {
+	// Name identifies the schema to the API, e.g. "triage_verdict".
+	Name string
+	// JSON is a JSON Schema of an object, as built by SchemaOf.
+	JSON map[string]any
+}
+
+// Enumer is implemented by string types that take a fixed set of values;
+// SchemaOf lists them as the enum of the field.
+type Enumer interface {
+	Enum() []string
+}
+
+// Descriptions on struct fields are given with a `desc` tag.
+const descTag = "desc"
+
+var enumerType = reflect.TypeOf((*Enumer)(nil)).Elem()
+
+// SchemaOf builds the JSON Schema of v's type from its json tags. Every
+// field is required and objects allow no other properties, as OpenAI's
+// strict mode demands; fields tagged "-" are left out.
+func SchemaOf(name string, v any) *Schema {
+	return &Schema{Name: name, JSON: typeSchema(reflect.TypeOf(v))}
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	out := map[string]any{}
+	if t.Implements(enumerType) {
+		out["type"] = "string"
+		out["enum"] = reflect.Zero(t).Interface().(Enumer).Enum()
+		return out
+	}
+	switch t.Kind() {
+	case reflect.String:
+		out["type"] = "string"
+	case reflect.Bool:
+		out["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		out["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		out["type"] = "array"
+		out["items"] = typeSchema(t.Elem())
+	case reflect.Map:
+		out["type"] = "object"
+		out["additionalProperties"] = typeSchema(t.Elem())
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := typeSchema(f.Type)
+			if d := f.Tag.Get(descTag); d != "" {
+				prop["description"] = d
+			}
+			props[name] = prop
+			required = append(required, name)
+		}
+		out["type"] = "object"
+		out["properties"] = props
+		out["required"] = required
+		out["additionalProperties"] = false
+	}
+	return out
+}
+
+// geminiSchema converts a JSON Schema to the OpenAPI subset Gemini's
+// responseSchema accepts: upper-case types, no additionalProperties, and
+// propertyOrdering so fields come back in declaration order.
+func geminiSchema(s map[string]any) map[string]any {
+	out := map[string]any{}
+	for k, v := range s {
+		switch k {
+		case "type":
+			out[k] = strings.ToUpper(v.(string))
+		case "properties":
+			props := map[string]any{}
+			for name, p := range v.(map[string]any) {
+				props[name] = geminiSchema(p.(map[string]any))
+			}
+			out[k] = props
+		case "items":
+			out[k] = geminiSchema(v.(map[string]any))
+		case "required":
+			out[k] = v
+			out["propertyOrdering"] = v
+		case "enum":
+			out[k] = v
+			out["format"] = "enum"
+		case "description":
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -1,15 +1,13 @@
 package model
 
-import "time"
+import (
+	"time"
 
-// Event represents a Windows event log entry after normalization.
-type Event struct {
-	Time    time.Time `json:"time"`
-	Level   string    `json:"level"`
-	EventID uint32    `json:"event_id"`
-	Source  string    `json:"source"`
-	Message string    `json:"message"`
-}
+	"winopsguard/internal/event"
+)
+
+// Event is the canonical event record; see package event for the schema.
+type Event = event.Event
 
 type TopEventID struct {
 	ID    uint32 `json:"id"`
@@ -21,13 +19,244 @@ type LogSet struct {
 	LevelCounts map[string]int `json:"level_counts"`
 	TopEventIDs []TopEventID   `json:"top_event_ids"`
 	Recent      []Event        `json:"recent"`
+	Clusters    []LogCluster   `json:"clusters"`
 	Raw         []Event        `json:"-"`
 }
 
-// WULog holds Windows Update log excerpts.
+// LogCluster is a message template mined from similar events; variable
+// tokens are replaced by <*>.
+type LogCluster struct {
+	Template  string     `json:"template"`
+	Count     int        `json:"count"`
+	Source    string     `json:"source"`
+	EventID   uint32     `json:"event_id"`
+	Level     string     `json:"level"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+	Examples  [][]string `json:"example_params,omitempty"`
+}
+
+// WULog holds Windows Update log excerpts and the parsed failure records.
 type WULog struct {
-	Summary string   `json:"summary"`
-	Excerpt []string `json:"excerpt"`
+	Summary        string      `json:"summary"`
+	Excerpt        []string    `json:"excerpt"`
+	FailedInstalls []WUFailure `json:"failed_installs,omitempty"`
+	FirstFailure   string      `json:"first_failure,omitempty"`
+	LastFailure    string      `json:"last_failure,omitempty"`
+	ErrorCodes     []string    `json:"error_codes,omitempty"`
+	Entries        []WUEntry   `json:"entries,omitempty"`
+}
+
+// WUEntry is one WindowsUpdate.log record with an update, code or result.
+type WUEntry struct {
+	Time      time.Time `json:"time"`
+	Component string    `json:"component"`
+	HRESULT   string    `json:"hresult,omitempty"`
+	UpdateID  string    `json:"update_id,omitempty"`
+	KB        string    `json:"kb,omitempty"`
+	Result    string    `json:"result,omitempty"`
+	Message   string    `json:"message"`
+	Raw       string    `json:"-"`
+}
+
+// WUFailure aggregates failed install attempts of one KB.
+type WUFailure struct {
+	KB         string    `json:"kb"`
+	UpdateID   string    `json:"update_id,omitempty"`
+	Count      int       `json:"count"`
+	ErrorCodes []string  `json:"error_codes"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// ServicingEntry is one CBS.log or dism.log line with servicing evidence.
+type ServicingEntry struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Level     string    `json:"level"`
+	Component string    `json:"component"`
+	HRESULT   string    `json:"hresult,omitempty"`
+	// Markers lists corrupt, repaired and manifest_missing findings.
+	Markers  []string `json:"markers,omitempty"`
+	Packages []string `json:"packages,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// ServicingLog summarizes component store (CBS/DISM) evidence.
+type ServicingLog struct {
+	Summary              string           `json:"summary"`
+	CorruptCount         int              `json:"corrupt_count"`
+	RepairedCount        int              `json:"repaired_count"`
+	ManifestMissingCount int              `json:"manifest_missing_count"`
+	ErrorCodes           []string         `json:"error_codes"`
+	Packages             []string         `json:"packages"`
+	Entries              []ServicingEntry `json:"entries"`
+}
+
+// WERReport is the fault signature of one Windows Error Reporting Report.wer.
+type WERReport struct {
+	EventType     string    `json:"event_type"`
+	Time          time.Time `json:"time"`
+	App           string    `json:"app"`
+	AppVersion    string    `json:"app_version,omitempty"`
+	AppPath       string    `json:"app_path,omitempty"`
+	Module        string    `json:"module"`
+	ModuleVersion string    `json:"module_version,omitempty"`
+	ExceptionCode string    `json:"exception_code"`
+}
+
+// CrashBucket groups crashes of one application, module, module version and
+// exception code.
+type CrashBucket struct {
+	App           string    `json:"app"`
+	AppVersion    string    `json:"app_version,omitempty"`
+	Module        string    `json:"module"`
+	ModuleVersion string    `json:"module_version,omitempty"`
+	ExceptionCode string    `json:"exception_code"`
+	Count         int       `json:"count"`
+	EventCount    int       `json:"event_count"`
+	ReportCount   int       `json:"report_count"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// CrashSummary holds application crash buckets, most frequent first.
+type CrashSummary struct {
+	Summary string        `json:"summary"`
+	Buckets []CrashBucket `json:"buckets"`
+}
+
+// RebootEvent is one entry of the reboot timeline: a Kernel-Power 41,
+// EventLog 6008 or BugCheck 1001 event, or a crash dump header.
+type RebootEvent struct {
+	Time         time.Time `json:"time"`
+	Kind         string    `json:"kind"`
+	EventID      int       `json:"event_id,omitempty"`
+	BugcheckCode string    `json:"bugcheck_code,omitempty"`
+	BugcheckName string    `json:"bugcheck_name,omitempty"`
+	Parameters   []string  `json:"parameters,omitempty"`
+	DumpPath     string    `json:"dump_path,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+}
+
+// RebootHistory is the unexpected reboot timeline. UnexpectedCount counts
+// incidents, not records: one crash usually logs 41, 6008 and 1001 together.
+type RebootHistory struct {
+	Summary         string        `json:"summary"`
+	UnexpectedCount int           `json:"unexpected_count"`
+	BugcheckCount   int           `json:"bugcheck_count"`
+	BugcheckCodes   []string      `json:"bugcheck_codes,omitempty"`
+	LastReboot      time.Time     `json:"last_reboot"`
+	Timeline        []RebootEvent `json:"timeline"`
+}
+
+// PendingReboot lists why Windows is waiting for a restart. DISM and SFC
+// results are unreliable until it happens.
+type PendingReboot struct {
+	Pending bool     `json:"pending"`
+	Reasons []string `json:"reasons"`
+	Details []string `json:"details,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Service start types and states used in ServiceHealth.
+const (
+	StartAutomatic = "automatic"
+	StartDelayed   = "automatic_delayed"
+	StartManual    = "manual"
+	StartDisabled  = "disabled"
+
+	ServiceRunning      = "running"
+	ServiceStopped      = "stopped"
+	ServiceNotInstalled = "not_installed"
+)
+
+// DiskHealth is the free space of one volume.
+type DiskHealth struct {
+	Root        string  `json:"root"`
+	FreeBytes   uint64  `json:"free_bytes"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	FreePercent float64 `json:"free_percent"`
+}
+
+// MemoryHealth is physical memory and commit usage.
+type MemoryHealth struct {
+	LoadPercent          int    `json:"load_percent"`
+	TotalBytes           uint64 `json:"total_bytes"`
+	AvailableBytes       uint64 `json:"available_bytes"`
+	CommitTotalBytes     uint64 `json:"commit_total_bytes"`
+	CommitAvailableBytes uint64 `json:"commit_available_bytes"`
+}
+
+// ServiceHealth is the start type and state of one service.
+type ServiceHealth struct {
+	Name      string `json:"name"`
+	StartType string `json:"start_type,omitempty"`
+	State     string `json:"state"`
+}
+
+// HostHealth is a snapshot of the resources servicing depends on. Flags
+// hold one human-readable line per problem found.
+type HostHealth struct {
+	SystemDrive   DiskHealth      `json:"system_drive"`
+	WinSxSBytes   uint64          `json:"winsxs_bytes"`
+	WinSxSPartial bool            `json:"winsxs_partial,omitempty"`
+	Memory        MemoryHealth    `json:"memory"`
+	UptimeSeconds int64           `json:"uptime_seconds"`
+	BootTime      time.Time       `json:"boot_time"`
+	Services      []ServiceHealth `json:"services"`
+	Flags         []string        `json:"flags"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+// DecodedError is an error code found in the payload, decoded from the
+// offline table. Name and Description are empty when only the facility is
+// known.
+type DecodedError struct {
+	Code        string   `json:"code"`
+	Kind        string   `json:"kind,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Facility    string   `json:"facility,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Count       int      `json:"count,omitempty"`
+	SeenIn      []string `json:"seen_in,omitempty"`
+}
+
+// SignatureMatch is a known-issue signature matched locally against the
+// collected evidence. Matches are deterministic, high-confidence evidence.
+type SignatureMatch struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	IncidentType string   `json:"incident_type"`
+	Severity     string   `json:"severity"`
+	Cause        string   `json:"cause"`
+	Action       string   `json:"action"`
+	Command      string   `json:"command,omitempty"`
+	ErrorCode    string   `json:"error_code,omitempty"`
+	Count        int      `json:"count"`
+	Evidence     []string `json:"evidence"`
+	Catalog      string   `json:"catalog"`
+}
+
+// DroppedItem counts the evidence of one section and level that was cut to
+// fit the payload budget, with the distinct event IDs involved.
+type DroppedItem struct {
+	Section  string   `json:"section"`
+	Level    string   `json:"level,omitempty"`
+	Count    int      `json:"count"`
+	EventIDs []uint32 `json:"event_ids,omitempty"`
+}
+
+// TrimReport records how the payload was fitted to its byte and, when
+// configured, token budgets.
+type TrimReport struct {
+	BudgetBytes       int64         `json:"budget_bytes"`
+	FinalBytes        int           `json:"final_bytes"`
+	BudgetTokens      int           `json:"budget_tokens,omitempty"`
+	FinalTokens       int           `json:"final_tokens,omitempty"`
+	Tokenizer         string        `json:"tokenizer,omitempty"`
+	TruncatedMessages int           `json:"truncated_messages"`
+	Dropped           []DroppedItem `json:"dropped"`
 }
 
 // AIRequest is the payload sent to LLM.
@@ -45,25 +274,33 @@ type AIRequest struct {
 		System      LogSet `json:"system"`
 		Application LogSet `json:"application"`
 	} `json:"eventlog"`
-	WindowsUpdateLog WULog  `json:"windows_update_log"`
-	Ask             string `json:"ask"`
+	WindowsUpdateLog WULog            `json:"windows_update_log"`
+	ServicingLogs    ServicingLog     `json:"servicing_logs"`
+	Crashes          CrashSummary     `json:"crashes"`
+	RebootHistory    RebootHistory    `json:"reboot_history"`
+	PendingReboot    PendingReboot    `json:"pending_reboot"`
+	HostHealth       HostHealth       `json:"host_health"`
+	DecodedErrors    []DecodedError   `json:"decoded_errors"`
+	SignatureMatches []SignatureMatch `json:"signature_matches"`
+	Trimmed          TrimReport       `json:"trimmed"`
+	Ask              string           `json:"ask"`
 }
 
 // AIResponse defines fixed structure expected from LLM.
 type AIResponse struct {
-	Status                  string `json:"status"`
-	LikelyCauses            []Cause `json:"likely_causes"`
+	Status                  string    `json:"status"`
+	LikelyCauses            []Cause   `json:"likely_causes"`
 	RecommendedCommands     []Command `json:"recommended_commands"`
-	Warnings                []string `json:"warnings"`
-	Uncertainties           []string `json:"uncertainties"`
-	AdditionalLogsRequested []string `json:"additional_logs_requested"`
+	Warnings                []string  `json:"warnings"`
+	Uncertainties           []string  `json:"uncertainties"`
+	AdditionalLogsRequested []string  `json:"additional_logs_requested"`
 }
 
 type Cause struct {
-	Title     string   `json:"title"`
-	Evidence  []string `json:"evidence"`
-	Confidence string  `json:"confidence"`
-	Notes     string   `json:"notes"`
+	Title      string   `json:"title"`
+	Evidence   []string `json:"evidence"`
+	Confidence string   `json:"confidence"`
+	Notes      string   `json:"notes"`
 }
 
 type Command struct {
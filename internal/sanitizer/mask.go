@@ -20,6 +20,19 @@ func MaskRequest(req *model.AIRequest) {
 		for i := range set.Recent {
 			set.Recent[i].Message = maskString(set.Recent[i].Message)
 			set.Recent[i].Source = maskString(set.Recent[i].Source)
+			set.Recent[i].Computer = maskString(set.Recent[i].Computer)
+			for k, v := range set.Recent[i].Data {
+				set.Recent[i].Data[k] = maskString(v)
+			}
+		}
+		for i := range set.Clusters {
+			set.Clusters[i].Template = maskString(set.Clusters[i].Template)
+			set.Clusters[i].Source = maskString(set.Clusters[i].Source)
+			for _, ex := range set.Clusters[i].Examples {
+				for j := range ex {
+					ex[j] = maskString(ex[j])
+				}
+			}
 		}
 	}
 	maskEventSet(&req.EventLog.System)
@@ -29,6 +42,20 @@ func MaskRequest(req *model.AIRequest) {
 		req.WindowsUpdateLog.Excerpt[i] = maskString(req.WindowsUpdateLog.Excerpt[i])
 	}
 	req.WindowsUpdateLog.Summary = maskString(req.WindowsUpdateLog.Summary)
+	for i := range req.WindowsUpdateLog.Entries {
+		req.WindowsUpdateLog.Entries[i].Message = maskString(req.WindowsUpdateLog.Entries[i].Message)
+	}
+	for i := range req.ServicingLogs.Entries {
+		req.ServicingLogs.Entries[i].Message = maskString(req.ServicingLogs.Entries[i].Message)
+	}
+	for i := range req.PendingReboot.Details {
+		req.PendingReboot.Details[i] = maskString(req.PendingReboot.Details[i])
+	}
+	for i := range req.SignatureMatches {
+		for j := range req.SignatureMatches[i].Evidence {
+			req.SignatureMatches[i].Evidence[j] = maskString(req.SignatureMatches[i].Evidence[j])
+		}
+	}
 	req.Host.Hostname = maskString(req.Host.Hostname)
 	req.Host.OS = maskString(req.Host.OS)
 }
@@ -0,0 +1,456 @@
+// Package signature matches collected evidence against a catalog of known
+// Windows servicing, crash and IIS failure signatures. Matching is local and
+// deterministic, so it works without an LLM and its results can be treated
+// as high-confidence evidence.
+package signature
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"winopsguard/internal/errcode"
+	"winopsguard/internal/model"
+)
+
+//go:embed catalog.json
+var builtin []byte
+
+// Actions are the remediation actions a signature may recommend, with the
+// command each stands for. The remediation CLIs only run these.
+var Actions = map[string]string{
+	"dism_restore_health": "dism /online /cleanup-image /restorehealth",
+	"sfc_scannow":         "sfc /scannow",
+	"reset_update_cache":  "",
+	"iisreset":            "iisreset",
+	"manual_check":        "",
+}
+
+var severityRank = map[string]int{"critical": 4, "high": 3, "medium": 2, "low": 1}
+
+// maxEvidence caps the evidence lines recorded per match.
+const maxEvidence = 3
+
+// Rule is one signature. Every criterion that is set must hold for the same
+// observation; within a criterion any listed value matches.
+type Rule struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Disabled     bool     `json:"disabled,omitempty"`
+	EventIDs     []uint32 `json:"event_ids,omitempty"`
+	Providers    []string `json:"providers,omitempty"`
+	MessageRegex []string `json:"message_regex,omitempty"`
+	ErrorCodes   []string `json:"error_codes,omitempty"`
+	Markers      []string `json:"markers,omitempty"`
+	IncidentType string   `json:"incident_type"`
+	Severity     string   `json:"severity"`
+	Cause        string   `json:"cause"`
+	Action       string   `json:"action"`
+
+	patterns []*regexp.Regexp
+	codes    []uint32
+}
+
+type file struct {
+	Version    string  `json:"version"`
+	Signatures []*Rule `json:"signatures"`
+}
+
+// Catalog is the built-in catalog with any overrides applied.
+type Catalog struct {
+	version string
+	rules   []*Rule
+}
+
+// Observation is one piece of evidence: an event, a cluster, a log entry or
+// a crash bucket. Count weighs it in the match count; 0 marks evidence that
+// is already counted elsewhere (recent events next to their clusters).
+type Observation struct {
+	Section  string
+	EventID  uint32
+	Provider string
+	Message  string
+	Markers  []string
+	Count    int
+
+	codes []uint32
+}
+
+// Builtin returns the embedded catalog.
+func Builtin() *Catalog {
+	c, err := Load("")
+	if err != nil {
+		panic("signature: embedded catalog: " + err.Error())
+	}
+	return c
+}
+
+// Load reads the embedded catalog and then every *.json file in dir, in name
+// order. An override rule replaces the rule with the same id, or is added;
+// "disabled": true removes it. An empty dir loads the embedded catalog only.
+func Load(dir string) (*Catalog, error) {
+	var base file
+	if err := json.Unmarshal(builtin, &base); err != nil {
+		return nil, err
+	}
+	c := &Catalog{version: base.Version}
+	if err := c.merge("builtin", base.Signatures); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return c, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f file
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := c.merge(path, f.Signatures); err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if f.Version != "" {
+			name += "@" + f.Version
+		}
+		c.version += "+" + name
+	}
+	return c, nil
+}
+
+func (c *Catalog) merge(origin string, rules []*Rule) error {
+	for _, r := range rules {
+		if r == nil || r.ID == "" {
+			return fmt.Errorf("%s: signature without id", origin)
+		}
+		i := c.index(r.ID)
+		if r.Disabled {
+			if i >= 0 {
+				c.rules = append(c.rules[:i], c.rules[i+1:]...)
+			}
+			continue
+		}
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("%s: %s: %w", origin, r.ID, err)
+		}
+		if i >= 0 {
+			c.rules[i] = r
+		} else {
+			c.rules = append(c.rules, r)
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) index(id string) int {
+	for i, r := range c.rules {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Rule) compile() error {
+	if len(r.EventIDs)+len(r.Providers)+len(r.MessageRegex)+len(r.ErrorCodes)+len(r.Markers) == 0 {
+		return errors.New("no match criteria")
+	}
+	if _, ok := Actions[r.Action]; !ok {
+		return fmt.Errorf("action %q is not allowed", r.Action)
+	}
+	if _, ok := severityRank[strings.ToLower(r.Severity)]; !ok {
+		return fmt.Errorf("unknown severity %q", r.Severity)
+	}
+	if r.IncidentType == "" {
+		return errors.New("incident_type is required")
+	}
+	r.patterns = r.patterns[:0]
+	for _, expr := range r.MessageRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	r.codes = r.codes[:0]
+	for _, s := range r.ErrorCodes {
+		code, err := errcode.Parse(s)
+		if err != nil {
+			return fmt.Errorf("error code %q: %w", s, err)
+		}
+		r.codes = append(r.codes, code)
+	}
+	return nil
+}
+
+// Version identifies the catalog, e.g. "2026.10.2+site@3".
+func (c *Catalog) Version() string { return c.version }
+
+// Len returns the number of active rules.
+func (c *Catalog) Len() int { return len(c.rules) }
+
+// Evaluate returns the rules matched by obs, most severe first, then by
+// match count.
+func (c *Catalog) Evaluate(obs []Observation) []model.SignatureMatch {
+	for i := range obs {
+		obs[i].codes = errcode.Extract(obs[i].Message)
+	}
+	var out []model.SignatureMatch
+	for _, r := range c.rules {
+		var m *model.SignatureMatch
+		for i := range obs {
+			code, ok := r.match(&obs[i])
+			if !ok {
+				continue
+			}
+			if m == nil {
+				m = &model.SignatureMatch{
+					ID:           r.ID,
+					Title:        r.Title,
+					IncidentType: r.IncidentType,
+					Severity:     r.Severity,
+					Cause:        r.Cause,
+					Action:       r.Action,
+					Command:      Actions[r.Action],
+					Catalog:      c.version,
+				}
+			}
+			if m.ErrorCode == "" && code != 0 {
+				m.ErrorCode = errcode.Format(code)
+			}
+			m.Count += obs[i].Count
+			if len(m.Evidence) < maxEvidence {
+				m.Evidence = append(m.Evidence, obs[i].evidence())
+			}
+		}
+		if m != nil {
+			if m.Count == 0 {
+				m.Count = 1
+			}
+			out = append(out, *m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, rj := severityRank[strings.ToLower(out[i].Severity)], severityRank[strings.ToLower(out[j].Severity)]
+		if ri != rj {
+			return ri > rj
+		}
+		return out[i].Count > out[j].Count
+	})
+	return out
+}
+
+// KnownEvent reports whether ev matches a signature on its own.
+func (c *Catalog) KnownEvent(ev model.Event) bool {
+	o := eventObservation("", ev, 1)
+	o.codes = errcode.Extract(o.Message)
+	for _, r := range c.rules {
+		if _, ok := r.match(&o); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether o satisfies r, with the first of r's error codes
+// found (or the first code in o when r lists none).
+func (r *Rule) match(o *Observation) (uint32, bool) {
+	if len(r.EventIDs) > 0 && !containsID(r.EventIDs, o.EventID) {
+		return 0, false
+	}
+	if len(r.Providers) > 0 && !containsFold(r.Providers, o.Provider) {
+		return 0, false
+	}
+	if len(r.Markers) > 0 && !anyFold(r.Markers, o.Markers) {
+		return 0, false
+	}
+	if len(r.patterns) > 0 {
+		hit := false
+		for _, re := range r.patterns {
+			if re.MatchString(o.Message) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return 0, false
+		}
+	}
+	if len(r.codes) == 0 {
+		if len(o.codes) > 0 {
+			return o.codes[0], true
+		}
+		return 0, true
+	}
+	for _, want := range r.codes {
+		for _, got := range o.codes {
+			if want == got {
+				return want, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (o Observation) evidence() string {
+	var b strings.Builder
+	b.WriteString(o.Section)
+	b.WriteString(": ")
+	if o.Provider != "" {
+		b.WriteString(o.Provider)
+		b.WriteString(" ")
+	}
+	if o.EventID != 0 {
+		b.WriteString(strconv.FormatUint(uint64(o.EventID), 10))
+		b.WriteString(" ")
+	}
+	msg := strings.Join(strings.Fields(o.Message), " ")
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	b.WriteString(msg)
+	return strings.ToValidUTF8(b.String(), "")
+}
+
+// FromEvents returns one observation per event.
+func FromEvents(section string, events []model.Event) []Observation {
+	obs := make([]Observation, 0, len(events))
+	for _, ev := range events {
+		obs = append(obs, eventObservation(section, ev, 1))
+	}
+	return obs
+}
+
+// FromRequest returns the observations of an agent payload. Raw events are
+// used when present (the agent); otherwise clusters carry the counts and
+// recent events add their full messages (a payload read back from JSON).
+func FromRequest(req *model.AIRequest) []Observation {
+	var obs []Observation
+	for _, l := range []struct {
+		name string
+		set  *model.LogSet
+	}{
+		{"eventlog.system", &req.EventLog.System},
+		{"eventlog.application", &req.EventLog.Application},
+	} {
+		if len(l.set.Raw) > 0 {
+			obs = append(obs, FromEvents(l.name, l.set.Raw)...)
+			continue
+		}
+		weight := 1
+		if len(l.set.Clusters) > 0 {
+			weight = 0
+		}
+		for _, ev := range l.set.Recent {
+			obs = append(obs, eventObservation(l.name, ev, weight))
+		}
+		for _, c := range l.set.Clusters {
+			msg := c.Template
+			for _, ex := range c.Examples {
+				msg += " " + strings.Join(ex, " ")
+			}
+			obs = append(obs, Observation{
+				Section:  l.name + ".clusters",
+				EventID:  c.EventID,
+				Provider: c.Source,
+				Message:  msg,
+				Count:    c.Count,
+			})
+		}
+	}
+	for _, e := range req.WindowsUpdateLog.Entries {
+		obs = append(obs, Observation{
+			Section:  "windows_update_log",
+			Provider: e.Component,
+			Message:  strings.TrimSpace(e.HRESULT + " " + e.Message),
+			Count:    1,
+		})
+	}
+	for _, e := range req.ServicingLogs.Entries {
+		obs = append(obs, Observation{
+			Section:  "servicing_logs",
+			Provider: e.Source,
+			Message:  strings.TrimSpace(e.HRESULT + " " + e.Message),
+			Markers:  e.Markers,
+			Count:    1,
+		})
+	}
+	for _, b := range req.Crashes.Buckets {
+		obs = append(obs, Observation{
+			Section: "crashes",
+			Message: fmt.Sprintf("%s %s %s", b.App, b.Module, b.ExceptionCode),
+			Count:   b.Count,
+		})
+	}
+	return obs
+}
+
+func eventObservation(section string, ev model.Event, weight int) Observation {
+	msg := ev.Message
+	for _, k := range sortedKeys(ev.Data) {
+		msg += " " + ev.Data[k]
+	}
+	return Observation{
+		Section:  section,
+		EventID:  ev.EventID,
+		Provider: ev.Source,
+		Message:  msg,
+		Count:    weight,
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsID(list []uint32, id uint32) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyFold(want, have []string) bool {
+	for _, h := range have {
+		if containsFold(want, h) {
+			return true
+		}
+	}
+	return false
+}
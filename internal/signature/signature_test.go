@@ -0,0 +1,146 @@
+package signature
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"winopsguard/internal/model"
+)
+
+func writeOverride(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	// Files apply in name order: 20-site.json sees the rule 10-team.json added.
+	writeOverride(t, dir, "10-team.json", `{"version": "3", "signatures": [
+		{"id": "service-crash", "disabled": true},
+		{"id": "contoso-agent", "title": "Contoso agent crash", "event_ids": [1000], "message_regex": ["(?i)contoso"],
+		 "incident_type": "application_crash", "severity": "Low", "cause": "Known agent bug.", "action": "manual_check"}
+	]}`)
+	writeOverride(t, dir, "20-site.json", `{"signatures": [
+		{"id": "contoso-agent", "title": "Contoso agent crash (site)", "event_ids": [1000], "message_regex": ["(?i)contoso"],
+		 "incident_type": "application_crash", "severity": "High", "cause": "Known agent bug.", "action": "manual_check"},
+		{"id": "disk-full", "title": "Disk full", "error_codes": ["0x80070070"],
+		 "incident_type": "windows_update_failure", "severity": "Critical", "cause": "No space.", "action": "manual_check"},
+		{"id": "no-such-rule", "disabled": true}
+	]}`)
+	writeOverride(t, dir, "notes.txt", "ignored")
+
+	builtin := Builtin()
+	c, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := builtin.Version() + "+10-team@3+20-site"; c.Version() != want {
+		t.Errorf("version = %q, want %q", c.Version(), want)
+	}
+	if c.Len() != builtin.Len() {
+		t.Errorf("len = %d, want %d (one disabled, one added)", c.Len(), builtin.Len())
+	}
+	if c.index("service-crash") >= 0 {
+		t.Error("disabled rule still present")
+	}
+	if i := c.index("contoso-agent"); i < 0 || c.rules[i].Severity != "High" {
+		t.Error("later override did not replace the added rule")
+	}
+	// A replaced built-in rule keeps its place in the catalog.
+	if c.index("disk-full") != builtin.index("disk-full") || c.rules[c.index("disk-full")].Severity != "Critical" {
+		t.Error("built-in rule not replaced in place")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"bad json", `{"signatures": [`, "bad.json"},
+		{"no id", `{"signatures": [{"title": "x"}]}`, "signature without id"},
+		{"no criteria", `{"signatures": [{"id": "x", "incident_type": "y", "severity": "Low", "action": "manual_check"}]}`, "x: no match criteria"},
+		{"action not allowed", `{"signatures": [{"id": "x", "event_ids": [1], "incident_type": "y", "severity": "Low", "action": "format_c"}]}`, `action "format_c" is not allowed`},
+		{"bad severity", `{"signatures": [{"id": "x", "event_ids": [1], "incident_type": "y", "severity": "Urgent", "action": "manual_check"}]}`, `unknown severity "Urgent"`},
+		{"bad regex", `{"signatures": [{"id": "x", "message_regex": ["("], "incident_type": "y", "severity": "Low", "action": "manual_check"}]}`, "missing closing )"},
+		{"bad code", `{"signatures": [{"id": "x", "error_codes": ["0xZZ"], "incident_type": "y", "severity": "Low", "action": "manual_check"}]}`, `error code "0xZZ"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeOverride(t, dir, "bad.json", tt.content)
+			if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing directory: no error")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	c := Builtin()
+	obs := []Observation{
+		{Section: "windows_update_log", Message: "0x80072ee2 Download failed", Count: 1},
+		{Section: "windows_update_log", Message: "0x80072EFD Send failed", Count: 1},
+		{Section: "windows_update_log", Message: "0x80072ee2 retry", Count: 1},
+		{Section: "servicing_logs", Message: "0x800f081f source missing", Count: 1},
+		{Section: "eventlog.system", EventID: 41, Provider: "Microsoft-Windows-Kernel-Power", Count: 1},
+		{Section: "eventlog.system", EventID: 41, Provider: "Microsoft-Windows-Kernel-Power", Count: 1},
+		{Section: "eventlog.system", EventID: 153, Provider: "disk", Message: "The IO operation at logical block address 0x1234 was retried.", Count: 1},
+		{Section: "eventlog.system.clusters", EventID: 7031, Provider: "Service Control Manager", Count: 0},
+	}
+	got := c.Evaluate(obs)
+	var ids []string
+	for _, m := range got {
+		ids = append(ids, m.ID)
+	}
+	// Critical, then High by count (2 before 1), then Medium by count.
+	want := []string{"disk-errors", "unexpected-reboot", "cbs-source-missing", "wu-network", "service-crash"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ids = %v, want %v", ids, want)
+	}
+	wu := got[3]
+	if wu.Count != 3 || wu.ErrorCode != "0x80072ee2" || len(wu.Evidence) != 3 || wu.Command != "" || wu.Catalog != c.Version() {
+		t.Errorf("wu-network = %+v", wu)
+	}
+	if got[4].Count != 1 {
+		t.Errorf("service-crash count = %d, want at least 1", got[4].Count)
+	}
+	if got[2].Command != Actions["dism_restore_health"] || got[2].ErrorCode != "0x800f081f" {
+		t.Errorf("cbs-source-missing = %+v", got[2])
+	}
+}
+
+func TestKnownEvent(t *testing.T) {
+	c := Builtin()
+	tests := []struct {
+		name string
+		ev   model.Event
+		want bool
+	}{
+		{"ntfs corruption", model.Event{EventID: 55, Source: "Ntfs"}, true},
+		{"ntfs corruption, new provider name", model.Event{EventID: 55, Source: "Microsoft-Windows-Ntfs"}, true},
+		{"storport reset", model.Event{EventID: 129, Source: "stornvme"}, true},
+		{"retried io", model.Event{EventID: 153, Source: "Disk"}, true},
+		// Event 129 is also the Time-Service's routine NTP peer warning.
+		{"time service 129", model.Event{EventID: 129, Source: "Microsoft-Windows-Time-Service"}, false},
+		{"other provider 55", model.Event{EventID: 55, Source: "Microsoft-Windows-Kernel-Processor-Power"}, false},
+		{"error code in data", model.Event{EventID: 20, Source: "Microsoft-Windows-WindowsUpdateClient",
+			Data: map[string]string{"errorCode": "0x80070002"}}, true},
+		{"error code elsewhere", model.Event{EventID: 7000, Source: "Service Control Manager", Message: "failed with 0x800f081f"}, true},
+		{"benign", model.Event{EventID: 7036, Source: "Service Control Manager", Message: "The BITS service entered the running state."}, false},
+	}
+	for _, tt := range tests {
+		if got := c.KnownEvent(tt.ev); got != tt.want {
+			t.Errorf("%s: KnownEvent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
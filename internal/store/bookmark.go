@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bookmark is the last EventRecordID delivered for a channel.
+type Bookmark struct {
+	Channel   string    `json:"channel"`
+	RecordID  uint64    `json:"record_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Bookmarks persists per-channel bookmarks under <dir>/state so that
+// scheduled runs only return events newer than the previous run. Channel
+// names are matched case-insensitively, as the event log does.
+type Bookmarks struct {
+	path  string
+	marks map[string]Bookmark
+
+	// Logf, if set, reports bookmarks dropped by Rewind.
+	Logf func(format string, args ...any)
+}
+
+// BookmarkPath returns the bookmark file location for a queue/state dir.
+func BookmarkPath(dir string) string {
+	if dir == "" {
+		dir = "queue"
+	}
+	return filepath.Join(dir, "state", "bookmarks.json")
+}
+
+// LoadBookmarks reads the bookmark file; a missing file yields an empty set.
+func LoadBookmarks(dir string) (*Bookmarks, error) {
+	b := &Bookmarks{path: BookmarkPath(dir), marks: map[string]Bookmark{}}
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+	var list []Bookmark
+	if err := json.Unmarshal(data, &list); err != nil {
+		return b, err
+	}
+	for _, m := range list {
+		b.marks[strings.ToLower(m.Channel)] = m
+	}
+	return b, nil
+}
+
+// Get returns the bookmarked record ID for channel.
+func (b *Bookmarks) Get(channel string) (uint64, bool) {
+	m, ok := b.marks[strings.ToLower(channel)]
+	return m.RecordID, ok
+}
+
+// Advance moves the bookmark forward; older record IDs are ignored.
+func (b *Bookmarks) Advance(channel string, recordID uint64) {
+	key := strings.ToLower(channel)
+	if m, ok := b.marks[key]; ok && m.RecordID >= recordID {
+		return
+	}
+	b.marks[key] = Bookmark{Channel: channel, RecordID: recordID, UpdatedAt: time.Now().UTC()}
+}
+
+// Rewind drops channel's bookmark when it is beyond newest, the channel's
+// newest record ID (0 for an empty channel). That happens when the log was
+// cleared: record IDs start over, and Advance alone would skip every new
+// record until the old ID is reached again. It reports whether the bookmark
+// was dropped.
+func (b *Bookmarks) Rewind(channel string, newest uint64) bool {
+	key := strings.ToLower(channel)
+	m, ok := b.marks[key]
+	if !ok || m.RecordID <= newest {
+		return false
+	}
+	delete(b.marks, key)
+	if b.Logf != nil {
+		b.Logf("bookmark for %s reset: record %d is beyond the newest record %d; the log was probably cleared", m.Channel, m.RecordID, newest)
+	}
+	return true
+}
+
+// Reset forgets the given channels, or every channel when none are given.
+// It returns the channels that were removed.
+func (b *Bookmarks) Reset(channels ...string) []string {
+	var removed []string
+	if len(channels) == 0 {
+		for _, m := range b.marks {
+			removed = append(removed, m.Channel)
+		}
+		b.marks = map[string]Bookmark{}
+		sort.Strings(removed)
+		return removed
+	}
+	for _, ch := range channels {
+		key := strings.ToLower(ch)
+		if m, ok := b.marks[key]; ok {
+			removed = append(removed, m.Channel)
+			delete(b.marks, key)
+		}
+	}
+	return removed
+}
+
+// Save writes the bookmarks atomically.
+func (b *Bookmarks) Save() error {
+	list := make([]Bookmark, 0, len(b.marks))
+	for _, m := range b.marks {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Channel < list[j].Channel })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadBookmarksMissingFile(t *testing.T) {
+	b, err := LoadBookmarks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Get("System"); ok {
+		t.Error("empty set has a System bookmark")
+	}
+}
+
+func TestLoadBookmarksInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := BookmarkPath(dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBookmarks(dir); err == nil {
+		t.Error("corrupt bookmark file loaded without error")
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance []uint64
+		want    uint64
+	}{
+		{"first", []uint64{10}, 10},
+		{"forward", []uint64{10, 11, 25}, 25},
+		{"older ignored", []uint64{25, 10}, 25},
+		{"equal ignored", []uint64{25, 25}, 25},
+		{"out of order", []uint64{3, 9, 4, 7}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := LoadBookmarks(t.TempDir())
+			for _, id := range tt.advance {
+				b.Advance("System", id)
+			}
+			if got, ok := b.Get("system"); !ok || got != tt.want {
+				t.Errorf("Get = %d, %v; want %d", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewind(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   uint64
+		newest   uint64
+		want     bool
+		wantMark bool
+	}{
+		{"log cleared", 5000, 12, true, false},
+		{"log emptied", 5000, 0, true, false},
+		{"caught up", 5000, 5000, false, true},
+		{"behind", 5000, 6000, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := LoadBookmarks(t.TempDir())
+			var logged []string
+			b.Logf = func(format string, args ...any) { logged = append(logged, format) }
+			b.Advance("Application", tt.stored)
+			if got := b.Rewind("application", tt.newest); got != tt.want {
+				t.Errorf("Rewind = %v, want %v", got, tt.want)
+			}
+			if _, ok := b.Get("Application"); ok != tt.wantMark {
+				t.Errorf("bookmark kept = %v, want %v", ok, tt.wantMark)
+			}
+			if (len(logged) > 0) != tt.want {
+				t.Errorf("logged %d message(s), want a message only on reset", len(logged))
+			}
+		})
+	}
+
+	b, _ := LoadBookmarks(t.TempDir())
+	if b.Rewind("System", 0) {
+		t.Error("Rewind of an unknown channel reported a reset")
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []string
+		want     []string
+		left     []string
+	}{
+		{"all", nil, []string{"Application", "Setup", "System"}, nil},
+		{"one, any case", []string{"system"}, []string{"System"}, []string{"Application", "Setup"}},
+		{"unknown", []string{"Security"}, nil, []string{"Application", "Setup", "System"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := LoadBookmarks(t.TempDir())
+			for i, ch := range []string{"System", "Application", "Setup"} {
+				b.Advance(ch, uint64(i+1))
+			}
+			if got := b.Reset(tt.channels...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reset = %v, want %v", got, tt.want)
+			}
+			var left []string
+			for _, ch := range []string{"Application", "Setup", "System"} {
+				if _, ok := b.Get(ch); ok {
+					left = append(left, ch)
+				}
+			}
+			if !reflect.DeepEqual(left, tt.left) {
+				t.Errorf("left = %v, want %v", left, tt.left)
+			}
+		})
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	b, _ := LoadBookmarks(dir)
+	b.Advance("System", 42)
+	b.Advance("Microsoft-Windows-TaskScheduler/Operational", 7)
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(BookmarkPath(dir) + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	loaded, err := LoadBookmarks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ch, want := range map[string]uint64{"system": 42, "microsoft-windows-taskscheduler/operational": 7} {
+		if got, ok := loaded.Get(ch); !ok || got != want {
+			t.Errorf("%s = %d, %v; want %d", ch, got, ok, want)
+		}
+	}
+	if !reflect.DeepEqual(loaded.marks, b.marks) {
+		t.Errorf("round trip changed bookmarks:\n got %v\nwant %v", loaded.marks, b.marks)
+	}
+}
+
+func TestSaveKeepsOldFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	b, _ := LoadBookmarks(dir)
+	b.Advance("System", 1)
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(BookmarkPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in the temporary file's place makes the write fail.
+	if err := os.Mkdir(BookmarkPath(dir)+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	b.Advance("System", 2)
+	if err := b.Save(); err == nil {
+		t.Fatal("Save succeeded with an unwritable temporary file")
+	}
+	after, err := os.ReadFile(BookmarkPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("bookmark file changed by a failed save:\n%s", after)
+	}
+}
@@ -0,0 +1,363 @@
+package summarizer
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"winopsguard/internal/model"
+	"winopsguard/internal/tokenizer"
+)
+
+// Message limits: long messages are shortened before anything is dropped,
+// and shortened again before Error-level evidence is dropped.
+const (
+	longMessage  = 512
+	shortMessage = 128
+)
+
+// Score weights. Level dominates: an Error always outranks an Information
+// event; rarity, signature match and recency order events within a level.
+var levelWeight = map[string]float64{
+	"critical":    100,
+	"error":       80,
+	"warning":     40,
+	"information": 10,
+	"info":        10,
+	"verbose":     0,
+}
+
+const (
+	knownWeight   = 30
+	rarityWeight  = 20
+	recencyWeight = 10
+	clusterBonus  = 15
+)
+
+// knownEventIDs are System/Application events with a well-understood
+// failure meaning for update and IIS triage.
+var knownEventIDs = map[uint32]bool{
+	20: true, 25: true, 31: true, // WindowsUpdateClient install failures
+	41: true, 1001: true, 6008: true, // unexpected reboots
+	1000: true, 1002: true, 1026: true, // application crashes and hangs
+	7023: true, 7031: true, 7034: true, // service failures
+	5002: true, 5009: true, 5010: true, 5011: true, 5186: true, // WAS app pool failures
+	55: true, 129: true, 153: true, 2004: true, // NTFS, storage, resource exhaustion
+}
+
+// KnownFunc reports whether an event matches a known failure signature;
+// matches are kept ahead of other events of the same level. A nil KnownFunc
+// means KnownEvent.
+type KnownFunc func(ev model.Event) bool
+
+// KnownEvent is the built-in KnownFunc: a fixed list of event IDs. Callers
+// with a signature catalog combine it with their own.
+func KnownEvent(ev model.Event) bool {
+	return knownEventIDs[ev.EventID]
+}
+
+// item is one droppable piece of evidence.
+type item struct {
+	section string
+	score   float64
+	size    int
+	eventID uint32
+	level   string
+	mark    func()
+}
+
+// sizer measures the JSON encoding of a value, in bytes or tokens.
+type sizer func(v any) int
+
+// FitPayload trims req to maxBytes of JSON and records what was cut in
+// req.Trimmed. It works in stages, stopping as soon as the payload fits:
+// shorten long messages; drop events and clusters scored below Error level,
+// lowest first; shorten messages further; drop the remaining events and
+// clusters; drop the entries of the other sections. It may be called again
+// after more sections are attached; the report accumulates. known scores
+// events against the caller's signatures; nil uses KnownEvent.
+func FitPayload(req *model.AIRequest, maxBytes int64, known KnownFunc) {
+	if maxBytes <= 0 {
+		return
+	}
+	req.Trimmed.BudgetBytes = maxBytes
+	fit(req, maxBytes, jsonSize, known)
+	req.Trimmed.FinalBytes = jsonSize(req)
+}
+
+// FitPayloadTokens is FitPayload with the budget in tokens as counted by est.
+func FitPayloadTokens(req *model.AIRequest, est tokenizer.Estimator, maxTokens int, known KnownFunc) {
+	if maxTokens <= 0 {
+		return
+	}
+	size := func(v any) int {
+		b, _ := json.Marshal(v)
+		return est.Count(string(b))
+	}
+	req.Trimmed.BudgetTokens = maxTokens
+	req.Trimmed.Tokenizer = est.Name()
+	fit(req, int64(maxTokens), size, known)
+	req.Trimmed.FinalTokens = size(req)
+	req.Trimmed.FinalBytes = jsonSize(req)
+}
+
+func fit(req *model.AIRequest, limit int64, size sizer, known KnownFunc) {
+	if known == nil {
+		known = KnownEvent
+	}
+	floor := levelWeight["error"]
+	stages := []func(){
+		func() { req.Trimmed.TruncatedMessages += truncateMessages(req, longMessage) },
+		func() {
+			items, apply := scoredItems(req, size, known)
+			dropLowest(req, items, apply, limit, floor, size)
+		},
+		func() { req.Trimmed.TruncatedMessages += truncateMessages(req, shortMessage) },
+		func() {
+			items, apply := scoredItems(req, size, known)
+			dropLowest(req, items, apply, limit, -1, size)
+		},
+		func() {
+			items, apply := sectionItems(req, size)
+			dropLowest(req, items, apply, limit, -1, size)
+		},
+	}
+	for _, stage := range stages {
+		if int64(size(req)) <= limit {
+			return
+		}
+		stage()
+	}
+	// The drop report itself takes space; the last stages absorb it.
+	for range 3 {
+		if int64(size(req)) <= limit {
+			return
+		}
+		stages[3]()
+		stages[4]()
+	}
+}
+
+// dropLowest marks items in the given order until the estimated size fits
+// or an item scores at or above floor (a negative floor drops anything),
+// then apply removes the marked items from their lists.
+func dropLowest(req *model.AIRequest, items []item, apply func(), limit int64, floor float64, size sizer) {
+	total := size(req)
+	for _, it := range items {
+		if int64(total) <= limit || (floor >= 0 && it.score >= floor) {
+			break
+		}
+		total -= it.size + 1 // the separating comma
+		it.mark()
+		recordDrop(&req.Trimmed, it)
+	}
+	apply()
+}
+
+func recordDrop(r *model.TrimReport, it item) {
+	var d *model.DroppedItem
+	for i := range r.Dropped {
+		if r.Dropped[i].Section == it.section && r.Dropped[i].Level == it.level {
+			d = &r.Dropped[i]
+			break
+		}
+	}
+	if d == nil {
+		r.Dropped = append(r.Dropped, model.DroppedItem{Section: it.section, Level: it.level})
+		d = &r.Dropped[len(r.Dropped)-1]
+	}
+	d.Count++
+	if it.eventID == 0 {
+		return
+	}
+	i := sort.Search(len(d.EventIDs), func(i int) bool { return d.EventIDs[i] >= it.eventID })
+	if i == len(d.EventIDs) || d.EventIDs[i] != it.eventID {
+		d.EventIDs = append(d.EventIDs, 0)
+		copy(d.EventIDs[i+1:], d.EventIDs[i:])
+		d.EventIDs[i] = it.eventID
+	}
+}
+
+// scoredItems lists recent events and clusters of both channels, lowest
+// score first. apply removes the marked ones.
+func scoredItems(req *model.AIRequest, size sizer, known KnownFunc) ([]item, func()) {
+	var (
+		items []item
+		apply []func()
+	)
+	add := func(name string, set *model.LogSet) {
+		idCounts := map[uint32]int{}
+		for _, ev := range set.Raw {
+			idCounts[ev.EventID]++
+		}
+		oldest, newest := timeRange(set.Recent)
+		removed := map[int]bool{}
+		removedClusters := map[int]bool{}
+		for i, ev := range set.Recent {
+			score := levelWeight[strings.ToLower(ev.Level)]
+			if known(ev) {
+				score += knownWeight
+			}
+			if n := idCounts[ev.EventID]; n > 0 {
+				score += rarityWeight / float64(n)
+			} else {
+				score += rarityWeight
+			}
+			if span := newest - oldest; span > 0 {
+				score += recencyWeight * float64(ev.Time.Unix()-oldest) / float64(span)
+			}
+			items = append(items, item{
+				section: "eventlog." + name + ".recent",
+				score:   score,
+				size:    size(ev),
+				eventID: ev.EventID,
+				level:   ev.Level,
+				mark:    func() { removed[i] = true },
+			})
+		}
+		for i, c := range set.Clusters {
+			score := levelWeight[strings.ToLower(c.Level)] + clusterBonus
+			if known(model.Event{EventID: c.EventID, Source: c.Source, Level: c.Level, Message: c.Template}) {
+				score += knownWeight
+			}
+			items = append(items, item{
+				section: "eventlog." + name + ".clusters",
+				score:   score,
+				size:    size(c),
+				eventID: c.EventID,
+				level:   c.Level,
+				mark:    func() { removedClusters[i] = true },
+			})
+		}
+		apply = append(apply, func() {
+			set.Recent = compact(set.Recent, removed)
+			set.Clusters = compact(set.Clusters, removedClusters)
+		})
+	}
+	add("system", &req.EventLog.System)
+	add("application", &req.EventLog.Application)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].score < items[j].score })
+	return items, func() {
+		for _, fn := range apply {
+			fn()
+		}
+	}
+}
+
+// sectionItems lists the entries of the other sections for when events
+// alone cannot meet the budget: log lines and the reboot timeline oldest
+// first, then crash buckets, decoded errors and signature matches from the
+// least frequent up, since those conclusions are what the model most needs.
+func sectionItems(req *model.AIRequest, size sizer) ([]item, func()) {
+	var items []item
+	apply := []func(){
+		listItems(&items, "windows_update_log.entries", &req.WindowsUpdateLog.Entries, false, size),
+		listItems(&items, "servicing_logs.entries", &req.ServicingLogs.Entries, false, size),
+		listItems(&items, "windows_update_log.excerpt", &req.WindowsUpdateLog.Excerpt, false, size),
+		listItems(&items, "reboot_history.timeline", &req.RebootHistory.Timeline, false, size),
+		listItems(&items, "crashes.buckets", &req.Crashes.Buckets, true, size),
+		listItems(&items, "decoded_errors", &req.DecodedErrors, true, size),
+		listItems(&items, "signature_matches", &req.SignatureMatches, true, size),
+	}
+	return items, func() {
+		for _, fn := range apply {
+			fn()
+		}
+	}
+}
+
+// listItems appends the entries of list to items in drop order: from the
+// front, or from the back for lists ranked most important first. The
+// returned func removes the marked entries.
+func listItems[T any](items *[]item, section string, list *[]T, fromBack bool, size sizer) func() {
+	removed := map[int]bool{}
+	n := len(*list)
+	for k := range n {
+		i := k
+		if fromBack {
+			i = n - 1 - k
+		}
+		*items = append(*items, item{section: section, size: size((*list)[i]), mark: func() { removed[i] = true }})
+	}
+	return func() { *list = compact(*list, removed) }
+}
+
+// compact returns list without the removed indexes.
+func compact[T any](list []T, removed map[int]bool) []T {
+	if len(removed) == 0 {
+		return list
+	}
+	out := make([]T, 0, len(list)-len(removed))
+	for i, v := range list {
+		if !removed[i] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func truncateMessages(req *model.AIRequest, limit int) int {
+	n := 0
+	for _, set := range []*model.LogSet{&req.EventLog.System, &req.EventLog.Application} {
+		for i := range set.Recent {
+			if t := Truncate(set.Recent[i].Message, limit); t != set.Recent[i].Message {
+				set.Recent[i].Message = t
+				n++
+			}
+		}
+	}
+	for i := range req.ServicingLogs.Entries {
+		if t := Truncate(req.ServicingLogs.Entries[i].Message, limit); t != req.ServicingLogs.Entries[i].Message {
+			req.ServicingLogs.Entries[i].Message = t
+			n++
+		}
+	}
+	for i := range req.WindowsUpdateLog.Entries {
+		if t := Truncate(req.WindowsUpdateLog.Entries[i].Message, limit); t != req.WindowsUpdateLog.Entries[i].Message {
+			req.WindowsUpdateLog.Entries[i].Message = t
+			n++
+		}
+	}
+	return n
+}
+
+// truncatedMark ends a string shortened by Truncate.
+const truncatedMark = "...(truncated)"
+
+// Truncate cuts s to at most limit bytes, marker included, on a rune
+// boundary, so multibyte (e.g. Japanese) text is never split mid-character.
+// A limit too small for the marker cuts without it.
+func Truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	mark := truncatedMark
+	if limit <= len(mark) {
+		mark = ""
+	}
+	cut := limit - len(mark)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + mark
+}
+
+func timeRange(events []model.Event) (int64, int64) {
+	var oldest, newest int64
+	for i, ev := range events {
+		t := ev.Time.Unix()
+		if i == 0 || t < oldest {
+			oldest = t
+		}
+		if i == 0 || t > newest {
+			newest = t
+		}
+	}
+	return oldest, newest
+}
+
+func jsonSize(v any) int {
+	b, _ := json.Marshal(v)
+	return len(b)
+}
@@ -0,0 +1,133 @@
+package summarizer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		limit int
+		want  string
+	}{
+		{"fits", "short message", 13, "short message"},
+		{"no limit", "short message", 0, "short message"},
+		{"marker counted", "The Windows Update service failed to start.", 30, "The Windows Upda...(truncated)"},
+		{"rune boundary", "サービスの開始に失敗しました", 21, "サー...(truncated)"},
+		{"too small for marker", "The Windows Update service failed to start.", 14, "The Windows Up"},
+		{"too small rune boundary", "サービス", 5, "サ"},
+	}
+	for _, tt := range tests {
+		got := Truncate(tt.in, tt.limit)
+		if got != tt.want {
+			t.Errorf("%s: Truncate(%q, %d) = %q, want %q", tt.name, tt.in, tt.limit, got, tt.want)
+		}
+		if tt.limit > 0 && len(got) > tt.limit {
+			t.Errorf("%s: %d bytes, over the limit of %d", tt.name, len(got), tt.limit)
+		}
+		if again := Truncate(got, tt.limit); again != got {
+			t.Errorf("%s: truncating again gave %q", tt.name, again)
+		}
+	}
+}
+
+// derivedRequest has no events, only the sections built from other sources.
+func derivedRequest() model.AIRequest {
+	var req model.AIRequest
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 20 {
+		req.RebootHistory.Timeline = append(req.RebootHistory.Timeline, model.RebootEvent{
+			Time: start.Add(time.Duration(i) * time.Hour), Kind: "kernel_power", EventID: 41,
+			Detail: "no bugcheck recorded: power loss, hard hang or forced reset",
+		})
+	}
+	for i := range 10 {
+		req.Crashes.Buckets = append(req.Crashes.Buckets, model.CrashBucket{
+			App: fmt.Sprintf("app%d.exe", i), Module: "ntdll.dll", ExceptionCode: "0xc0000374", Count: 10 - i,
+		})
+		req.DecodedErrors = append(req.DecodedErrors, model.DecodedError{
+			Code: fmt.Sprintf("0x800f09%02d", i), Description: strings.Repeat("x", 80), Count: 10 - i,
+		})
+	}
+	for i := range 3 {
+		req.SignatureMatches = append(req.SignatureMatches, model.SignatureMatch{
+			ID: fmt.Sprintf("sig-%d", i), Title: strings.Repeat("y", 80), Evidence: []string{strings.Repeat("z", 80)},
+		})
+	}
+	return req
+}
+
+func TestFitPayloadSections(t *testing.T) {
+	full := jsonSize(derivedRequest())
+
+	// A little over: only the oldest reboot timeline entries go.
+	req := derivedRequest()
+	FitPayload(&req, int64(full-500), nil)
+	if req.Trimmed.FinalBytes > full-500 {
+		t.Fatalf("final %d bytes, budget %d", req.Trimmed.FinalBytes, full-500)
+	}
+	tl := req.RebootHistory.Timeline
+	if len(tl) == 0 || len(tl) == 20 || tl[len(tl)-1].Time.Hour() != 19 {
+		t.Errorf("timeline kept %d entries ending %v, want the newest kept", len(tl), tl)
+	}
+	if len(req.Crashes.Buckets) != 10 || len(req.DecodedErrors) != 10 || len(req.SignatureMatches) != 3 {
+		t.Errorf("dropped beyond the timeline: %+v", req.Trimmed.Dropped)
+	}
+
+	// Far over: the ranked sections lose their least frequent entries first.
+	req = derivedRequest()
+	FitPayload(&req, 1700, nil)
+	if req.Trimmed.FinalBytes > 1700 {
+		t.Fatalf("final %d bytes, budget 1700", req.Trimmed.FinalBytes)
+	}
+	if len(req.RebootHistory.Timeline) != 0 || len(req.Crashes.Buckets) != 0 || len(req.DecodedErrors) != 0 {
+		t.Errorf("kept timeline %d, crashes %d, decoded errors %d", len(req.RebootHistory.Timeline), len(req.Crashes.Buckets), len(req.DecodedErrors))
+	}
+	if len(req.SignatureMatches) == 0 || len(req.SignatureMatches) == 3 || req.SignatureMatches[0].ID != "sig-0" {
+		t.Errorf("signature matches = %+v, want the first kept", req.SignatureMatches)
+	}
+	sections := map[string]int{}
+	for _, d := range req.Trimmed.Dropped {
+		sections[d.Section] += d.Count
+	}
+	if sections["reboot_history.timeline"] != 20 || sections["crashes.buckets"] != 10 || sections["decoded_errors"] != 10 {
+		t.Errorf("drop report = %v", sections)
+	}
+}
+
+func TestFitPayloadKnown(t *testing.T) {
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ev := func(id uint32) model.Event {
+		return model.Event{Time: at, Level: "Warning", EventID: id, Source: "Contoso", Message: strings.Repeat("m", 100)}
+	}
+	build := func() model.AIRequest {
+		var req model.AIRequest
+		req.EventLog.System.Recent = []model.Event{ev(9002), ev(9001)}
+		req.EventLog.System.Raw = req.EventLog.System.Recent
+		return req
+	}
+	budget := int64(jsonSize(build()) - 50)
+
+	tests := []struct {
+		name  string
+		known KnownFunc
+		want  uint32
+	}{
+		{"built-in", nil, 9001},
+		{"caller signatures", func(e model.Event) bool { return KnownEvent(e) || e.EventID == 9002 }, 9002},
+	}
+	for _, tt := range tests {
+		req := build()
+		FitPayload(&req, budget, tt.known)
+		recent := req.EventLog.System.Recent
+		if len(recent) != 1 || recent[0].EventID != tt.want {
+			t.Errorf("%s: kept %+v, want event %d", tt.name, recent, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package summarizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"winopsguard/internal/model"
+)
+
+// Drain parameters. A message joins the most similar template of its group
+// when at least simThreshold of its tokens match; differing positions become
+// the wildcard.
+const (
+	wildcard     = "<*>"
+	simThreshold = 0.5
+	maxClusters  = 50
+	maxExamples  = 3
+)
+
+// drainCluster is one template under construction.
+type drainCluster struct {
+	id       int
+	template []string
+	members  []int
+}
+
+// Miner clusters event messages into templates with the Drain algorithm:
+// messages are grouped by source, event ID, token count and first token (a
+// fixed-depth parse tree), then matched to the most similar template in the
+// group.
+type Miner struct {
+	groups   map[string][]*drainCluster
+	clusters []*drainCluster
+	events   []model.Event
+	tokens   [][]string
+}
+
+// NewMiner returns an empty miner.
+func NewMiner() *Miner {
+	return &Miner{groups: map[string][]*drainCluster{}}
+}
+
+// Add mines one event and returns the ID of the cluster it joined.
+func (m *Miner) Add(ev model.Event) int {
+	toks := strings.Fields(ev.Message)
+	idx := len(m.events)
+	m.events = append(m.events, ev)
+	m.tokens = append(m.tokens, toks)
+
+	masked := make([]string, len(toks))
+	for i, t := range toks {
+		masked[i] = maskToken(t)
+	}
+	first := ""
+	if len(masked) > 0 {
+		first = masked[0]
+	}
+	key := fmt.Sprintf("%s|%d|%d|%s", ev.Source, ev.EventID, len(masked), first)
+
+	var best *drainCluster
+	bestSim, bestParams := -1.0, -1
+	for _, c := range m.groups[key] {
+		sim, params := similarity(c.template, masked)
+		if sim > bestSim || (sim == bestSim && params > bestParams) {
+			best, bestSim, bestParams = c, sim, params
+		}
+	}
+	if best == nil || (len(masked) > 0 && bestSim < simThreshold) {
+		best = &drainCluster{id: len(m.clusters), template: masked}
+		m.groups[key] = append(m.groups[key], best)
+		m.clusters = append(m.clusters, best)
+	} else {
+		for i, t := range masked {
+			if best.template[i] != t {
+				best.template[i] = wildcard
+			}
+		}
+	}
+	best.members = append(best.members, idx)
+	return best.id
+}
+
+// Clusters returns the mined templates, largest first, at most limit of them
+// (0 means no limit). Example parameters are the distinct values seen at the
+// wildcard positions.
+func (m *Miner) Clusters(limit int) []model.LogCluster {
+	out := make([]model.LogCluster, 0, len(m.clusters))
+	for _, c := range m.clusters {
+		first := m.events[c.members[0]]
+		lc := model.LogCluster{
+			Template:  strings.Join(c.template, " "),
+			Count:     len(c.members),
+			Source:    first.Source,
+			EventID:   first.EventID,
+			Level:     first.Level,
+			FirstSeen: first.Time,
+			LastSeen:  first.Time,
+		}
+		seen := map[string]bool{}
+		for _, idx := range c.members {
+			ev := m.events[idx]
+			if ev.Time.Before(lc.FirstSeen) {
+				lc.FirstSeen = ev.Time
+			}
+			if ev.Time.After(lc.LastSeen) {
+				lc.LastSeen = ev.Time
+			}
+			if len(lc.Examples) >= maxExamples {
+				continue
+			}
+			var params []string
+			for i, t := range c.template {
+				if t == wildcard && i < len(m.tokens[idx]) {
+					params = append(params, m.tokens[idx][i])
+				}
+			}
+			k := strings.Join(params, "\x00")
+			if len(params) > 0 && !seen[k] {
+				seen[k] = true
+				lc.Examples = append(lc.Examples, params)
+			}
+		}
+		out = append(out, lc)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// similarity is the share of positions where template and tokens agree, and
+// the number of wildcard positions as the tie-breaker. A position masked in
+// both agrees, so messages that differ only in numbers are identical.
+func similarity(template, tokens []string) (float64, int) {
+	if len(tokens) == 0 {
+		return 1, 0
+	}
+	same, params := 0, 0
+	for i, t := range template {
+		if t == wildcard {
+			params++
+		}
+		if t == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), params
+}
+
+// maskToken replaces tokens containing digits (counts, PIDs, HRESULTs,
+// GUIDs, addresses, timestamps) with the wildcard up front, so they never
+// split otherwise identical messages.
+func maskToken(t string) string {
+	for _, r := range t {
+		if unicode.IsDigit(r) {
+			return wildcard
+		}
+	}
+	return t
+}
@@ -0,0 +1,132 @@
+package summarizer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"winopsguard/internal/model"
+)
+
+func wuEvent(msg string, at time.Time) model.Event {
+	return model.Event{Time: at, Source: "Microsoft-Windows-WindowsUpdateClient", EventID: 20, Level: "Error", Message: msg}
+}
+
+func TestMinerClusters(t *testing.T) {
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		messages  []string
+		want      []string
+		wantCount []int
+	}{
+		{
+			name:      "identical numeric messages",
+			messages:  repeat("Installation failed 0x80070002 KB5031234 2024-01-01 10:00:00", 50),
+			want:      []string{"Installation failed <*> <*> <*> <*>"},
+			wantCount: []int{50},
+		},
+		{
+			name: "numbers differ",
+			messages: []string{
+				"Installation failed 0x80070002 KB5031234 2024-01-01 10:00:00",
+				"Installation failed 0x800f0922 KB5034441 2024-01-02 11:30:00",
+				"Installation failed 0x80073712 KB5031234 2024-01-03 09:15:42",
+			},
+			want:      []string{"Installation failed <*> <*> <*> <*>"},
+			wantCount: []int{3},
+		},
+		{
+			name: "one word differs",
+			messages: []string{
+				"The BITS service terminated unexpectedly 3 time(s)",
+				"The Spooler service terminated unexpectedly 1 time(s)",
+				"The wuauserv service terminated unexpectedly 2 time(s)",
+			},
+			want:      []string{"The <*> service terminated unexpectedly <*> time(s)"},
+			wantCount: []int{3},
+		},
+		{
+			name: "different text stays apart",
+			messages: []string{
+				"Installation failed 0x80070002 KB5031234",
+				"Installation failed 0x80070002 KB5031234",
+				"Download started for update KB5031234",
+			},
+			want:      []string{"Installation failed <*> <*>", "Download started for update <*>"},
+			wantCount: []int{2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMiner()
+			for i, msg := range tt.messages {
+				m.Add(wuEvent(msg, start.Add(time.Duration(i)*time.Minute)))
+			}
+			var templates []string
+			var counts []int
+			for _, c := range m.Clusters(0) {
+				templates = append(templates, c.Template)
+				counts = append(counts, c.Count)
+			}
+			if !reflect.DeepEqual(templates, tt.want) || !reflect.DeepEqual(counts, tt.wantCount) {
+				t.Errorf("clusters = %q %v, want %q %v", templates, counts, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestMinerClusterDetails(t *testing.T) {
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	m := NewMiner()
+	codes := []string{"0x80070002", "0x800f0922", "0x80070002", "0x80073712", "0x80240017"}
+	for i, code := range codes {
+		m.Add(wuEvent("Installation failed "+code, start.Add(time.Duration(i)*time.Hour)))
+	}
+	got := m.Clusters(0)
+	if len(got) != 1 {
+		t.Fatalf("%d clusters, want 1", len(got))
+	}
+	c := got[0]
+	if !c.FirstSeen.Equal(start) || !c.LastSeen.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("seen %v..%v", c.FirstSeen, c.LastSeen)
+	}
+	want := [][]string{{"0x80070002"}, {"0x800f0922"}, {"0x80073712"}}
+	if !reflect.DeepEqual(c.Examples, want) {
+		t.Errorf("examples = %q, want %q (distinct, at most %d)", c.Examples, want, maxExamples)
+	}
+}
+
+func TestSummarizeEventsDedupesRecent(t *testing.T) {
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	var events []model.Event
+	for i := range 50 {
+		events = append(events, wuEvent("Installation failed 0x80070002 KB5031234 2024-01-01 10:00:00", start.Add(time.Duration(i)*time.Minute)))
+	}
+	events = append(events,
+		wuEvent("Installation failed 0x800f0922 KB5034441 2024-01-02 11:30:00", start.Add(time.Hour)),
+		model.Event{Time: start, Source: "Service Control Manager", EventID: 7031, Level: "Error",
+			Message: "The BITS service terminated unexpectedly. It has done this 1 time(s)."},
+	)
+	set := SummarizeEvents(events, 10)
+	if len(set.Recent) != 2 {
+		t.Errorf("recent = %d events, want one per template (2)", len(set.Recent))
+	}
+	if len(set.Clusters) != 2 || set.Clusters[0].Count != 51 || set.Clusters[1].Count != 1 {
+		t.Errorf("clusters = %+v", set.Clusters)
+	}
+	if set.LevelCounts["Error"] != 52 || len(set.Raw) != 52 {
+		t.Errorf("level counts %v, raw %d", set.LevelCounts, len(set.Raw))
+	}
+	if want := []model.TopEventID{{ID: 20, Count: 51}, {ID: 7031, Count: 1}}; !reflect.DeepEqual(set.TopEventIDs, want) {
+		t.Errorf("top IDs = %v, want %v", set.TopEventIDs, want)
+	}
+}
+
+func repeat(s string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = s
+	}
+	return out
+}
@@ -1,28 +1,31 @@
 package summarizer
 
 import (
-	"encoding/json"
 	"sort"
 	"strings"
 
 	"winopsguard/internal/model"
 )
 
-// SummarizeEvents builds counts and top IDs from raw events.
+// SummarizeEvents builds counts, top IDs and message templates from raw
+// events. Recent keeps one event per template, so a provider repeating the
+// same message does not crowd out the rest; the repeats are counted in
+// Clusters.
 func SummarizeEvents(events []model.Event, maxRecent int) model.LogSet {
 	levelCounts := map[string]int{}
 	idCounts := map[uint32]int{}
 	var recent []model.Event
 
+	miner := NewMiner()
+	seen := map[int]bool{}
 	for _, ev := range events {
 		levelCounts[ev.Level]++
 		idCounts[ev.EventID]++
-	}
-
-	if len(events) > maxRecent {
-		recent = append(recent, events[:maxRecent]...)
-	} else {
-		recent = append(recent, events...)
+		id := miner.Add(ev)
+		if !seen[id] && len(recent) < maxRecent {
+			seen[id] = true
+			recent = append(recent, ev)
+		}
 	}
 
 	topIDs := make([]model.TopEventID, 0, len(idCounts))
@@ -43,12 +46,14 @@ func SummarizeEvents(events []model.Event, maxRecent int) model.LogSet {
 		LevelCounts: levelCounts,
 		TopEventIDs: topIDs,
 		Recent:      recent,
+		Clusters:    miner.Clusters(maxClusters),
 		Raw:         events,
 	}
 }
 
-// BuildPayload trims payload to fit within size budget.
-func BuildPayload(sys model.LogSet, app model.LogSet, wu model.WULog, maxBytes int64) model.AIRequest {
+// BuildPayload assembles the payload and fits it to the size budget with
+// FitPayload.
+func BuildPayload(sys model.LogSet, app model.LogSet, wu model.WULog, maxBytes int64, known KnownFunc) model.AIRequest {
 	req := model.AIRequest{}
 	req.EventLog.System = sys
 	req.EventLog.Application = app
@@ -56,54 +61,10 @@ func BuildPayload(sys model.LogSet, app model.LogSet, wu model.WULog, maxBytes i
 	req.Collection.MaxEvents = len(sys.Raw) + len(app.Raw)
 	req.Ask = "Identify likely causes and propose investigative PowerShell commands. Do not execute."
 	req.Collection.WindowMinutes = 0 // caller must set
-	trimToSize(&req, maxBytes)
+	FitPayload(&req, maxBytes, known)
 	return req
 }
 
-func trimToSize(req *model.AIRequest, maxBytes int64) {
-	recentLimit := 50
-	messageLimit := 512
-
-	for {
-		b, _ := json.Marshal(req)
-		if int64(len(b)) <= maxBytes || (recentLimit == 0 && messageLimit == 64) {
-			return
-		}
-		// Reduce message size first
-		for i := range req.EventLog.System.Recent {
-			req.EventLog.System.Recent[i].Message = truncate(req.EventLog.System.Recent[i].Message, messageLimit)
-		}
-		for i := range req.EventLog.Application.Recent {
-			req.EventLog.Application.Recent[i].Message = truncate(req.EventLog.Application.Recent[i].Message, messageLimit)
-		}
-		// Reduce number of recents
-		if len(req.EventLog.System.Recent) > recentLimit {
-			req.EventLog.System.Recent = req.EventLog.System.Recent[:recentLimit]
-		}
-		if len(req.EventLog.Application.Recent) > recentLimit {
-			req.EventLog.Application.Recent = req.EventLog.Application.Recent[:recentLimit]
-		}
-		// tighten limits for next loop
-		if recentLimit > 10 {
-			recentLimit -= 10
-		} else {
-			recentLimit = 0
-		}
-		if messageLimit > 128 {
-			messageLimit /= 2
-		} else {
-			messageLimit = 64
-		}
-	}
-}
-
-func truncate(s string, limit int) string {
-	if limit <= 0 || len(s) <= limit {
-		return s
-	}
-	return s[:limit] + "...(truncated)"
-}
-
 // MaskStrings applies in-place string masking helper.
 func MaskStrings(entries []string, mask func(string) string) []string {
 	out := make([]string, len(entries))
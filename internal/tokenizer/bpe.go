@@ -0,0 +1,115 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// pretokenize approximates the cl100k_base split pattern. RE2 has no
+// lookahead, so trailing whitespace before a word is not peeled off; counts
+// may differ from tiktoken by a token per whitespace run.
+var pretokenize = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// maxPiece caps the bytes merged at once. Merging is quadratic in the piece
+// length and pretokenize passes long letter runs (base64, hex dumps) whole,
+// so longer pieces are counted in chunks. Merges across a chunk boundary are
+// lost, which overcounts slightly: the safe direction for a budget.
+const maxPiece = 128
+
+// BPE counts tokens with a byte-pair-encoding rank table in the tiktoken
+// format (one "<base64 token> <rank>" per line), e.g. cl100k_base.tiktoken
+// for GPT-4 and GPT-3.5 or o200k_base.tiktoken for GPT-4o. No table is
+// embedded; see For.
+type BPE struct {
+	name  string
+	ranks map[string]int
+}
+
+// LoadBPE reads a tiktoken rank file.
+func LoadBPE(path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks := map[string]int{}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		tok, rank, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+		if !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(tok)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		r, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		ranks[string(b)] = r
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("%s: no ranks", path)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &BPE{name: "bpe:" + name, ranks: ranks}, nil
+}
+
+func (b *BPE) Name() string { return b.name }
+
+func (b *BPE) Count(text string) int {
+	n := 0
+	for _, piece := range pretokenize.FindAllString(text, -1) {
+		for len(piece) > maxPiece {
+			cut := maxPiece
+			for cut > 0 && !utf8.RuneStart(piece[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxPiece
+			}
+			n += b.countPiece(piece[:cut])
+			piece = piece[cut:]
+		}
+		n += b.countPiece(piece)
+	}
+	return n
+}
+
+// countPiece merges the lowest-ranked adjacent pair until no pair is in the
+// table, and returns the number of parts left.
+func (b *BPE) countPiece(piece string) int {
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+	// bounds[i] is the start offset of part i; the last entry is len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			if r, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && (best < 0 || r < bestRank) {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
@@ -0,0 +1,127 @@
+// Package tokenizer estimates how many tokens an LLM provider will count for
+// a prompt, so payloads can be budgeted in tokens rather than bytes.
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Estimator counts the tokens of a text.
+type Estimator interface {
+	Name() string
+	Count(text string) int
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Estimator{}
+)
+
+// Register installs e for a provider ("openai") or a provider/model pair
+// ("openai/gpt-4o-mini"); the pair wins over the provider.
+func Register(key string, e Estimator) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[strings.ToLower(key)] = e
+}
+
+// For returns the estimator for provider and model: a registered one first,
+// then bpe for OpenAI-style providers (and for an unknown provider) when a
+// rank table is loaded, otherwise Heuristic.
+//
+// No rank table is embedded: cl100k_base and o200k_base are megabytes each
+// and the agent is meant to stay small. Without tokenizer_file every count
+// is a Heuristic estimate; callers should say so when they budget with it
+// (see IsHeuristic).
+func For(provider, model string, bpe *BPE) Estimator {
+	provider, model = strings.ToLower(provider), strings.ToLower(model)
+	mu.RLock()
+	e, ok := registry[provider+"/"+model]
+	if !ok {
+		e, ok = registry[provider]
+	}
+	mu.RUnlock()
+	if ok {
+		return e
+	}
+	if bpe != nil && (provider == "" || provider == "openai" || provider == "azure-openai") {
+		return bpe
+	}
+	return Heuristic{}
+}
+
+// Heuristic approximates BPE tokenizers without a rank table. It errs on
+// the high side so a budget it accepts also holds for the real tokenizer:
+//   - a run of ASCII letters or digits costs one token per 4 characters;
+//   - a run of ASCII punctuation costs one token per 2 characters;
+//   - each CJK ideograph, kana or hangul character costs one token (BPE
+//     vocabularies split Japanese text far finer than English);
+//   - other non-ASCII letters cost one token per 2 characters;
+//   - whitespace is free except line breaks, one token per run.
+type Heuristic struct{}
+
+func (Heuristic) Name() string { return "heuristic" }
+
+// IsHeuristic reports whether e is the Heuristic fallback rather than a real
+// tokenizer.
+func IsHeuristic(e Estimator) bool {
+	_, ok := e.(Heuristic)
+	return ok
+}
+
+func (Heuristic) Count(text string) int {
+	const (
+		none = iota
+		word
+		punct
+		other
+		newline
+	)
+	tokens, run, class := 0, 0, none
+	flush := func() {
+		switch class {
+		case word:
+			tokens += (run + 3) / 4
+		case punct, other:
+			tokens += (run + 1) / 2
+		case newline:
+			tokens++
+		}
+		run, class = 0, none
+	}
+	for _, r := range text {
+		var c int
+		switch {
+		case r == '\n' || r == '\r':
+			c = newline
+		case unicode.IsSpace(r):
+			flush()
+			continue
+		case isWide(r):
+			flush()
+			tokens++
+			continue
+		case r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			c = word
+		case r < 0x80:
+			c = punct
+		default:
+			c = other
+		}
+		if c != class {
+			flush()
+			class = c
+		}
+		run++
+	}
+	flush()
+	return tokens
+}
+
+func isWide(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
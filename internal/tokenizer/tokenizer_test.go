@@ -0,0 +1,143 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRanks writes a tiktoken rank file with every single byte plus merges.
+func writeRanks(t *testing.T, merges ...string) string {
+	t.Helper()
+	var sb strings.Builder
+	rank := 0
+	for b := range 256 {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), rank)
+		rank++
+	}
+	for _, m := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(m)), rank)
+		rank++
+	}
+	path := filepath.Join(t.TempDir(), "test_base.tiktoken")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBPECount(t *testing.T) {
+	bpe, err := LoadBPE(writeRanks(t, "he", "ll", "hell", "hello", " w", "or", "aa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bpe.Name() != "bpe:test_base" {
+		t.Errorf("name = %q", bpe.Name())
+	}
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hello world", 5}, // "hello" + " w" "or" "l" "d"
+		{"help", 3},        // "he" "l" "p"
+		{strings.Repeat("a", 10000), 5000},
+		// Chunks end on rune boundaries, so no character is split further
+		// than its bytes already are.
+		{strings.Repeat("日", 100), 300},
+	}
+	for _, tt := range tests {
+		name := tt.text
+		if len(name) > 20 {
+			name = name[:20] + "..."
+		}
+		if got := bpe.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBPEErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"bad token", "aGVsbG8= 0\n!!! 1\n", "bad.tiktoken:2"},
+		{"bad rank", "aGVsbG8= first\n", "bad.tiktoken:1"},
+		{"empty", "\n\n", "no ranks"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "bad.tiktoken")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadBPE(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := LoadBPE(filepath.Join(dir, "missing.tiktoken")); err == nil {
+		t.Error("missing file: no error")
+	}
+}
+
+func TestHeuristicCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Windows Update", 4},
+		{"0x800f0922", 3},
+		{"a,b", 3},
+		{"line\r\nnext", 3},
+		{"サービス", 4},
+		{"サービスの開始に失敗しました。", 15},
+		{"Ångström", 5}, // "Å" 1, "ngstr" 2, "ö" 1, "m" 1
+	}
+	for _, tt := range tests {
+		if got := (Heuristic{}).Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+type fixed int
+
+func (fixed) Name() string       { return "fixed" }
+func (f fixed) Count(string) int { return int(f) }
+
+func TestFor(t *testing.T) {
+	bpe := &BPE{name: "bpe:test", ranks: map[string]int{"a": 0}}
+	Register("fortest", fixed(1))
+	Register("FORTEST/Model-X", fixed(2))
+
+	tests := []struct {
+		provider, model string
+		bpe             *BPE
+		want            string
+		heuristic       bool
+	}{
+		{"openai", "gpt-4o-mini", bpe, "bpe:test", false},
+		{"azure-openai", "", bpe, "bpe:test", false},
+		{"", "", bpe, "bpe:test", false},
+		{"openai", "gpt-4o-mini", nil, "heuristic", true},
+		{"anthropic", "claude", bpe, "heuristic", true},
+		{"fortest", "other", bpe, "fixed", false},
+		{"fortest", "model-x", nil, "fixed", false},
+	}
+	for _, tt := range tests {
+		e := For(tt.provider, tt.model, tt.bpe)
+		if e.Name() != tt.want || IsHeuristic(e) != tt.heuristic {
+			t.Errorf("For(%q, %q) = %s (heuristic %v), want %s", tt.provider, tt.model, e.Name(), IsHeuristic(e), tt.want)
+		}
+	}
+	if got := For("fortest", "model-x", nil).Count(""); got != 2 {
+		t.Errorf("provider/model registration not preferred: count %d", got)
+	}
+}
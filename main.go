@@ -2,117 +2,110 @@ package main
 
 import (
 	"encoding/json"
-	"encoding/xml"
 	"flag"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
-	"unsafe"
 
-	"golang.org/x/sys/windows"
+	"winopsguard/internal/collector"
+	"winopsguard/internal/event"
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+	"winopsguard/internal/store"
 )
 
 const (
-	evtQueryChannelPath              = 0x1
-	evtQueryTolerateQueryErrs        = 0x1000
-	evtRenderEventXML                = 1
-	evtFormatMessageEvent            = 1
-	defaultLookbackMinutes           = 10
-	defaultMaxEvents                 = 256
-	defaultLogName                   = "application"
-	evtNextBatchSize          uint32 = 16
+	defaultLookbackMinutes = 10
+	defaultMaxEvents       = 256
+	defaultLogName         = "application"
+	defaultStateDir        = "queue"
 )
 
-var (
-	modWevtapi                   = windows.NewLazySystemDLL("wevtapi.dll")
-	procEvtQuery                 = modWevtapi.NewProc("EvtQuery")
-	procEvtNext                  = modWevtapi.NewProc("EvtNext")
-	procEvtRender                = modWevtapi.NewProc("EvtRender")
-	procEvtClose                 = modWevtapi.NewProc("EvtClose")
-	procEvtOpenPublisherMetadata = modWevtapi.NewProc("EvtOpenPublisherMetadata")
-	procEvtFormatMessage         = modWevtapi.NewProc("EvtFormatMessage")
-)
-
-type eventRecord struct {
-	TimeGenerated time.Time `json:"timeGenerated"`
-	Level         string    `json:"level"`
-	EventID       uint32    `json:"eventId"`
-	Source        string    `json:"source"`
-	Message       string    `json:"message"`
-}
-
-type eventXML struct {
-	System struct {
-		Provider struct {
-			Name string `xml:"Name,attr"`
-		} `xml:"Provider"`
-		EventID     uint32 `xml:"EventID"`
-		Level       uint32 `xml:"Level"`
-		TimeCreated struct {
-			SystemTime string `xml:"SystemTime,attr"`
-		} `xml:"TimeCreated"`
-	} `xml:"System"`
+// channelSpec is one -log value: a channel name with an optional event budget.
+type channelSpec struct {
+	name string
+	max  int
 }
 
-type publisherCache struct {
-	mu      sync.Mutex
-	handles map[string]windows.Handle
-}
+// channelList collects repeated or comma-separated -log values.
+type channelList []channelSpec
 
-func (c *publisherCache) get(provider string) (windows.Handle, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.handles == nil {
-		c.handles = make(map[string]windows.Handle)
-	}
-	if h, ok := c.handles[provider]; ok {
-		return h, nil
+func (c *channelList) String() string {
+	var parts []string
+	for _, spec := range *c {
+		parts = append(parts, spec.name)
 	}
-	ptr, err := windows.UTF16PtrFromString(provider)
-	if err != nil {
-		return 0, fmt.Errorf("publisher UTF16: %w", err)
-	}
-	r, _, callErr := procEvtOpenPublisherMetadata.Call(
-		0, // local session
-		uintptr(unsafe.Pointer(ptr)),
-		0, // log file path
-		0, // locale
-		0, // flags
-	)
-	if r == 0 {
-		return 0, fmt.Errorf("EvtOpenPublisherMetadata: %w", callErr)
-	}
-	h := windows.Handle(r)
-	c.handles[provider] = h
-	return h, nil
+	return strings.Join(parts, ",")
 }
 
-func (c *publisherCache) close() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	for k, h := range c.handles {
-		evtCloseHandle(h)
-		delete(c.handles, k)
+func (c *channelList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		spec, err := parseChannelSpec(item)
+		if err != nil {
+			return err
+		}
+		*c = append(*c, spec)
 	}
+	return nil
 }
 
 func main() {
 	minutes := flag.Int("minutes", defaultLookbackMinutes, "lookback window in minutes")
 	maxEvents := flag.Int("max", defaultMaxEvents, "maximum number of events to return")
-	logName := flag.String("log", defaultLogName, "event log channel: application|system|setup")
-	provider := flag.String("provider", "", "optional provider name filter (e.g. Microsoft-Windows-WindowsUpdateClient)")
+	var logs channelList
+	flag.Var(&logs, "log", "event log channel, repeatable or comma-separated: application|system|setup|<channel>[=max] (default application)")
+	provider := flag.String("provider", "", "optional provider name filter, comma-separated (e.g. Microsoft-Windows-WindowsUpdateClient)")
+	eventIDs := flag.String("event-id", "", "optional event ID filter, comma-separated IDs and ranges (e.g. 20,25,1000-1005)")
+	levels := flag.String("level", "", "optional level filter, comma-separated: critical|error|warning|information|verbose")
+	keywords := flag.String("keywords", "", "optional keyword filter, comma-separated names (AuditFailure, Classic, ...) or hex masks")
+	file := flag.String("file", "", "read an exported .evtx file instead of the live log (-log and -minutes are ignored)")
+	sinceBookmark := flag.Bool("since-bookmark", false, "return only events newer than the stored per-channel bookmark, then advance it")
+	resetBookmark := flag.Bool("reset-bookmark", false, "delete stored bookmarks for the -log channels (all channels if -log is omitted) and exit")
+	stateDir := flag.String("state-dir", defaultStateDir, "queue/state directory holding bookmarks")
 	flag.Parse()
 
-	channel, err := normalizeLogName(*logName)
+	if *resetBookmark {
+		if err := resetBookmarks(*stateDir, logs); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	filter, err := buildFilter(*provider, *eventIDs, *levels, *keywords)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(2)
 	}
 
-	events, err := fetchEvents(channel, strings.TrimSpace(*provider), *minutes, *maxEvents)
+	var events []model.Event
+	if path := strings.TrimSpace(*file); path != "" {
+		events, err = readEVTXFile(path, filter, *maxEvents)
+	} else {
+		if len(logs) == 0 {
+			logs.Set(defaultLogName)
+		}
+		filter.Window = lookback(*minutes)
+		var marks *store.Bookmarks
+		if *sinceBookmark {
+			if marks, err = store.LoadBookmarks(*stateDir); err != nil {
+				fmt.Fprintf(os.Stderr, "error: load bookmarks: %v\n", err)
+				os.Exit(2)
+			}
+			marks.Logf = func(format string, args ...any) {
+				fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+			}
+		}
+		events, err = fetchChannels(collector.DefaultSource(), logs, filter, *maxEvents, marks)
+		if err == nil && marks != nil {
+			err = marks.Save()
+		}
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(2)
@@ -121,246 +114,126 @@ func main() {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetEscapeHTML(false)
 	enc.SetIndent("", "  ")
-	if err := enc.Encode(events); err != nil {
+	if err := enc.Encode(event.NewDocument(events)); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
 		os.Exit(2)
 	}
 }
 
-func normalizeLogName(name string) (string, error) {
-	switch strings.ToLower(strings.TrimSpace(name)) {
-	case "application", "":
-		return "Application", nil
-	case "system":
-		return "System", nil
-	case "setup":
-		return "Setup", nil
-	default:
-		return "", fmt.Errorf("unsupported log: %s (use application|system|setup)", name)
-	}
-}
-
-func fetchEvents(logName, provider string, minutes, maxEvents int) ([]eventRecord, error) {
-	if minutes <= 0 {
-		minutes = defaultLookbackMinutes
-	}
+// readEVTXFile parses an exported log with the pure-Go reader, so it also runs
+// on non-Windows workstations.
+func readEVTXFile(path string, filter eventquery.Filter, maxEvents int) ([]model.Event, error) {
 	if maxEvents <= 0 {
 		maxEvents = defaultMaxEvents
 	}
+	src := collector.EVTXSource{Path: path, Warn: func(err error) {
+		fmt.Fprintf(os.Stderr, "warning: skipped %v\n", err)
+	}}
+	return src.Read("", filter, maxEvents)
+}
 
-	ms := minutes * 60 * 1000
-	queryStr := buildQuery(ms, provider)
-	queryPtr, err := windows.UTF16PtrFromString(queryStr)
-	if err != nil {
-		return nil, fmt.Errorf("query UTF16: %w", err)
-	}
-	pathPtr, err := windows.UTF16PtrFromString(logName)
-	if err != nil {
-		return nil, fmt.Errorf("path UTF16: %w", err)
-	}
-
-	hQuery, err := evtQuery(pathPtr, queryPtr)
-	if err != nil {
-		return nil, err
-	}
-	defer evtCloseHandle(hQuery)
-
+// fetchChannels reads every channel with its own budget. A channel that cannot
+// be read is reported on stderr; the run fails only if none could be read.
+// With marks, a bookmarked channel returns only newer records (regardless of
+// the time window) and its bookmark advances to the last record returned.
+func fetchChannels(src collector.EventSource, specs []channelSpec, filter eventquery.Filter, defaultMax int, marks *store.Bookmarks) ([]model.Event, error) {
 	var (
-		results = make([]eventRecord, 0, maxEvents)
-		cache   publisherCache
+		results []model.Event
+		errs    []string
 	)
-	defer cache.close()
-
-	for len(results) < maxEvents {
-		handles, err := evtNextBatch(hQuery, evtNextBatchSize)
-		if err != nil {
-			if err == windows.ERROR_NO_MORE_ITEMS {
-				break
-			}
-			return nil, err
+	for _, spec := range specs {
+		max := spec.max
+		if max <= 0 {
+			max = defaultMax
 		}
-		for _, hEvt := range handles {
-			rec, err := parseEvent(hEvt, &cache)
-			evtCloseHandle(hEvt)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, rec)
-			if len(results) >= maxEvents {
-				break
-			}
+		events, err := collector.ReadChannel(src, spec.name, filter, max, marks)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v\n", spec.name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", spec.name, err))
+			continue
 		}
+		results = append(results, events...)
 	}
-	return results, nil
-}
-
-func buildQuery(ms int, provider string) string {
-	if provider == "" {
-		return fmt.Sprintf("*[System[TimeCreated[timediff(@SystemTime) <= %d]]]", ms)
-	}
-	quotedProvider := strconv.Quote(provider)
-	return fmt.Sprintf("*[System[Provider[@Name=%s] and TimeCreated[timediff(@SystemTime) <= %d]]]", quotedProvider, ms)
-}
-
-func evtQuery(path, query *uint16) (windows.Handle, error) {
-	r, _, err := procEvtQuery.Call(
-		0,
-		uintptr(unsafe.Pointer(path)),
-		uintptr(unsafe.Pointer(query)),
-		evtQueryChannelPath|evtQueryTolerateQueryErrs,
-	)
-	if r == 0 {
-		return 0, fmt.Errorf("EvtQuery: %w", err)
-	}
-	return windows.Handle(r), nil
-}
-
-func evtNextBatch(hQuery windows.Handle, batch uint32) ([]windows.Handle, error) {
-	handles := make([]windows.Handle, batch)
-	var returned uint32
-	r, _, err := procEvtNext.Call(
-		uintptr(hQuery),
-		uintptr(batch),
-		uintptr(unsafe.Pointer(&handles[0])),
-		2000, // 2s timeout
-		0,
-		uintptr(unsafe.Pointer(&returned)),
-	)
-	if r == 0 {
-		if errno, ok := err.(windows.Errno); ok && errno == windows.ERROR_NO_MORE_ITEMS {
-			return nil, windows.ERROR_NO_MORE_ITEMS
-		}
-		return nil, fmt.Errorf("EvtNext: %w", err)
+	if len(errs) == len(specs) {
+		return nil, fmt.Errorf("no channel could be read (%s)", strings.Join(errs, "; "))
 	}
-	return handles[:returned], nil
+	return results, nil
 }
 
-func parseEvent(hEvt windows.Handle, cache *publisherCache) (eventRecord, error) {
-	xmlText, err := renderEventXML(hEvt)
+func resetBookmarks(dir string, specs []channelSpec) error {
+	marks, err := store.LoadBookmarks(dir)
 	if err != nil {
-		return eventRecord{}, err
+		return fmt.Errorf("load bookmarks: %w", err)
 	}
-	var parsed eventXML
-	if err := xml.Unmarshal([]byte(xmlText), &parsed); err != nil {
-		return eventRecord{}, fmt.Errorf("parse XML: %w", err)
+	var channels []string
+	for _, spec := range specs {
+		channels = append(channels, spec.name)
 	}
-
-	timestamp, err := time.Parse(time.RFC3339Nano, parsed.System.TimeCreated.SystemTime)
-	if err != nil {
-		return eventRecord{}, fmt.Errorf("parse time: %w", err)
+	removed := marks.Reset(channels...)
+	if err := marks.Save(); err != nil {
+		return fmt.Errorf("save bookmarks: %w", err)
 	}
+	fmt.Fprintf(os.Stderr, "bookmarks reset: %d channel(s) %s\n", len(removed), strings.Join(removed, ", "))
+	return nil
+}
 
-	levelText := mapLevel(parsed.System.Level)
-	source := parsed.System.Provider.Name
-
-	meta, err := cache.get(source)
+func parseChannelSpec(v string) (channelSpec, error) {
+	name, budget, hasBudget := strings.Cut(strings.TrimSpace(v), "=")
+	channel, err := normalizeLogName(name)
 	if err != nil {
-		return eventRecord{}, err
+		return channelSpec{}, err
 	}
-
-	msg, err := formatMessage(meta, hEvt)
-	if err != nil {
-		return eventRecord{}, err
+	spec := channelSpec{name: channel}
+	if hasBudget {
+		n, err := strconv.Atoi(strings.TrimSpace(budget))
+		if err != nil || n <= 0 {
+			return channelSpec{}, fmt.Errorf("invalid event budget for %s: %q", channel, budget)
+		}
+		spec.max = n
 	}
-
-	return eventRecord{
-		TimeGenerated: timestamp.UTC(),
-		Level:         levelText,
-		EventID:       parsed.System.EventID,
-		Source:        source,
-		Message:       msg,
-	}, nil
+	return spec, nil
 }
 
-func renderEventXML(hEvt windows.Handle) (string, error) {
-	var bufferUsed uint32
-	var propCount uint32
-
-	r, _, err := procEvtRender.Call(
-		0,
-		uintptr(hEvt),
-		evtRenderEventXML,
-		0,
-		0,
-		uintptr(unsafe.Pointer(&bufferUsed)),
-		uintptr(unsafe.Pointer(&propCount)),
-	)
-	if r == 0 {
-		if errno, ok := err.(windows.Errno); !ok || errno != windows.ERROR_INSUFFICIENT_BUFFER {
-			return "", fmt.Errorf("EvtRender(size): %w", err)
-		}
+// normalizeLogName maps the classic aliases to their channel names and passes
+// any other channel (e.g. Microsoft-Windows-WAS/Operational) through as-is.
+func normalizeLogName(name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+	switch strings.ToLower(trimmed) {
+	case "application", "":
+		return "Application", nil
+	case "system":
+		return "System", nil
+	case "setup":
+		return "Setup", nil
+	case "security":
+		return "Security", nil
 	}
-
-	buffer := make([]uint16, bufferUsed)
-	r, _, err = procEvtRender.Call(
-		0,
-		uintptr(hEvt),
-		evtRenderEventXML,
-		uintptr(bufferUsed),
-		uintptr(unsafe.Pointer(&buffer[0])),
-		uintptr(unsafe.Pointer(&bufferUsed)),
-		uintptr(unsafe.Pointer(&propCount)),
-	)
-	if r == 0 {
-		return "", fmt.Errorf("EvtRender: %w", err)
+	for _, r := range trimmed {
+		if r < 0x20 || strings.ContainsRune(`"*?<>|`, r) {
+			return "", fmt.Errorf("invalid log channel: %q", name)
+		}
 	}
-
-	return windows.UTF16ToString(buffer), nil
+	return trimmed, nil
 }
 
-func formatMessage(meta windows.Handle, hEvt windows.Handle) (string, error) {
-	var used uint32
-	r, _, err := procEvtFormatMessage.Call(
-		uintptr(meta),
-		uintptr(hEvt),
-		0,
-		0,
-		0,
-		evtFormatMessageEvent,
-		0,
-		0,
-		uintptr(unsafe.Pointer(&used)),
-	)
-	if r == 0 {
-		if errno, ok := err.(windows.Errno); !ok || errno != windows.ERROR_INSUFFICIENT_BUFFER {
-			return "", fmt.Errorf("EvtFormatMessage(size): %w", err)
-		}
+func buildFilter(providers, eventIDs, levels, keywords string) (eventquery.Filter, error) {
+	f := eventquery.Filter{Providers: eventquery.ParseProviders(providers)}
+	var err error
+	if f.EventIDs, err = eventquery.ParseEventIDs(eventIDs); err != nil {
+		return f, err
 	}
-
-	buf := make([]uint16, used)
-	r, _, err = procEvtFormatMessage.Call(
-		uintptr(meta),
-		uintptr(hEvt),
-		0,
-		0,
-		0,
-		evtFormatMessageEvent,
-		uintptr(used),
-		uintptr(unsafe.Pointer(&buf[0])),
-		uintptr(unsafe.Pointer(&used)),
-	)
-	if r == 0 {
-		return "", fmt.Errorf("EvtFormatMessage: %w", err)
+	if f.Levels, err = eventquery.ParseLevels(levels); err != nil {
+		return f, err
 	}
-	return strings.TrimSpace(windows.UTF16ToString(buf)), nil
-}
-
-func mapLevel(level uint32) string {
-	switch level {
-	case 2:
-		return "Error"
-	case 3:
-		return "Warning"
-	case 4:
-		return "Information"
-	default:
-		return "Unknown"
+	if f.Keywords, err = eventquery.ParseKeywords(keywords); err != nil {
+		return f, err
 	}
+	return f, nil
 }
 
-func evtCloseHandle(h windows.Handle) {
-	if h == 0 {
-		return
+func lookback(minutes int) time.Duration {
+	if minutes <= 0 {
+		minutes = defaultLookbackMinutes
 	}
-	procEvtClose.Call(uintptr(h))
+	return time.Duration(minutes) * time.Minute
 }
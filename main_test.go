@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"winopsguard/internal/collector"
+	"winopsguard/internal/eventquery"
+	"winopsguard/internal/model"
+	"winopsguard/internal/store"
+)
+
+func TestParseChannelSpec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    channelSpec
+		wantErr string
+	}{
+		{in: "system", want: channelSpec{name: "System"}},
+		{in: " APPLICATION ", want: channelSpec{name: "Application"}},
+		{in: "", want: channelSpec{name: "Application"}},
+		{in: "Setup=25", want: channelSpec{name: "Setup", max: 25}},
+		{in: "security = 10 ", want: channelSpec{name: "Security", max: 10}},
+		{in: "Microsoft-Windows-WAS/Operational=50", want: channelSpec{name: "Microsoft-Windows-WAS/Operational", max: 50}},
+		{in: "Microsoft-Windows-TaskScheduler/Operational", want: channelSpec{name: "Microsoft-Windows-TaskScheduler/Operational"}},
+		{in: "system=0", wantErr: `invalid event budget for System: "0"`},
+		{in: "system=-5", wantErr: `invalid event budget for System: "-5"`},
+		{in: "system=ten", wantErr: `invalid event budget for System: "ten"`},
+		{in: "system=", wantErr: `invalid event budget for System: ""`},
+		{in: "Microsoft-Windows-*", wantErr: "invalid log channel"},
+		{in: `"System"`, wantErr: "invalid log channel"},
+		{in: "Sys\ttem", wantErr: "invalid log channel"},
+	}
+	for _, tt := range tests {
+		got, err := parseChannelSpec(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseChannelSpec(%q) err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseChannelSpec(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestChannelListSet(t *testing.T) {
+	var logs channelList
+	for _, v := range []string{"system=100, application", "Microsoft-Windows-WAS/Operational", ",,"} {
+		if err := logs.Set(v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+	want := channelList{{name: "System", max: 100}, {name: "Application"}, {name: "Microsoft-Windows-WAS/Operational"}}
+	if !reflect.DeepEqual(logs, want) {
+		t.Errorf("logs = %+v, want %+v", logs, want)
+	}
+	if s := logs.String(); s != "System,Application,Microsoft-Windows-WAS/Operational" {
+		t.Errorf("String() = %q", s)
+	}
+	if err := logs.Set("setup,system=x"); err == nil {
+		t.Error("invalid budget accepted")
+	}
+}
+
+// brokenSource fails for the listed channels and reads the rest from a
+// FakeSource.
+type brokenSource struct {
+	collector.FakeSource
+	broken map[string]bool
+}
+
+func (s brokenSource) Read(channel string, filter eventquery.Filter, max int) ([]model.Event, error) {
+	if s.broken[channel] {
+		return nil, errors.New("The specified channel could not be found.")
+	}
+	return s.FakeSource.Read(channel, filter, max)
+}
+
+func channelEvents(channel string, n int, now time.Time) []model.Event {
+	var out []model.Event
+	for i := range n {
+		out = append(out, model.Event{Channel: channel, RecordID: uint64(i + 1), Time: now.Add(-time.Duration(n-i) * time.Second), EventID: 7036})
+	}
+	return out
+}
+
+func TestFetchChannels(t *testing.T) {
+	now := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	src := brokenSource{
+		FakeSource: collector.FakeSource{Now: now, Events: map[string][]model.Event{
+			"System":      channelEvents("System", 6, now),
+			"Application": channelEvents("Application", 4, now),
+		}},
+		broken: map[string]bool{"Microsoft-Windows-WAS/Operational": true, "Setup": true},
+	}
+	count := func(events []model.Event) map[string]int {
+		out := map[string]int{}
+		for _, ev := range events {
+			out[ev.Channel]++
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		specs   []channelSpec
+		want    map[string]int
+		wantErr string
+	}{
+		{"per-channel budgets", []channelSpec{{name: "System", max: 2}, {name: "Application"}}, map[string]int{"System": 2, "Application": 3}, ""},
+		{"default budget", []channelSpec{{name: "System"}}, map[string]int{"System": 3}, ""},
+		{"one channel fails", []channelSpec{{name: "System", max: 5}, {name: "Microsoft-Windows-WAS/Operational"}, {name: "Application", max: 1}},
+			map[string]int{"System": 5, "Application": 1}, ""},
+		{"every channel fails", []channelSpec{{name: "Microsoft-Windows-WAS/Operational"}, {name: "Setup"}}, nil,
+			"no channel could be read (Microsoft-Windows-WAS/Operational: The specified channel could not be found.; Setup: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := fetchChannels(src, tt.specs, eventquery.Filter{}, 3, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := count(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events per channel = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchChannelsBookmarks(t *testing.T) {
+	now := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	src := collector.FakeSource{Now: now, Events: map[string][]model.Event{"System": channelEvents("System", 6, now)}}
+	marks, err := store.LoadBookmarks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	marks.Advance("System", 4)
+	events, err := fetchChannels(src, []channelSpec{{name: "System"}, {name: "Application"}}, eventquery.Filter{Window: time.Nanosecond}, 10, marks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only records after the bookmark, regardless of the window; an
+	// unbookmarked channel with nothing in the window is not an error.
+	var ids []uint64
+	for _, ev := range events {
+		ids = append(ids, ev.RecordID)
+	}
+	if !reflect.DeepEqual(ids, []uint64{6, 5}) {
+		t.Errorf("record IDs = %v, want [6 5]", ids)
+	}
+	if id, _ := marks.Get("System"); id != 6 {
+		t.Errorf("bookmark = %d, want 6", id)
+	}
+}